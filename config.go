@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/lsp"
+)
+
+// Config shared by every folder of the session: the defaults overridden by the client settings.
+// The previous config is kept when the settings are invalid
+func loadEditorConfig(previous *lsp.ProjectConfig, settings json.RawMessage, source string, output io.Writer) *lsp.ProjectConfig {
+	partialConfig, err := lsp.DecodeProjectConfig(settings)
+	if err != nil {
+		reportConfigError(output, source, err)
+		return previous
+	}
+
+	return lsp.MergeProjectConfig(lsp.DefaultProjectConfig(TARGET_FILE_EXTENSIONS), partialConfig)
+}
+
+// Config of the folder: the editor config overridden by the project file found at the root of the folder (if any).
+// The editor config is used as is when the project file is invalid
+func loadFolderConfig(editorConfig *lsp.ProjectConfig, folderUri string, output io.Writer) *lsp.ProjectConfig {
+	path := filepath.Join(uriToFilePath(folderUri), lsp.PROJECT_CONFIG_FILE_NAME)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return editorConfig
+	} else if err != nil {
+		reportConfigError(output, path, err)
+		return editorConfig
+	}
+
+	partialConfig, err := lsp.DecodeProjectConfig(data)
+	if err != nil {
+		reportConfigError(output, path, err)
+		return editorConfig
+	}
+
+	slog.Info("project config loaded", slog.String("path", path))
+
+	return lsp.MergeProjectConfig(editorConfig, partialConfig)
+}
+
+func reportConfigError(output io.Writer, source string, err error) {
+	msg := "invalid configuration from '" + source + "', it is ignored. " + err.Error()
+	slog.Warn(msg)
+
+	lsp.SendShowMessage(output, 1, SERVER_NAME+": "+msg)
+}
+
+// Folder whose project file is the file targeted by 'fileUri'.
+// Return nil if the file is not a project file at the root of a folder
+func findFolderOfProjectConfig(folders map[string]*workspaceFolder, fileUri string) *workspaceFolder {
+	folderUri, found := strings.CutSuffix(fileUri, "/"+lsp.PROJECT_CONFIG_FILE_NAME)
+	if !found {
+		return nil
+	}
+
+	folder, ok := folders[folderUri]
+	if !ok || folder.isSingleFile {
+		return nil
+	}
+
+	return folder
+}
+
+// Every file extension analysed during the session, sorted
+func collectFileExtensions(editorConfig *lsp.ProjectConfig, folders map[string]*workspaceFolder) []string {
+	extensions := slices.Clone(editorConfig.GetExtensions())
+
+	for _, folder := range folders {
+		extensions = append(extensions, folder.storage.Config.GetExtensions()...)
+	}
+
+	slices.Sort(extensions)
+
+	return slices.Compact(extensions)
+}
+
+// Replace the registrations scoped to the file extensions when the extensions analysed during the session changed.
+// Nothing is done before 'initialized', since the first registration happen there
+func updateRegistration(registration *lsp.RegistrationParams, registeredExtensions []string, client *lsp.ClientCapabilities, editorConfig *lsp.ProjectConfig, folders map[string]*workspaceFolder, serverRequests *lsp.ServerRequests) (*lsp.RegistrationParams, []string) {
+	if registeredExtensions == nil {
+		return registration, registeredExtensions
+	}
+
+	extensions := collectFileExtensions(editorConfig, folders)
+	if slices.Equal(extensions, registeredExtensions) {
+		return registration, registeredExtensions
+	}
+
+	unregistration, registration := lsp.ProcessFileExtensionsChange(registration, client, extensions)
+	if unregistration != nil {
+		_ = serverRequests.Send("client/unregisterCapability", unregistration)
+	}
+
+	if registration != nil {
+		_ = serverRequests.Send("client/registerCapability", registration)
+	}
+
+	return registration, extensions
+}
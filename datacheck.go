@@ -0,0 +1,214 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+	"github.com/yayolande/go-template-lsp/lsp"
+
+	"github.com/yayolande/gota"
+	"github.com/yayolande/gota/lexer"
+	"github.com/yayolande/gota/parser"
+)
+
+// What a template require from its data, that the data of a Go call site do not provide.
+// The errors are located within the template file
+type dataMismatch struct {
+	SetId       string
+	TemplateUri string // empty when the template is not found
+	Definition  lexer.Range
+	Errs        []gota.Error
+}
+
+// Mismatches already found, see 'checkTemplateCallsData()'. Key: set id, template name and go code of the call
+type dataMismatchCache map[string]*dataMismatch
+
+func getDataMismatchKey(call gosource.TemplateCall) string {
+	return call.SetId + "\x00" + call.TemplateName + "\x00" + call.GoCode
+}
+
+// Forget the mismatches of the template sets containing one of the files, the files belonging to no set invalidate everything.
+// The whole cache must be cleared by the caller when the go code change
+func (cache dataMismatchCache) invalidate(storage *workSpaceStore, uris []string) {
+	if len(uris) == 0 {
+		return
+	}
+
+	var setIds []string
+	isOrphanChanged := false
+
+	for _, uri := range uris {
+		isOrphan := true
+
+		for _, set := range storage.GoSource.Sets {
+			if strings.HasPrefix(uri, "file://") && set.Contains(uriToFilePath(uri)) {
+				setIds = append(setIds, set.Id)
+				isOrphan = false
+			}
+		}
+
+		isOrphanChanged = isOrphanChanged || isOrphan
+	}
+
+	maps.DeleteFunc(cache, func(key string, mismatch *dataMismatch) bool {
+		return isOrphanChanged || mismatch.SetId == "" || slices.Contains(setIds, mismatch.SetId)
+	})
+}
+
+// Check the data given to the templates by the go code. The template is analysed once without 'Input' type (everything is inferred),
+// and once with the 'Input' type of the call site: errors only found by the later are what the data lack (missing field, type mismatch, ...).
+// The analysis is done within the template set, since the template might call other templates with its data.
+// Only the calls whose result is not cached yet are analysed.
+// 'muTextFromClient' is only held while parsing, the analysis itself only read what the diagnostic handler alone modify
+func checkTemplateCallsData(storage *workSpaceStore, muTextFromClient *sync.Mutex, cache dataMismatchCache) {
+	type pendingCheck struct {
+		mismatch   *dataMismatch
+		inferred   *parser.GroupStatementNode
+		withInput  *parser.GroupStatementNode
+		groupIndex int
+	}
+
+	var pendings []pendingCheck
+
+	muTextFromClient.Lock()
+
+	groups := groupFilesByTemplateSet(storage.ParsedFiles, storage.GoSource.Sets)
+
+	for _, call := range storage.GoSource.Calls {
+		key := getDataMismatchKey(call)
+		if _, ok := cache[key]; ok || call.GoCode == "" {
+			continue
+		}
+
+		mismatch := &dataMismatch{SetId: call.SetId}
+		cache[key] = mismatch
+
+		mismatch.TemplateUri, mismatch.Definition = storage.FindTemplateDefinition(call.SetId, call.TemplateName)
+		if mismatch.TemplateUri == "" {
+			continue
+		}
+
+		groupIndex := slices.IndexFunc(storage.GoSource.Sets, func(set gosource.TemplateSet) bool { return set.Id == call.SetId })
+		if groupIndex < 0 {
+			groupIndex = slices.IndexFunc(groups, func(group map[string]*parser.GroupStatementNode) bool {
+				_, ok := group[mismatch.TemplateUri]
+				return ok
+			})
+		}
+
+		if groupIndex < 0 || groupIndex >= len(groups) {
+			continue
+		}
+
+		execution := gosource.TemplateExecution{SetId: call.SetId, TemplateName: call.TemplateName, GoCode: call.GoCode}
+		content := storage.RawFiles[mismatch.TemplateUri]
+
+		pending := pendingCheck{mismatch: mismatch, groupIndex: groupIndex}
+		pending.inferred, _ = storage.ParseFileWithExecution(mismatch.TemplateUri, content, nil)
+		pending.withInput, _ = storage.ParseFileWithExecution(mismatch.TemplateUri, content, &execution)
+
+		pendings = append(pendings, pending)
+	}
+
+	muTextFromClient.Unlock()
+
+	for _, pending := range pendings {
+		group := maps.Clone(groups[pending.groupIndex])
+
+		group[pending.mismatch.TemplateUri] = pending.inferred
+		inferredErrs := getAnalysisErrorsOfFile(group, pending.mismatch.TemplateUri)
+
+		group[pending.mismatch.TemplateUri] = pending.withInput
+		inputErrs := getAnalysisErrorsOfFile(group, pending.mismatch.TemplateUri)
+
+		// errors of the inferred analysis are already reported on the template itself
+		count := make(map[string]int)
+		for _, err := range inferredErrs {
+			count[getErrorKey(err)]++
+		}
+
+		for _, err := range inputErrs {
+			if key := getErrorKey(err); count[key] > 0 {
+				count[key]--
+				continue
+			}
+
+			pending.mismatch.Errs = append(pending.mismatch.Errs, err)
+		}
+	}
+}
+
+func getAnalysisErrorsOfFile(group map[string]*parser.GroupStatementNode, uri string) []gota.Error {
+	for _, fileAnalyzed := range gota.DefinitionAnalysisChainTrigerredByBatchFileChange(group, uri) {
+		if fileAnalyzed.FileName == uri {
+			return fileAnalyzed.Errs
+		}
+	}
+
+	return nil
+}
+
+func getErrorKey(err gota.Error) string {
+	reach := err.GetRange()
+
+	return strconv.Itoa(reach.Start.Line) + ":" + strconv.Itoa(reach.Start.Character) + ":" + err.GetError()
+}
+
+// One diagnostic on the data argument of each call site whose data do not satisfy the template,
+// with the errors found within the template as related information. Key: uri of the go file
+func createDataMismatchDiagnostics(storage *workSpaceStore, cache dataMismatchCache, goFiles *goFileIndexes, severity int) map[string][]lsp.Diagnostic {
+	diagnosticsByUri := make(map[string][]lsp.Diagnostic)
+
+	for _, call := range storage.GoSource.Calls {
+		mismatch := cache[getDataMismatchKey(call)]
+		if mismatch == nil || call.GoCode == "" {
+			continue
+		}
+
+		templateLineIndex := lsp.NewLineIndex(storage.RawFiles[mismatch.TemplateUri], storage.Client.GetPositionEncoding())
+
+		var relatedInformation []lsp.DiagnosticRelatedInformation
+		for _, err := range mismatch.Errs {
+			if templateLineIndex.IsBeyondContent(err.GetRange().Start) {
+				continue // within the 'go:code' injected for the call
+			}
+
+			information := lsp.DiagnosticRelatedInformation{
+				Location: lsp.Location{Uri: mismatch.TemplateUri, Range: *fromParserRangeToLspRange(err.GetRange(), templateLineIndex)},
+				Message:  err.GetError(),
+			}
+
+			relatedInformation = append(relatedInformation, information)
+		}
+
+		if len(relatedInformation) == 0 {
+			continue
+		}
+
+		message := "data do not satisfy template '" + call.TemplateName + "': " + relatedInformation[0].Message
+		if len(relatedInformation) > 1 {
+			message += " (and " + strconv.Itoa(len(relatedInformation)-1) + " more)"
+		}
+
+		definition := lsp.DiagnosticRelatedInformation{
+			Location: lsp.Location{Uri: mismatch.TemplateUri, Range: *fromParserRangeToLspRange(mismatch.Definition, templateLineIndex)},
+			Message:  "template '" + call.TemplateName + "' defined here",
+		}
+
+		diagnostic := lsp.Diagnostic{
+			Range:              goFiles.toLspRange(call.DataStart, call.DataEnd),
+			Message:            message,
+			Severity:           severity,
+			RelatedInformation: append([]lsp.DiagnosticRelatedInformation{definition}, relatedInformation...),
+		}
+
+		uri := filePathToUri(call.DataStart.Filename)
+		diagnosticsByUri[uri] = append(diagnosticsByUri[uri], diagnostic)
+	}
+
+	return diagnosticsByUri
+}
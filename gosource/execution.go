@@ -0,0 +1,489 @@
+package gosource
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A call to 'Execute()' or 'ExecuteTemplate()' found in the Go code.
+// 'GoCode' declare the static type of the data argument as the 'Input' type, the one of '.' within the template
+type TemplateExecution struct {
+	SetId        string // empty when the template set of the call is unknown (eg. template stored in a struct field)
+	TemplateName string // file base name or '{{ define }}' name
+	GoCode       string // single line of Go code, declarations are separated by ';'
+	CallSite     string // eg. 'cmd/web/main.go:42'
+}
+
+// Same call as 'TemplateExecution', along its position within the Go file, to check it against the templates of the set.
+// Unlike the executions, calls are also kept when the type of the data argument is unknown
+type TemplateCall struct {
+	SetId        string // empty when the template set of the call is unknown
+	TemplateName string
+	GoCode       string // empty when the type of the data argument carry no information
+
+	NameStart token.Position // string literal of the template name, quotes included. Invalid when the name is not a literal
+	NameEnd   token.Position
+	DataStart token.Position // data argument
+	DataEnd   token.Position
+
+	Symbols GoCodeSymbols // Go declarations of the types, fields and methods rendered within 'GoCode'
+}
+
+// Go declaration of what is declared within a 'GoCode', the key is the path of the symbol within the 'GoCode',
+// eg. 'Input', 'Input.Name' (field or method) or 'Input.Address.Street' (field of an anonymous struct).
+// The key of a template function is its name
+type GoCodeSymbols map[string]token.Position
+
+// Maximum count of type declarations rendered for a single execution,
+// types beyond that (eg. deep trees of the standard library) are rendered as 'any'
+const MAX_RENDERED_TYPES = 64
+
+func isExecuteCall(call *ast.CallExpr, info *types.Info) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || info == nil {
+		return false
+	}
+
+	switch {
+	case selector.Sel.Name == "Execute" && len(call.Args) == 2:
+	case selector.Sel.Name == "ExecuteTemplate" && len(call.Args) == 3:
+	default:
+		return false
+	}
+
+	selection := info.Selections[selector]
+	if selection == nil {
+		return false
+	}
+
+	method, ok := selection.Obj().(*types.Func)
+	if !ok || method.Pkg() == nil {
+		return false
+	}
+
+	return slices.Contains(TEMPLATE_PACKAGE_PATHS, method.Pkg().Path())
+}
+
+func newTemplateCall(pkg *GoPackage, call *ast.CallExpr, file *ast.File, set *TemplateSet, packagesByTypes map[*types.Package]*GoPackage) (TemplateCall, bool) {
+	templateCall := TemplateCall{}
+	data := call.Args[len(call.Args)-1]
+
+	if set != nil {
+		templateCall.SetId = set.Id
+	}
+
+	if len(call.Args) == 3 {
+		name, ok := EvaluateStringExpression(call.Args[1], file)
+		if !ok {
+			return templateCall, false
+		}
+
+		templateCall.TemplateName = name
+
+		if literal, ok := call.Args[1].(*ast.BasicLit); ok {
+			templateCall.NameStart = pkg.Fset.Position(literal.Pos())
+			templateCall.NameEnd = pkg.Fset.Position(literal.End())
+		}
+	} else if set != nil && set.RootName != "" {
+		templateCall.TemplateName = set.RootName
+	} else {
+		return templateCall, false // no way to know which template is run
+	}
+
+	templateCall.GoCode, templateCall.Symbols = RenderInputType(pkg.Info.TypeOf(data), func(object types.Object) token.Position {
+		return getDeclarationPosition(object, packagesByTypes)
+	})
+	templateCall.DataStart = pkg.Fset.Position(data.Pos())
+	templateCall.DataEnd = pkg.Fset.Position(data.End())
+
+	return templateCall, true
+}
+
+func newTemplateExecution(templateCall TemplateCall, position token.Position, moduleRoot string) (TemplateExecution, bool) {
+	if templateCall.GoCode == "" {
+		return TemplateExecution{}, false
+	}
+
+	relativeFile, _ := filepath.Rel(moduleRoot, position.Filename)
+
+	execution := TemplateExecution{
+		SetId:        templateCall.SetId,
+		TemplateName: templateCall.TemplateName,
+		GoCode:       templateCall.GoCode,
+		CallSite:     filepath.ToSlash(relativeFile) + ":" + strconv.Itoa(position.Line),
+	}
+
+	return execution, true
+}
+
+// Position of the declaration of a workspace or standard library object, invalid for the others
+func getDeclarationPosition(object types.Object, packagesByTypes map[*types.Package]*GoPackage) token.Position {
+	if object == nil || object.Pkg() == nil || !object.Pos().IsValid() {
+		return token.Position{}
+	}
+
+	if workspacePackage := packagesByTypes[object.Pkg()]; workspacePackage != nil {
+		return workspacePackage.Fset.Position(object.Pos())
+	}
+
+	if isStandardPackage(object.Pkg().Path()) {
+		return stdlibFset.Position(object.Pos())
+	}
+
+	return token.Position{}
+}
+
+// Render the type as the 'go:code' declaration of 'Input', along the named types it depend on.
+// Only what a template can reach is kept: exported fields and exported methods.
+// Pointers are dereferenced since templates do it anyway, and interfaces become 'any'.
+// An empty string is returned when the type carry no information (untyped nil, 'any', unresolved type).
+// The Go declaration of every symbol rendered is found with 'positionOf', when not nil
+func RenderInputType(dataType types.Type, positionOf func(types.Object) token.Position) (string, GoCodeSymbols) {
+	if dataType == nil {
+		return "", nil
+	}
+
+	for {
+		pointer, ok := types.Unalias(dataType).(*types.Pointer)
+		if !ok {
+			break
+		}
+
+		dataType = pointer.Elem()
+	}
+
+	dataType = types.Unalias(dataType)
+
+	switch underlying := dataType.Underlying().(type) {
+	case *types.Interface:
+		return "", nil
+	case *types.Basic:
+		if underlying.Kind() == types.Invalid || underlying.Kind() == types.UntypedNil {
+			return "", nil
+		}
+	}
+
+	renderer := &goCodeRenderer{
+		names:      make(map[string]string),
+		usedNames:  map[string]bool{"Input": true},
+		symbols:    make(GoCodeSymbols),
+		positionOf: positionOf,
+	}
+
+	if named, ok := dataType.(*types.Named); ok && named.Obj().Pkg() != nil {
+		renderer.names[types.TypeString(named, nil)] = "Input"
+		renderer.queue = append(renderer.queue, named)
+	} else {
+		renderer.path = "Input"
+		renderer.declarations = append(renderer.declarations, "type Input "+renderer.render(types.Default(dataType)))
+	}
+
+	for len(renderer.queue) > 0 {
+		named := renderer.queue[0]
+		renderer.queue = renderer.queue[1:]
+
+		renderer.declareNamedType(named)
+	}
+
+	return strings.Join(renderer.declarations, "; "), renderer.symbols
+}
+
+type goCodeRenderer struct {
+	names        map[string]string // local name of the named types, key: full type string (type arguments included)
+	usedNames    map[string]bool
+	declarations []string
+	queue        []*types.Named
+
+	symbols    GoCodeSymbols
+	positionOf func(types.Object) token.Position // nil when the symbols are not wanted
+	path       string                            // symbol path of the type being rendered, see 'GoCodeSymbols'
+
+	// no type declaration at all, named types are replaced by their underlying basic type or else 'any'
+	isOpaque bool
+}
+
+func (renderer *goCodeRenderer) addSymbol(path string, object types.Object) {
+	if renderer.positionOf == nil || renderer.symbols == nil {
+		return
+	}
+
+	if position := renderer.positionOf(object); position.IsValid() {
+		renderer.symbols[path] = position
+	}
+}
+
+func (renderer *goCodeRenderer) declareNamedType(named *types.Named) {
+	name := renderer.names[types.TypeString(named, nil)]
+
+	renderer.path = name
+	renderer.addSymbol(name, named.Obj())
+
+	renderer.declarations = append(renderer.declarations, "type "+name+" "+renderer.render(named.Underlying()))
+
+	if types.IsInterface(named) {
+		return
+	}
+
+	methods := types.NewMethodSet(types.NewPointer(named))
+	for index := range methods.Len() {
+		selection := methods.At(index)
+		if len(selection.Index()) > 1 || !selection.Obj().Exported() {
+			continue // promoted methods come with their embedded field
+		}
+
+		signature := selection.Type().(*types.Signature)
+		renderer.addSymbol(name+"."+selection.Obj().Name(), selection.Obj())
+		renderer.declarations = append(renderer.declarations, "func ("+name+") "+selection.Obj().Name()+renderer.renderSignature(signature))
+	}
+}
+
+// Local name of the named type, queued for declaration on its first use
+func (renderer *goCodeRenderer) nameOf(named *types.Named) string {
+	key := types.TypeString(named, nil)
+	if name, ok := renderer.names[key]; ok {
+		return name
+	}
+
+	if len(renderer.names) >= MAX_RENDERED_TYPES {
+		return "any"
+	}
+
+	name := named.Obj().Name()
+	if renderer.usedNames[name] {
+		name = named.Obj().Pkg().Name() + "_" + named.Obj().Name()
+	}
+
+	for count := 2; renderer.usedNames[name]; count++ {
+		name = named.Obj().Pkg().Name() + "_" + named.Obj().Name() + strconv.Itoa(count)
+	}
+
+	renderer.usedNames[name] = true
+	renderer.names[key] = name
+	renderer.queue = append(renderer.queue, named)
+
+	return name
+}
+
+func (renderer *goCodeRenderer) render(typ types.Type) string {
+	switch typ := types.Unalias(typ).(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Invalid, types.UntypedNil, types.UnsafePointer:
+			return "any"
+		}
+
+		return types.Default(typ).String()
+	case *types.Pointer:
+		return renderer.render(typ.Elem())
+	case *types.Named:
+		if typ.Obj().Pkg() == nil { // 'error' and 'comparable'
+			return typ.Obj().Name()
+		}
+
+		if types.IsInterface(typ) {
+			return "any"
+		}
+
+		if renderer.isOpaque {
+			if basic, ok := typ.Underlying().(*types.Basic); ok {
+				return renderer.render(basic)
+			}
+
+			return "any"
+		}
+
+		return renderer.nameOf(typ)
+	case *types.Slice:
+		return "[]" + renderer.render(typ.Elem())
+	case *types.Array:
+		return "[" + strconv.FormatInt(typ.Len(), 10) + "]" + renderer.render(typ.Elem())
+	case *types.Map:
+		return "map[" + renderer.render(typ.Key()) + "]" + renderer.render(typ.Elem())
+	case *types.Chan:
+		return "chan " + renderer.render(typ.Elem())
+	case *types.Signature:
+		return "func" + renderer.renderSignature(typ)
+	case *types.Struct:
+		fields := make([]string, 0, typ.NumFields())
+
+		path := renderer.path
+
+		for index := range typ.NumFields() {
+			field := typ.Field(index)
+
+			if !field.Embedded() && !field.Exported() {
+				continue
+			}
+
+			renderer.path = path + "." + field.Name()
+			renderer.addSymbol(renderer.path, field)
+
+			if field.Embedded() {
+				fields = append(fields, renderer.render(field.Type()))
+			} else {
+				fields = append(fields, field.Name()+" "+renderer.render(field.Type()))
+			}
+		}
+
+		renderer.path = path
+
+		// spaces around the fields, so nested structs never produce '}}' within the go:code comment
+		return "struct { " + strings.Join(fields, "; ") + " }"
+	}
+
+	return "any" // interfaces, type parameters
+}
+
+// Path of the symbol found at the byte 'offset' of the 'GoCode', see 'GoCodeSymbols'.
+// A type referenced (eg. '[]User') give the path of that type.
+// Return an empty string when there is no symbol there (eg. keyword, basic type)
+func FindSymbolPath(goCode string, offset int) string {
+	const header = "package p; "
+
+	fset := token.NewFileSet()
+
+	file, _ := parser.ParseFile(fset, "", header+goCode, parser.SkipObjectResolution)
+	if file == nil {
+		return ""
+	}
+
+	base := fset.File(file.Pos()).Base()
+	position := token.Pos(base + len(header) + offset)
+
+	contains := func(ident *ast.Ident) bool {
+		return ident != nil && ident.Pos() <= position && position < ident.End()
+	}
+
+	var findInType func(expr ast.Expr, path string) string
+	findInType = func(expr ast.Expr, path string) string {
+		switch node := expr.(type) {
+		case *ast.Ident:
+			if contains(node) && types.Universe.Lookup(node.Name) == nil {
+				return node.Name
+			}
+		case *ast.StarExpr:
+			return findInType(node.X, path)
+		case *ast.ArrayType:
+			return findInType(node.Elt, path)
+		case *ast.Ellipsis:
+			return findInType(node.Elt, path)
+		case *ast.ChanType:
+			return findInType(node.Value, path)
+		case *ast.MapType:
+			if symbolPath := findInType(node.Key, path); symbolPath != "" {
+				return symbolPath
+			}
+
+			return findInType(node.Value, path)
+		case *ast.FuncType:
+			for _, fields := range []*ast.FieldList{node.Params, node.Results} {
+				if fields == nil {
+					continue
+				}
+
+				for _, field := range fields.List {
+					if symbolPath := findInType(field.Type, ""); symbolPath != "" {
+						return symbolPath
+					}
+				}
+			}
+		case *ast.StructType:
+			for _, field := range node.Fields.List {
+				fieldPath := path
+
+				for _, name := range field.Names {
+					if contains(name) {
+						return path + "." + name.Name
+					}
+
+					fieldPath = path + "." + name.Name
+				}
+
+				if symbolPath := findInType(field.Type, fieldPath); symbolPath != "" {
+					return symbolPath
+				}
+			}
+		}
+
+		return ""
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+
+				if contains(typeSpec.Name) {
+					return typeSpec.Name.Name
+				}
+
+				if symbolPath := findInType(typeSpec.Type, typeSpec.Name.Name); symbolPath != "" {
+					return symbolPath
+				}
+			}
+		case *ast.FuncDecl:
+			receiver := ""
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				if symbolPath := findInType(decl.Recv.List[0].Type, ""); symbolPath != "" {
+					return symbolPath
+				}
+
+				if ident, ok := decl.Recv.List[0].Type.(*ast.Ident); ok {
+					receiver = ident.Name + "."
+				}
+			}
+
+			if contains(decl.Name) {
+				return receiver + decl.Name.Name
+			}
+
+			if symbolPath := findInType(decl.Type, ""); symbolPath != "" {
+				return symbolPath
+			}
+		}
+	}
+
+	return ""
+}
+
+func (renderer *goCodeRenderer) renderSignature(signature *types.Signature) string {
+	params := make([]string, 0, signature.Params().Len())
+
+	for index := range signature.Params().Len() {
+		param := signature.Params().At(index)
+
+		if slice, ok := param.Type().(*types.Slice); ok && signature.Variadic() && index == signature.Params().Len()-1 {
+			params = append(params, "..."+renderer.render(slice.Elem()))
+			continue
+		}
+
+		params = append(params, renderer.render(param.Type()))
+	}
+
+	results := make([]string, 0, signature.Results().Len())
+	for index := range signature.Results().Len() {
+		results = append(results, renderer.render(signature.Results().At(index).Type()))
+	}
+
+	rendered := "(" + strings.Join(params, ", ") + ")"
+
+	switch len(results) {
+	case 0:
+	case 1:
+		rendered += " " + results[0]
+	default:
+		rendered += " (" + strings.Join(results, ", ") + ")"
+	}
+
+	return rendered
+}
@@ -0,0 +1,195 @@
+package gosource
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverTemplateExecutions(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"models/user.go": `package models
+
+type User struct {
+	Name    string
+	Friends []*User
+	Tags    map[string]int
+	secret  string
+}
+
+func (u *User) Greeting(prefix string) string { return prefix + u.Name }
+
+func (u User) hidden() {}
+`,
+		"main.go": `package main
+
+import (
+	"html/template"
+	"os"
+
+	"example.com/app/models"
+	"github.com/unknown/dependency"
+)
+
+type server struct {
+	pages *template.Template
+}
+
+var layout = template.Must(template.ParseFiles("views/layout.html", "views/parts.html"))
+
+func main() {
+	layout.Execute(os.Stdout, models.User{Name: "a"})
+	layout.ExecuteTemplate(os.Stdout, "header", struct{ Title string }{Title: "b"})
+	layout.ExecuteTemplate(os.Stdout, "count", 42)
+	layout.ExecuteTemplate(os.Stdout, "nothing", nil)
+	layout.ExecuteTemplate(os.Stdout, "unknown", dependency.Value)
+
+	s := server{}
+	s.pages.ExecuteTemplate(os.Stdout, "home.html", &models.User{})
+}
+`,
+	})
+
+	packages := ParseWorkspacePackages(rootPath, nil)
+	CheckWorkspacePackages(rootPath, packages)
+
+	discovery := DiscoverTemplateSets(rootPath, packages)
+	sets, executions := discovery.Sets, discovery.Executions
+	if len(sets) != 1 || sets[0].RootName != "layout.html" {
+		t.Fatalf("expected a single template set rooted at 'layout.html', got %+v", sets)
+	}
+
+	userGoCode := "type Input struct { Name string; Friends []Input; Tags map[string]int }; func (Input) Greeting(string) string"
+
+	tests := []TemplateExecution{
+		{SetId: sets[0].Id, TemplateName: "layout.html", GoCode: userGoCode, CallSite: "main.go:18"},
+		{SetId: sets[0].Id, TemplateName: "header", GoCode: "type Input struct { Title string }", CallSite: "main.go:19"},
+		{SetId: sets[0].Id, TemplateName: "count", GoCode: "type Input int", CallSite: "main.go:20"},
+		{SetId: "", TemplateName: "home.html", GoCode: userGoCode, CallSite: "main.go:25"},
+	}
+
+	if len(executions) != len(tests) {
+		t.Fatalf("expected %d executions, got %d: %+v", len(tests), len(executions), executions)
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			if executions[count] != test {
+				t.Errorf("\n Expected: %+v \n Got: %+v", test, executions[count])
+			}
+		})
+	}
+
+	// line of the Go declaration of each symbol of the 'Input' type
+	symbols := map[string]int{"Input": 3, "Input.Name": 4, "Input.Friends": 5, "Input.Tags": 6, "Input.Greeting": 10}
+	headerSymbols := map[string]int{"Input.Title": 19}
+
+	for goCode, want := range map[string]map[string]int{userGoCode: symbols, "type Input struct { Title string }": headerSymbols} {
+		got := make(map[string]int)
+		for key, position := range discovery.Symbols[goCode] {
+			got[key] = position.Line
+		}
+
+		if !maps.Equal(got, want) {
+			t.Errorf("\n GoCode: %s \n Expected symbols: %v \n Got: %v", goCode, want, got)
+		}
+	}
+}
+
+func TestDiscoverTemplateCalls(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"main.go": `package main
+
+import (
+	"html/template"
+	"os"
+)
+
+const name = "footer"
+
+var pages = template.Must(template.ParseGlob("views/*.html"))
+
+func main() {
+	pages.ExecuteTemplate(os.Stdout, "heder", nil)
+	pages.ExecuteTemplate(os.Stdout, name, nil)
+	pages.ExecuteTemplate(os.Stdout, ` + "`home.html`" + `, 42)
+	pages.Execute(os.Stdout, nil)
+}
+`,
+	})
+
+	packages := ParseWorkspacePackages(rootPath, nil)
+	CheckWorkspacePackages(rootPath, packages)
+
+	discovery := DiscoverTemplateSets(rootPath, packages)
+	if len(discovery.Sets) != 1 {
+		t.Fatalf("expected a single template set, got %+v", discovery.Sets)
+	}
+
+	// line, start column and end column of the string literal, then of the data argument
+	tests := []struct {
+		name         string
+		goCode       string
+		namePosition [3]int
+		dataPosition [3]int
+	}{
+		{name: "heder", namePosition: [3]int{13, 35, 42}, dataPosition: [3]int{13, 44, 47}},
+		{name: "home.html", goCode: "type Input int", namePosition: [3]int{15, 35, 46}, dataPosition: [3]int{15, 48, 50}},
+	}
+
+	if len(discovery.Calls) != len(tests) {
+		t.Fatalf("expected %d calls, got %d: %+v", len(tests), len(discovery.Calls), discovery.Calls)
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			call := discovery.Calls[count]
+			namePosition := [3]int{call.NameStart.Line, call.NameStart.Column, call.NameEnd.Column}
+			dataPosition := [3]int{call.DataStart.Line, call.DataStart.Column, call.DataEnd.Column}
+
+			if call.TemplateName != test.name || call.GoCode != test.goCode || call.SetId != discovery.Sets[0].Id {
+				t.Errorf("\n Expected: %q, %q \n Got: %+v", test.name, test.goCode, call)
+			}
+
+			if namePosition != test.namePosition || dataPosition != test.dataPosition {
+				t.Errorf("\n Expected: %v, %v \n Got: %v, %v", test.namePosition, test.dataPosition, namePosition, dataPosition)
+			}
+		})
+	}
+}
+
+func TestFindSymbolPath(t *testing.T) {
+	goCode := "type Input struct { Name string; Address struct { Street string }; Friends []User }; type User struct { Age int }; func (Input) Greeting(User) string"
+
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{symbol: "Input struct", want: "Input"},
+		{symbol: "Name", want: "Input.Name"},
+		{symbol: "string;", want: ""},
+		{symbol: "Street", want: "Input.Address.Street"},
+		{symbol: "User }", want: "User"},
+		{symbol: "Age", want: "User.Age"},
+		{symbol: "Greeting", want: "Input.Greeting"},
+		{symbol: "User) string", want: "User"},
+		{symbol: "func", want: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			offset := strings.Index(goCode, test.symbol)
+			if offset < 0 {
+				t.Fatalf("symbol %q not found within the go code", test.symbol)
+			}
+
+			got := FindSymbolPath(goCode, offset)
+			if got != test.want {
+				t.Errorf("\n Symbol: %q \n Expected: %q \n Got: %q", test.symbol, test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package gosource
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Function of a 'template.FuncMap' given to '.Funcs()'.
+// 'GoCode' is the signature as declared within a 'go:code' comment, eg. 'func upper(string) string'
+type TemplateFunction struct {
+	SetId  string // empty when the template set is unknown
+	Name   string
+	GoCode string
+	Doc    string // doc comment of the Go function, without the comment markers
+
+	Definition token.Position // Go function, or function literal. Invalid for the functions of the other modules
+}
+
+func isFuncsCall(call *ast.CallExpr) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && selector.Sel.Name == "Funcs" && len(call.Args) == 1
+}
+
+// Functions of the 'FuncMap' argument of the '.Funcs()' call.
+// The argument is either a composite literal, or a variable initialized with one
+func newTemplateFunctions(pkg *GoPackage, call *ast.CallExpr, file *ast.File, set *TemplateSet, packagesByTypes map[*types.Package]*GoPackage) []TemplateFunction {
+	if pkg.Info == nil {
+		return nil
+	}
+
+	literal, literalFile := findFuncMapLiteral(call.Args[0], file, pkg)
+	if literal == nil {
+		return nil
+	}
+
+	var functions []TemplateFunction
+
+	for _, element := range literal.Elts {
+		keyValue, ok := element.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+
+		name, ok := EvaluateStringExpression(keyValue.Key, literalFile)
+		if !ok {
+			continue
+		}
+
+		signature, ok := types.Unalias(pkg.Info.TypeOf(keyValue.Value)).(*types.Signature)
+		if !ok {
+			continue
+		}
+
+		renderer := &goCodeRenderer{isOpaque: true}
+
+		function := TemplateFunction{
+			Name:   name,
+			GoCode: "func " + name + renderer.renderSignature(signature),
+			Doc:    findFunctionDoc(keyValue, literalFile, pkg, packagesByTypes),
+
+			Definition: findFunctionDefinition(keyValue, pkg, packagesByTypes),
+		}
+
+		if set != nil {
+			function.SetId = set.Id
+		}
+
+		functions = append(functions, function)
+	}
+
+	return functions
+}
+
+func findFuncMapLiteral(expr ast.Expr, file *ast.File, pkg *GoPackage) (*ast.CompositeLit, *ast.File) {
+	switch node := expr.(type) {
+	case *ast.CompositeLit:
+		return node, file
+	case *ast.ParenExpr:
+		return findFuncMapLiteral(node.X, file, pkg)
+	case *ast.Ident:
+		variable, ok := pkg.Info.Uses[node].(*types.Var)
+		if !ok {
+			return nil, nil
+		}
+
+		// initial value of the variable, looked up within the package only
+		for _, otherFile := range pkg.Files {
+			if otherFile.FileStart > variable.Pos() || variable.Pos() > otherFile.FileEnd {
+				continue
+			}
+
+			var value ast.Expr
+
+			ast.Inspect(otherFile, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.ValueSpec:
+					for index, name := range node.Names {
+						if pkg.Info.Defs[name] == variable && index < len(node.Values) {
+							value = node.Values[index]
+						}
+					}
+				case *ast.AssignStmt:
+					for index, lhs := range node.Lhs {
+						if name, ok := lhs.(*ast.Ident); ok && pkg.Info.Defs[name] == variable && index < len(node.Rhs) {
+							value = node.Rhs[index]
+						}
+					}
+				}
+
+				return value == nil
+			})
+
+			if literal, ok := value.(*ast.CompositeLit); ok {
+				return literal, otherFile
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func findFunctionDefinition(keyValue *ast.KeyValueExpr, pkg *GoPackage, packagesByTypes map[*types.Package]*GoPackage) token.Position {
+	switch value := keyValue.Value.(type) {
+	case *ast.Ident:
+		return getDeclarationPosition(pkg.Info.Uses[value], packagesByTypes)
+	case *ast.SelectorExpr:
+		return getDeclarationPosition(pkg.Info.Uses[value.Sel], packagesByTypes)
+	case *ast.FuncLit:
+		return pkg.Fset.Position(value.Pos())
+	}
+
+	return token.Position{}
+}
+
+// Doc comment of the function given as value, or the comment right above the map entry (eg. for function literals)
+func findFunctionDoc(keyValue *ast.KeyValueExpr, file *ast.File, pkg *GoPackage, packagesByTypes map[*types.Package]*GoPackage) string {
+	var function *types.Func
+
+	switch value := keyValue.Value.(type) {
+	case *ast.Ident:
+		function, _ = pkg.Info.Uses[value].(*types.Func)
+	case *ast.SelectorExpr:
+		function, _ = pkg.Info.Uses[value.Sel].(*types.Func)
+	}
+
+	if function != nil && function.Pkg() != nil {
+		if workspacePackage := packagesByTypes[function.Pkg()]; workspacePackage != nil {
+			position := workspacePackage.Fset.Position(function.Pos())
+			return findDocOfDeclaration(position, workspacePackage.Fset, workspacePackage.Files[position.Filename])
+		}
+
+		if isStandardPackage(function.Pkg().Path()) {
+			position := stdlibFset.Position(function.Pos())
+			fset, file := parseStandardFile(position.Filename)
+
+			return findDocOfDeclaration(position, fset, file)
+		}
+
+		return ""
+	}
+
+	entryLine := pkg.Fset.Position(keyValue.Pos()).Line
+
+	for _, group := range file.Comments {
+		if pkg.Fset.Position(group.End()).Line == entryLine-1 {
+			return strings.TrimSpace(group.Text())
+		}
+	}
+
+	return ""
+}
+
+// Doc comment of the function (or method) declared at 'position'
+func findDocOfDeclaration(position token.Position, fset *token.FileSet, file *ast.File) string {
+	if file == nil {
+		return ""
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Doc == nil {
+			continue
+		}
+
+		namePosition := fset.Position(funcDecl.Name.Pos())
+		if namePosition.Line == position.Line && namePosition.Column == position.Column {
+			return strings.TrimSpace(funcDecl.Doc.Text())
+		}
+	}
+
+	return ""
+}
+
+var (
+	standardFiles   = make(map[string]*standardFile)
+	muStandardFiles sync.Mutex
+)
+
+type standardFile struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+// Parse once (comments included) a file of '$GOROOT/src', since the comments are dropped by the type checker.
+// The file get its own file set, only the line and column of its positions are comparable with 'stdlibFset'
+func parseStandardFile(filename string) (*token.FileSet, *ast.File) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	muStandardFiles.Lock()
+	defer muStandardFiles.Unlock()
+
+	if cached, ok := standardFiles[filename]; ok {
+		return cached.fset, cached.file
+	}
+
+	fset := token.NewFileSet()
+	file, _ := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+
+	standardFiles[filename] = &standardFile{fset: fset, file: file}
+
+	return fset, file
+}
+
+// Go declaration of a builtin function of the templates (eg. 'len', 'printf'), found within the sources of 'text/template'.
+// Invalid when the function is unknown
+func FindBuiltinFunction(name string) token.Position {
+	_, file := parseStandardFile(filepath.Join(build.Default.GOROOT, "src", "text", "template", "funcs.go"))
+	if file == nil {
+		return token.Position{}
+	}
+
+	var value ast.Expr
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if funcDecl, ok := node.(*ast.FuncDecl); ok {
+			return funcDecl.Name.Name == "builtins" // the 'FuncMap' of the builtin functions
+		}
+
+		keyValue, ok := node.(*ast.KeyValueExpr)
+		if !ok {
+			return value == nil
+		}
+
+		if key, ok := keyValue.Key.(*ast.BasicLit); ok && key.Value == strconv.Quote(name) {
+			value = keyValue.Value
+		}
+
+		return false
+	})
+
+	switch value := value.(type) {
+	case *ast.Ident:
+		return findStandardFunction("text/template", value.Name)
+	case *ast.SelectorExpr:
+		packageName, ok := value.X.(*ast.Ident)
+		if !ok {
+			return token.Position{}
+		}
+
+		for _, spec := range file.Imports {
+			importPath, _ := strconv.Unquote(spec.Path.Value)
+			if path.Base(importPath) == packageName.Name {
+				return findStandardFunction(importPath, value.Sel.Name)
+			}
+		}
+	}
+
+	return token.Position{}
+}
+
+// Declaration of a package level function, searched within the files of '$GOROOT/src/<importPath>'
+func findStandardFunction(importPath string, name string) token.Position {
+	dir := filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(importPath))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return token.Position{}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		fset, file := parseStandardFile(filepath.Join(dir, entry.Name()))
+		if file == nil {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.Name == name {
+				return fset.Position(funcDecl.Name.Pos())
+			}
+		}
+	}
+
+	return token.Position{}
+}
@@ -0,0 +1,129 @@
+package gosource
+
+import (
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverTemplateFunctions(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"helpers/helpers.go": `package helpers
+
+import "time"
+
+type Status string
+
+type User struct{ Name string }
+
+// FormatDate render the date as '2006-01-02'
+func FormatDate(date time.Time) string { return date.Format("2006-01-02") }
+
+func Greet(user User, status Status) (string, error) { return "", nil }
+`,
+		"main.go": `package main
+
+import (
+	"html/template"
+	"strings"
+
+	"example.com/app/helpers"
+)
+
+var funcs = template.FuncMap{
+	"date":  helpers.FormatDate,
+	"greet": helpers.Greet,
+}
+
+var pages = template.Must(template.New("").Funcs(funcs).ParseGlob("views/*.html"))
+
+func main() {
+	mail := template.New("mail")
+	mail.Funcs(template.FuncMap{
+		"upper": strings.ToUpper,
+		// add sum the numbers
+		"add": func(numbers ...int) int { return 0 },
+	})
+	mail.ParseFiles("mail/base.html")
+}
+`,
+	})
+
+	packages := ParseWorkspacePackages(rootPath, nil)
+	CheckWorkspacePackages(rootPath, packages)
+
+	discovery := DiscoverTemplateSets(rootPath, packages)
+	if len(discovery.Sets) != 2 {
+		t.Fatalf("expected 2 template sets, got %+v", discovery.Sets)
+	}
+
+	pagesId, mailId := discovery.Sets[0].Id, discovery.Sets[1].Id
+
+	tests := []struct {
+		want      TemplateFunction
+		docPrefix string
+	}{
+		{want: TemplateFunction{SetId: pagesId, Name: "date", GoCode: "func date(any) string"}, docPrefix: "FormatDate render the date"},
+		{want: TemplateFunction{SetId: pagesId, Name: "greet", GoCode: "func greet(any, string) (string, error)"}},
+		{want: TemplateFunction{SetId: mailId, Name: "upper", GoCode: "func upper(string) string"}, docPrefix: "ToUpper returns s with all Unicode letters mapped to their upper case"},
+		{want: TemplateFunction{SetId: mailId, Name: "add", GoCode: "func add(...int) int"}, docPrefix: "add sum the numbers"},
+	}
+
+	if len(discovery.Functions) != len(tests) {
+		t.Fatalf("expected %d functions, got %d: %+v", len(tests), len(discovery.Functions), discovery.Functions)
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := discovery.Functions[count]
+			doc := got.Doc
+			got.Doc = ""
+
+			if !got.Definition.IsValid() {
+				t.Errorf("\n Expected the position of the Go function of %q", got.Name)
+			}
+
+			got.Definition = token.Position{}
+
+			if got != test.want {
+				t.Errorf("\n Expected: %+v \n Got: %+v", test.want, got)
+			}
+
+			if !strings.HasPrefix(doc, test.docPrefix) || (test.docPrefix == "" && doc != "") {
+				t.Errorf("\n Expected doc starting with: %q \n Got: %q", test.docPrefix, doc)
+			}
+		})
+	}
+}
+
+func TestFindBuiltinFunction(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantFile string
+	}{
+		{name: "len", wantFile: "funcs.go"},
+		{name: "printf", wantFile: "print.go"},
+		{name: "and", wantFile: "funcs.go"},
+		{name: "unknown", wantFile: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := FindBuiltinFunction(test.name)
+			if test.wantFile == "" {
+				if got.IsValid() {
+					t.Errorf("\n Expected no declaration \n Got: %v", got)
+				}
+
+				return
+			}
+
+			if !got.IsValid() || filepath.Base(got.Filename) != test.wantFile {
+				t.Errorf("\n Expected declaration within %q \n Got: %v", test.wantFile, got)
+			}
+		})
+	}
+}
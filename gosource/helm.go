@@ -0,0 +1,60 @@
+package gosource
+
+import "slices"
+
+// Built-in objects of the Helm templates (https://helm.sh/docs/chart_template_guide/builtin_objects/), but '.Values'
+// whose type come from the chart itself
+const helmDeclarations = `type Release struct { Name string; Namespace string; IsUpgrade bool; IsInstall bool; Revision int; Service string }
+type Chart struct {
+	Name string; Version string; AppVersion string; Description string; Type string; APIVersion string; KubeVersion string; Home string; Icon string
+	Keywords []string; Sources []string; Maintainers []ChartMaintainer; Dependencies []ChartDependency; Annotations map[string]string; Deprecated bool
+}
+type ChartMaintainer struct { Name string; Email string; URL string }
+type ChartDependency struct { Name string; Version string; Repository string; Condition string; Tags []string; Enabled bool; Alias string }
+type Capabilities struct { APIVersions VersionSet; KubeVersion KubeVersion; HelmVersion HelmVersion }
+type VersionSet []string
+func (VersionSet) Has(apiVersion string) bool
+type KubeVersion struct { Version string; Major string; Minor string }
+func (KubeVersion) String() string; func (KubeVersion) GitVersion() string
+type HelmVersion struct { Version string; GitCommit string; GitTreeState string; GoVersion string }
+type Files map[string][]byte
+func (Files) Get(name string) string; func (Files) GetBytes(name string) []byte; func (Files) Glob(pattern string) Files; func (Files) Lines(path string) []string
+func (Files) AsConfig() string; func (Files) AsSecrets() string
+type Template struct { Name string; BasePath string }`
+
+// Functions added by Helm on top of Sprig (https://helm.sh/docs/howto/charts_tips_and_tricks/)
+var helmFunctions = []libraryFunction{
+	{"include", "(name string, data any) (string, error)", "Execute the named template and return its output, which can be piped (eg. 'include \"mychart.labels\" . | indent 4')"},
+	{"tpl", "(template string, data any) (string, error)", "Execute the string as a template (eg. a value of 'values.yaml' holding actions)"},
+	{"required", "(message string, value any) (any, error)", "Fail the rendering with the message when the value is empty, return the value otherwise"},
+	{"lookup", "(apiVersion string, kind string, namespace string, name string) (map[string]any, error)", "Resource of the cluster, empty during 'helm template' and '--dry-run'. An empty 'name' list the resources of the kind"},
+	{"toYaml", "(value any) string", "Encode the value as YAML, empty when it cannot be encoded"},
+	{"toYamlPretty", "(value any) string", "Encode the value as YAML, with the lists indented within their parent"},
+	{"mustToYaml", "(value any) (string, error)", "Encode the value as YAML, fail the rendering when it cannot be encoded"},
+	{"fromYaml", "(s string) map[string]any", "Decode the YAML document into a map, the error is stored under the 'Error' key"},
+	{"fromYamlArray", "(s string) []any", "Decode the YAML list, the error is the only item on failure"},
+	{"toJson", "(value any) string", "Encode the value as JSON, empty when it cannot be encoded"},
+	{"mustToJson", "(value any) (string, error)", "Encode the value as JSON, fail the rendering when it cannot be encoded"},
+	{"fromJson", "(s string) map[string]any", "Decode the JSON object into a map, the error is stored under the 'Error' key"},
+	{"fromJsonArray", "(s string) []any", "Decode the JSON list, the error is the only item on failure"},
+	{"toToml", "(value any) string", "Encode the value as TOML, the error message when it cannot be encoded"},
+	{"fromToml", "(s string) map[string]any", "Decode the TOML document into a map, the error is stored under the 'Error' key"},
+}
+
+// Sprig functions removed by Helm, for the security of the rendering
+var helmRemovedFunctions = []string{"env", "expandenv"}
+
+// Functions of Helm, then the ones of Sprig that Helm neither override nor remove
+func getHelmLibraryFunctions() []libraryFunction {
+	functions := slices.Clone(helmFunctions)
+
+	for _, function := range sprigFunctions {
+		isOverridden := slices.ContainsFunc(helmFunctions, func(helmFunction libraryFunction) bool { return helmFunction.Name == function.Name })
+
+		if !isOverridden && !slices.Contains(helmRemovedFunctions, function.Name) {
+			functions = append(functions, function)
+		}
+	}
+
+	return functions
+}
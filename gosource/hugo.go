@@ -0,0 +1,319 @@
+package gosource
+
+// Types of the Hugo template context (https://gohugo.io/methods/), and of the namespaces of its functions (eg. 'resources.Get').
+// Only the members commonly used within the templates are declared, and the Go types of Hugo are loosened to 'any' where
+// they are opaque to the templates (eg. 'MediaType').
+// The namespaces are functions without argument returning the namespace, like the 'FuncMap' of Hugo do
+const hugoDeclarations = `type HTML string
+type Time interface {
+	Format(layout string) string; Year() int; Month() any; Day() int; Hour() int; Minute() int; Second() int; Weekday() any; YearDay() int
+	Unix() int64; IsZero() bool; Before(other Time) bool; After(other Time) bool; Equal(other Time) bool
+	Add(duration any) Time; AddDate(years int, months int, days int) Time; Sub(other Time) any; UTC() Time; Local() Time; String() string
+}
+type Page interface {
+	Title() string; LinkTitle() string; Description() string; Keywords() []string; Summary() HTML; Content() HTML; ContentWithoutSummary() HTML
+	Plain() string; RawContent() string; TableOfContents() HTML; Truncated() bool; WordCount() int; FuzzyWordCount() int; ReadingTime() int; Len() int
+	Draft() bool; Weight() int; Aliases() []string; Slug() string; Date() Time; Lastmod() Time; PublishDate() Time; ExpiryDate() Time
+	Permalink() string; RelPermalink() string; Path() string; Name() string; Kind() string; Type() string; Layout() string; Section() string; BundleType() string
+	IsHome() bool; IsPage() bool; IsSection() bool; IsNode() bool; IsTranslated() bool
+	Params() map[string]any; Param(key any) (any, error); Data() any; Store() Scratch; Scratch() Scratch
+	Site() Site; Sites() Sites; File() File; Language() Language; Lang() string; Translations() Pages; AllTranslations() Pages
+	Parent() Page; Ancestors() Pages; FirstSection() Page; CurrentSection() Page; Sections() Pages; Pages() Pages; RegularPages() Pages; RegularPagesRecursive() Pages
+	Next() Page; Prev() Page; NextInSection() Page; PrevInSection() Page; GetPage(path string) (Page, error); GetTerms(taxonomy string) Pages; HasShortcode(name string) bool
+	IsAncestor(other any) bool; IsDescendant(other any) bool; InSection(other any) bool; Eq(other any) bool
+	HasMenuCurrent(menu string, entry any) bool; IsMenuCurrent(menu string, entry any) bool
+	Resources() Resources; OutputFormats() OutputFormats; AlternativeOutputFormats() OutputFormats
+	Paginate(pages any, pagerSize ...any) (Pager, error); Paginator() (Pager, error); Render(layout ...string) (HTML, error); Page() Page
+}
+type Pages []Page
+func (Pages) ByDate() Pages; func (Pages) ByExpiryDate() Pages; func (Pages) ByLastmod() Pages; func (Pages) ByLength() Pages; func (Pages) ByLinkTitle() Pages
+func (Pages) ByParam(key any) Pages; func (Pages) ByPublishDate() Pages; func (Pages) ByTitle() Pages; func (Pages) ByWeight() Pages; func (Pages) Reverse() Pages
+func (Pages) Len() int; func (Pages) Next(current Page) Page; func (Pages) Prev(current Page) Page; func (Pages) Related(options any) (Pages, error)
+func (Pages) GroupBy(key string, order ...string) (any, error); func (Pages) GroupByDate(format string, order ...string) (any, error); func (Pages) GroupByParam(key string, order ...string) (any, error)
+type Site interface {
+	Title() string; BaseURL() string; Copyright() string; Home() (Page, error); Pages() Pages; RegularPages() Pages; AllPages() Pages; Sections() Pages
+	GetPage(path ...string) (Page, error); Params() map[string]any; Param(key any) (any, error); Data() map[string]any; Menus() map[string]Menu
+	Taxonomies() map[string]Taxonomy; Language() Language; LanguageCode() string; Languages() []Language; IsMultiLingual() bool; Sites() Sites
+	Lastmod() Time; BuildDrafts() bool; Config() any; Store() Scratch
+}
+type Sites []Site
+func (Sites) Default() Site
+type File interface {
+	Path() string; Filename() string; BaseFileName() string; ContentBaseName() string; Dir() string; Ext() string
+	LogicalName() string; TranslationBaseName() string; UniqueID() string; Lang() string; Section() string
+}
+type Language interface { Lang() string; LanguageName() string; LanguageCode() string; LanguageDirection() string; Weight() int }
+type Scratch interface {
+	Get(key string) any; Set(key string, value any) string; Add(key string, value any) (string, error); SetInMap(key string, mapKey string, value any) string
+	DeleteInMap(key string, mapKey string) string; GetSortedMapValues(key string) any; Delete(key string) string; Values() map[string]any
+}
+type Menu []MenuEntry
+func (Menu) ByName() Menu; func (Menu) ByWeight() Menu; func (Menu) Limit(n int) Menu; func (Menu) Reverse() Menu
+type MenuEntry interface {
+	Name() string; Title() string; URL() string; Identifier() string; Weight() int; Parent() string; Pre() HTML; Post() HTML; KeyName() string
+	Page() Page; Params() map[string]any; Children() Menu; HasChildren() bool
+}
+type Taxonomy map[string]any
+func (Taxonomy) Alphabetical() any; func (Taxonomy) ByCount() any; func (Taxonomy) Count(key string) int; func (Taxonomy) Get(key string) any; func (Taxonomy) Page() Page
+type OutputFormats []OutputFormat
+func (OutputFormats) Get(name string) OutputFormat
+type OutputFormat interface { Name() string; Rel() string; Permalink() string; RelPermalink() string; MediaType() any }
+type Pager interface {
+	Pages() Pages; PageNumber() int; PageSize() int; TotalPages() int; TotalNumberOfElements() int; NumberOfElements() int
+	HasPrev() bool; HasNext() bool; Prev() Pager; Next() Pager; First() Pager; Last() Pager; Pagers() []Pager; URL() HTML
+}
+type Resource interface {
+	Name() string; Title() string; Key() string; Permalink() string; RelPermalink() string; ResourceType() string; MediaType() any
+	Content() (any, error); Data() any; Params() map[string]any; Err() any; Publish() error
+	Width() int; Height() int; Exif() any; Resize(spec string) (Resource, error); Fit(spec string) (Resource, error); Fill(spec string) (Resource, error)
+	Crop(spec string) (Resource, error); Process(spec string) (Resource, error); Filter(filters ...any) (Resource, error)
+}
+type Resources []Resource
+func (Resources) ByType(mediaType any) Resources; func (Resources) Get(name any) Resource; func (Resources) GetMatch(pattern any) Resource; func (Resources) Match(pattern any) Resources
+type Shortcode interface {
+	Get(key any) any; Inner() HTML; InnerDeindent() HTML; Name() string; Ordinal() int; IsNamedParams() bool; Params() any
+	Page() Page; Site() Site; Parent() Shortcode; Position() any; Store() Scratch; Scratch() Scratch
+}
+type CollectionsNamespace interface {
+	After(index any, list any) (any, error); Append(args ...any) (any, error); Apply(list any, name string, args ...any) (any, error)
+	Complement(lists ...any) (any, error); Delimit(list any, separator any, last ...any) (string, error); Dictionary(values ...any) (map[string]any, error)
+	First(limit any, list any) (any, error); Group(key any, items any) (any, error); In(list any, value any) (bool, error); Index(item any, indices ...any) (any, error)
+	Intersect(a any, b any) (any, error); IsSet(collection any, key any) (bool, error); KeyVals(key any, values ...any) (any, error); Last(limit any, list any) (any, error)
+	Merge(params ...any) (any, error); NewScratch() Scratch; Querify(params ...any) (string, error); Reverse(list any) (any, error); Seq(args ...any) ([]int, error)
+	Shuffle(list any) (any, error); Slice(args ...any) any; Sort(list any, args ...any) (any, error); SymDiff(a any, b any) (any, error)
+	Union(a any, b any) (any, error); Uniq(list any) (any, error); Where(collection any, key any, args ...any) (any, error)
+}
+type CompareNamespace interface {
+	Default(fallback any, given ...any) (any, error); Conditional(condition bool, whenTrue any, whenFalse any) any
+	Eq(first any, others ...any) bool; Ne(a any, b any) bool; Ge(a any, b any) bool; Gt(a any, b any) bool; Le(a any, b any) bool; Lt(a any, b any) bool
+}
+type CastNamespace interface { ToInt(value any) (int, error); ToFloat(value any) (float64, error); ToString(value any) (string, error) }
+type CryptoNamespace interface {
+	MD5(value any) (string, error); SHA1(value any) (string, error); SHA256(value any) (string, error); FNV32a(value any) (int, error)
+	HMAC(hash any, key any, message any, encoding ...any) (string, error)
+}
+type CssNamespace interface {
+	Sass(args ...any) (Resource, error); PostCSS(args ...any) (Resource, error); TailwindCSS(args ...any) (Resource, error); Quoted(value any) any; Unquoted(value any) any
+}
+type DebugNamespace interface { Dump(value any) string; Timer(name string) any }
+type EncodingNamespace interface { Base64Decode(content any) (string, error); Base64Encode(content any) (string, error); Jsonify(args ...any) (HTML, error) }
+type FmtNamespace interface {
+	Print(args ...any) string; Printf(format string, args ...any) string; Println(args ...any) string
+	Errorf(format string, args ...any) string; Erroridf(id string, format string, args ...any) string; Warnf(format string, args ...any) string; Warnidf(id string, format string, args ...any) string
+}
+type HashNamespace interface { FNV32a(value any) (int, error); XxHash(value any) (string, error) }
+type HugoNamespace interface {
+	Version() string; Environment() string; IsProduction() bool; IsDevelopment() bool; IsServer() bool; IsExtended() bool; IsMultihost() bool; IsMultilingual() bool
+	Generator() HTML; BuildDate() string; CommitHash() string; GoVersion() string; WorkingDir() string; Store() Scratch; Deps() any
+}
+type ImagesNamespace interface {
+	Filter(args ...any) (Resource, error); Config(path any) (any, error); QR(args ...any) (Resource, error); Process(spec any) any
+	Brightness(percentage any) any; Contrast(percentage any) any; GaussianBlur(sigma any) any; Grayscale() any; Overlay(src Resource, x any, y any) any; Text(text string, options ...any) any
+}
+type InflectNamespace interface { Humanize(value any) (string, error); Pluralize(value any) (string, error); Singularize(value any) (string, error) }
+type LangNamespace interface {
+	Translate(id any, args ...any) (string, error); Merge(a any, b any) (any, error); FormatNumber(precision any, number any) (string, error)
+	FormatPercent(precision any, number any) (string, error); FormatCurrency(precision any, currency any, number any) (string, error)
+	FormatAccounting(precision any, currency any, number any) (string, error); FormatNumberCustom(precision any, number any, options ...any) (string, error)
+}
+type MathNamespace interface {
+	Abs(n any) (float64, error); Add(inputs ...any) (any, error); Sub(inputs ...any) (any, error); Mul(inputs ...any) (any, error); Div(inputs ...any) (any, error)
+	Mod(a any, b any) (int64, error); ModBool(a any, b any) (bool, error); Ceil(n any) (float64, error); Floor(n any) (float64, error); Round(n any) (float64, error)
+	Log(n any) (float64, error); Pow(a any, b any) (float64, error); Sqrt(n any) (float64, error); Max(inputs ...any) (float64, error); Min(inputs ...any) (float64, error)
+	Sum(inputs ...any) (any, error); Product(inputs ...any) (any, error); Rand() float64; Counter() uint64
+}
+type OsNamespace interface {
+	Getenv(key any) (string, error); ReadFile(filename any) (string, error); ReadDir(path any) ([]any, error); FileExists(path any) (bool, error); Stat(path any) (any, error)
+}
+type PartialsNamespace interface { Include(name string, context ...any) (any, error); IncludeCached(name string, context any, variants ...any) (any, error) }
+type PathNamespace interface {
+	Base(path any) (string, error); BaseName(path any) (string, error); Clean(path any) (string, error); Dir(path any) (string, error)
+	Ext(path any) (string, error); Join(elements ...any) (string, error); Split(path any) (any, error)
+}
+type ReflectNamespace interface { IsMap(value any) bool; IsSlice(value any) bool }
+type ResourcesNamespace interface {
+	Get(filename any) Resource; GetMatch(pattern any) Resource; Match(pattern any) Resources; ByType(mediaType any) Resources; GetRemote(args ...any) Resource
+	FromString(targetPath any, content any) Resource; ExecuteAsTemplate(targetPath any, data any, resource Resource) (Resource, error)
+	Concat(targetPath any, resources any) (Resource, error); Copy(targetPath any, resource Resource) (Resource, error); Fingerprint(args ...any) (Resource, error)
+	Minify(resource Resource) (Resource, error); PostProcess(resource Resource) (Resource, error)
+}
+type SafeNamespace interface {
+	CSS(s any) (any, error); HTML(s any) (HTML, error); HTMLAttr(s any) (any, error); JS(s any) (any, error); JSStr(s any) (any, error); URL(s any) (any, error)
+}
+type StringsNamespace interface {
+	Chomp(s any) (any, error); Contains(s any, substr any) (bool, error); ContainsAny(s any, chars any) (bool, error); ContainsNonSpace(s any) (bool, error)
+	Count(substr any, s any) (int, error); CountRunes(s any) (int, error); CountWords(s any) (int, error); Diff(oldName string, old any, newName string, new any) HTML
+	FindRE(expr string, content any, limit ...any) ([]string, error); FindRESubmatch(expr string, content any, limit ...any) ([][]string, error); FirstUpper(s any) (string, error)
+	HasPrefix(s any, prefix any) (bool, error); HasSuffix(s any, suffix any) (bool, error); Repeat(n any, s any) (string, error)
+	Replace(s any, old any, new any, limit ...any) (string, error); ReplaceRE(pattern any, replacement any, s any, n ...any) (string, error); RuneCount(s any) (int, error)
+	SliceString(s any, startEnd ...any) (string, error); Split(s any, delimiter string) ([]string, error); Substr(s any, nums ...any) (string, error)
+	Title(s any) (string, error); ToLower(s any) (string, error); ToUpper(s any) (string, error); Trim(s any, cutset any) (string, error)
+	TrimLeft(cutset any, s any) (string, error); TrimPrefix(prefix any, s any) (string, error); TrimRight(cutset any, s any) (string, error)
+	TrimSpace(s any) (string, error); TrimSuffix(suffix any, s any) (string, error); Truncate(s any, options ...any) (HTML, error)
+}
+type TemplatesNamespace interface { Exists(name string) bool }
+type TimeNamespace interface {
+	AsTime(value any, location ...any) (Time, error); Format(layout string, value any) (string, error); Now() Time
+	ParseDuration(duration any) (any, error); Duration(unit any, number any) (any, error)
+}
+type TransformNamespace interface {
+	Markdownify(s any) (HTML, error); Plainify(s any) (string, error); Emojify(s any) (HTML, error); Highlight(code any, lang string, options ...any) (HTML, error)
+	CanHighlight(lang string) bool; HTMLEscape(s any) (string, error); HTMLUnescape(s any) (string, error); XMLEscape(s any) (string, error)
+	Unmarshal(args ...any) (any, error); Remarshal(format string, data any) (string, error); ToMath(args ...any) (HTML, error)
+}
+type UrlsNamespace interface {
+	AbsURL(s any) (string, error); RelURL(s any) (string, error); AbsLangURL(s any) (string, error); RelLangURL(s any) (string, error)
+	Ref(page any, args any) (string, error); RelRef(page any, args any) (string, error); Parse(rawURL any) (any, error)
+	URLize(s any) (string, error); Anchorize(s any) (string, error); JoinPath(elements ...any) (string, error)
+}`
+
+// Functions of Hugo (https://gohugo.io/functions/), the aliases known without their namespace and the namespaces themselves.
+// The builtin functions of 'text/template' that Hugo override (eg. 'index', 'slice', 'js') are left to the builtins
+var hugoFunctions = []libraryFunction{
+	// namespaces
+	{"collections", "() CollectionsNamespace", "Functions of lists and maps (eg. 'collections.Where')"},
+	{"compare", "() CompareNamespace", "Functions of comparison (eg. 'compare.Default')"},
+	{"cast", "() CastNamespace", "Functions of conversion (eg. 'cast.ToInt')"},
+	{"crypto", "() CryptoNamespace", "Functions of hashing (eg. 'crypto.SHA256')"},
+	{"css", "() CssNamespace", "Functions of CSS processing (eg. 'css.Sass')"},
+	{"debug", "() DebugNamespace", "Functions of debugging (eg. 'debug.Dump')"},
+	{"encoding", "() EncodingNamespace", "Functions of encoding (eg. 'encoding.Jsonify')"},
+	{"fmt", "() FmtNamespace", "Functions of formatting and logging (eg. 'fmt.Warnf')"},
+	{"hash", "() HashNamespace", "Functions of non-cryptographic hashing (eg. 'hash.FNV32a')"},
+	{"hugo", "() HugoNamespace", "Information about the Hugo build (eg. 'hugo.IsProduction')"},
+	{"images", "() ImagesNamespace", "Functions and filters of image processing (eg. 'images.Filter')"},
+	{"inflect", "() InflectNamespace", "Functions of inflection (eg. 'inflect.Pluralize')"},
+	{"lang", "() LangNamespace", "Functions of translation and localized formatting (eg. 'lang.FormatNumber')"},
+	{"math", "() MathNamespace", "Functions of arithmetic (eg. 'math.Round')"},
+	{"os", "() OsNamespace", "Functions of the file system and the environment (eg. 'os.ReadFile')"},
+	{"partials", "() PartialsNamespace", "Functions executing the partial templates (eg. 'partials.Include')"},
+	{"path", "() PathNamespace", "Functions of slash separated paths (eg. 'path.Join')"},
+	{"reflect", "() ReflectNamespace", "Functions of reflection (eg. 'reflect.IsMap')"},
+	{"resources", "() ResourcesNamespace", "Functions of the global resources, within the 'assets' directory (eg. 'resources.Get')"},
+	{"safe", "() SafeNamespace", "Functions marking content as safe from escaping (eg. 'safe.HTML')"},
+	{"strings", "() StringsNamespace", "Functions of strings (eg. 'strings.Contains')"},
+	{"templates", "() TemplatesNamespace", "Functions of the templates (eg. 'templates.Exists')"},
+	{"time", "(value ...any) TimeNamespace", "Functions of dates (eg. 'time.Format'). Given a value, 'time' convert it to a date like 'time.AsTime', which is not reflected by its type"},
+	{"transform", "() TransformNamespace", "Functions of content transformation (eg. 'transform.Unmarshal')"},
+	{"urls", "() UrlsNamespace", "Functions of URLs (eg. 'urls.RelURL')"},
+
+	// global context
+	{"site", "() Site", "Site of the current language, available everywhere (eg. within a partial without context)"},
+	{"page", "() Page", "Page being rendered, available everywhere (eg. within a partial without context)"},
+
+	// partials
+	{"partial", "(name string, context ...any) (any, error)", "Execute the partial template of 'layouts/partials' with the given context"},
+	{"partialCached", "(name string, context any, variants ...any) (any, error)", "Same as 'partial', but the result is cached for each combination of 'name' and 'variants'"},
+
+	// collections
+	{"after", "(index any, list any) (any, error)", "Every item of the list after the first 'index' items"},
+	{"append", "(args ...any) (any, error)", "New list with the values appended to the list given last"},
+	{"apply", "(list any, name string, args ...any) (any, error)", "New list with the function 'name' applied to every item, '.' within 'args' is the item"},
+	{"complement", "(lists ...any) (any, error)", "Items of the last list that are not within any of the other lists"},
+	{"delimit", "(list any, separator any, last ...any) (string, error)", "Join the items of the list into a single string, separated by 'separator' ('last' before the last item)"},
+	{"dict", "(values ...any) (map[string]any, error)", "Map from the key/value pairs (eg. 'dict \"title\" .Title')"},
+	{"first", "(limit any, list any) (any, error)", "First 'limit' items of the list"},
+	{"group", "(key any, items any) (any, error)", "Group the pages under the key, like the result of 'GroupBy'"},
+	{"in", "(list any, value any) (bool, error)", "Whether the list (or string) contains the value"},
+	{"intersect", "(a any, b any) (any, error)", "Items common to both lists"},
+	{"isset", "(collection any, key any) (bool, error)", "Whether the key (or index) of the map (or list) is set"},
+	{"keyVals", "(key any, values ...any) (any, error)", "Key along its values, for 'Related'"},
+	{"last", "(limit any, list any) (any, error)", "Last 'limit' items of the list"},
+	{"merge", "(maps ...any) (any, error)", "Deep merge of the maps, the keys of the last map win"},
+	{"newScratch", "() Scratch", "New scratch, a store of values local to the template"},
+	{"querify", "(params ...any) (string, error)", "URL query string from the key/value pairs"},
+	{"reverse", "(list any) (any, error)", "New list with the items in reverse order"},
+	{"seq", "(args ...any) ([]int, error)", "Sequence of integers, like the 'seq' command (eg. 'seq 1 2 10')"},
+	{"shuffle", "(list any) (any, error)", "New list with the items in random order"},
+	{"sort", "(list any, args ...any) (any, error)", "Sort the list (or map), by the key given first and in the order given last ('asc' or 'desc')"},
+	{"symdiff", "(a any, b any) (any, error)", "Items found within only one of the lists"},
+	{"union", "(a any, b any) (any, error)", "Items found within either list, without duplicates"},
+	{"uniq", "(list any) (any, error)", "New list with the duplicates removed"},
+	{"where", "(collection any, key any, args ...any) (any, error)", "Items whose key match the value, with an optional operator (eg. 'where .Pages \"Section\" \"posts\"', 'where .Pages \"Weight\" \">\" 10')"},
+
+	// comparison and conversion, 'default', 'int' and 'string' are also Go keyword and types
+	{"default", "(fallback any, given ...any) (any, error)", "'fallback' when the given value is not set (zero value, empty list or map), the given value otherwise"},
+	{"cond", "(condition bool, whenTrue any, whenFalse any) any", "'whenTrue' when the condition is true, 'whenFalse' otherwise. Both values are evaluated"},
+	{"int", "(value any) (int, error)", "Convert the value to an integer"},
+	{"float", "(value any) (float64, error)", "Convert the value to a float"},
+	{"string", "(value any) (string, error)", "Convert the value to a string"},
+
+	// strings
+	{"chomp", "(s any) (any, error)", "Remove the trailing new line characters of the string"},
+	{"countrunes", "(s any) (int, error)", "Number of runes of the string, white space excluded"},
+	{"countwords", "(s any) (int, error)", "Number of words of the string"},
+	{"findRE", "(expr string, content any, limit ...any) ([]string, error)", "Matches of the regular expression, at most 'limit'"},
+	{"findRESubmatch", "(expr string, content any, limit ...any) ([][]string, error)", "Matches of the regular expression along their submatches, at most 'limit'"},
+	{"hasPrefix", "(s any, prefix any) (bool, error)", "Whether the string starts with 'prefix'"},
+	{"hasSuffix", "(s any, suffix any) (bool, error)", "Whether the string ends with 'suffix'"},
+	{"lower", "(s any) (string, error)", "Convert the string to lowercase"},
+	{"upper", "(s any) (string, error)", "Convert the string to uppercase"},
+	{"title", "(s any) (string, error)", "Convert the string to title case, following the 'titleCaseStyle' of the site configuration"},
+	{"replace", "(s any, old any, new any, limit ...any) (string, error)", "Replace the occurrences of 'old' by 'new', at most 'limit'"},
+	{"replaceRE", "(pattern any, replacement any, s any, n ...any) (string, error)", "Replace the matches of the regular expression, '$1' within the replacement is the first submatch"},
+	{"slicestr", "(s any, startEnd ...any) (string, error)", "Part of the string, from 'start' up to 'end' (excluded)"},
+	{"split", "(s any, delimiter string) ([]string, error)", "Split the string into a list of strings"},
+	{"substr", "(s any, nums ...any) (string, error)", "Part of the string, from 'start' and of 'length' runes (eg. 'substr \"abcdef\" 1 3' is 'bcd')"},
+	{"trim", "(s any, cutset any) (string, error)", "Remove the characters of 'cutset' from both sides of the string"},
+	{"truncate", "(s any, options ...any) (HTML, error)", "Truncate the text to the given length, without breaking words or HTML tags (eg. 'truncate 10 \"...\" .Summary')"},
+	{"humanize", "(value any) (string, error)", "Humanize the string, or the number as an ordinal (eg. '1st')"},
+	{"pluralize", "(value any) (string, error)", "Plural form of the word"},
+	{"singularize", "(value any) (string, error)", "Singular form of the word"},
+	{"anchorize", "(s any) (string, error)", "Convert the string to an anchor, like the ids of the headings"},
+	{"urlize", "(s any) (string, error)", "Convert the string to a path segment of URL"},
+
+	// formatting and logging
+	{"errorf", "(format string, args ...any) string", "Log the error and fail the build"},
+	{"erroridf", "(id string, format string, args ...any) string", "Same as 'errorf', the error can be ignored by its id within the site configuration"},
+	{"warnf", "(format string, args ...any) string", "Log the warning"},
+	{"warnidf", "(id string, format string, args ...any) string", "Same as 'warnf', the warning can be ignored by its id within the site configuration"},
+	{"dateFormat", "(layout string, value any) (string, error)", "Format the date, with the layout of the Go 'time' package (eg. '2006-01-02')"},
+	{"now", "() Time", "Current date and time"},
+	{"duration", "(unit any, number any) (any, error)", "Duration of 'number' units (eg. 'duration \"minute\" 5')"},
+
+	// content transformation and safety
+	{"markdownify", "(s any) (HTML, error)", "Render the markdown to HTML"},
+	{"plainify", "(s any) (string, error)", "Remove the HTML tags of the string"},
+	{"emojify", "(s any) (HTML, error)", "Replace the emoji shortcodes (eg. ':smile:') by the emoji"},
+	{"highlight", "(code any, lang string, options ...any) (HTML, error)", "Render the code with syntax highlighting"},
+	{"htmlEscape", "(s any) (string, error)", "Escape the special characters of HTML ('<', '>', '&', ''' and '\"')"},
+	{"htmlUnescape", "(s any) (string, error)", "Unescape the HTML entities of the string"},
+	{"jsonify", "(args ...any) (HTML, error)", "Encode the value as JSON, options first when given (eg. 'jsonify (dict \"indent\" \"  \") .')"},
+	{"unmarshal", "(args ...any) (any, error)", "Decode the JSON, TOML, YAML, XML or CSV data of the string or resource"},
+	{"safeCSS", "(s any) (any, error)", "Mark the string as safe CSS"},
+	{"safeHTML", "(s any) (HTML, error)", "Mark the string as safe HTML, it is not escaped"},
+	{"safeHTMLAttr", "(s any) (any, error)", "Mark the string as a safe HTML attribute"},
+	{"safeJS", "(s any) (any, error)", "Mark the string as safe JavaScript"},
+	{"safeJSStr", "(s any) (any, error)", "Mark the string as a safe JavaScript string"},
+	{"safeURL", "(s any) (any, error)", "Mark the string as a safe URL"},
+
+	// urls
+	{"absURL", "(s any) (string, error)", "Absolute URL, from the 'baseURL' of the site configuration"},
+	{"relURL", "(s any) (string, error)", "URL relative to the host, from the 'baseURL' of the site configuration"},
+	{"absLangURL", "(s any) (string, error)", "Same as 'absURL', with the language prefix"},
+	{"relLangURL", "(s any) (string, error)", "Same as 'relURL', with the language prefix"},
+	{"ref", "(page any, args any) (string, error)", "Absolute permalink of the page found at the path"},
+	{"relref", "(page any, args any) (string, error)", "Relative permalink of the page found at the path"},
+
+	// math, crypto and encoding
+	{"add", "(inputs ...any) (any, error)", "Sum of the numbers, or concatenation of the strings"},
+	{"sub", "(inputs ...any) (any, error)", "Subtract the numbers from the first one"},
+	{"mul", "(inputs ...any) (any, error)", "Product of the numbers"},
+	{"div", "(inputs ...any) (any, error)", "Divide the first number by the others"},
+	{"mod", "(a any, b any) (int64, error)", "Remainder of the division of 'a' by 'b'"},
+	{"modBool", "(a any, b any) (bool, error)", "Whether 'a' is divisible by 'b'"},
+	{"md5", "(value any) (string, error)", "MD5 digest of the string, hex encoded"},
+	{"sha1", "(value any) (string, error)", "SHA1 digest of the string, hex encoded"},
+	{"sha256", "(value any) (string, error)", "SHA256 digest of the string, hex encoded"},
+	{"hmac", "(hash any, key any, message any, encoding ...any) (string, error)", "HMAC of the message, with the hash function 'md5', 'sha1', 'sha256' or 'sha512'"},
+	{"base64Decode", "(content any) (string, error)", "Decode the Base64 string"},
+	{"base64Encode", "(content any) (string, error)", "Encode the string with Base64"},
+
+	// os, i18n and templates
+	{"getenv", "(key any) (string, error)", "Value of the environment variable, when allowed by the security policy of the site configuration"},
+	{"readDir", "(path any) ([]any, error)", "Content of the directory, relative to the project root"},
+	{"readFile", "(filename any) (string, error)", "Content of the file, relative to the project root"},
+	{"fileExists", "(path any) (bool, error)", "Whether the file (or directory) exists, relative to the project root"},
+	{"i18n", "(id any, args ...any) (string, error)", "Translation of the id, from the 'i18n' directory"},
+	{"T", "(id any, args ...any) (string, error)", "Same as 'i18n'"},
+}
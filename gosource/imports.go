@@ -0,0 +1,538 @@
+package gosource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The packages outside of the workspace (vendor directory, module cache) are type checked from their sources, without network access.
+// A module version never change within the module cache, so its packages are keyed on 'path@version' and shared by every workspace
+// for the whole session. The vendored packages are keyed on their directory, and dropped once the 'vendor/modules.txt' change
+var (
+	dependencyPackages   = make(map[string]*types.Package) // key: see 'moduleRequirements.findPackage()'
+	vendorStamps         = make(map[string]string)         // key: vendor directory, value: 'moduleRequirements.VendorStamp' of its packages
+	dependencyFset       = token.NewFileSet()
+	muDependencyPackages sync.Mutex
+)
+
+// Requirements of the 'go.mod' of a module, enough to locate the sources of its dependencies
+type moduleRequirements struct {
+	Root         string
+	Versions     map[string]string // key: module path
+	Replacements map[string]string // key: module path, value: local directory or 'path@version'
+	IsVendored   bool
+	VendorStamp  string // checksum of the 'vendor/modules.txt', which change whenever the vendored modules do
+}
+
+func readModuleRequirements(moduleRoot string) *moduleRequirements {
+	requirements := &moduleRequirements{
+		Root:         moduleRoot,
+		Versions:     make(map[string]string),
+		Replacements: make(map[string]string),
+	}
+
+	if content, err := os.ReadFile(filepath.Join(moduleRoot, "vendor", "modules.txt")); err == nil {
+		checksum := sha256.Sum256(content)
+
+		requirements.IsVendored = true
+		requirements.VendorStamp = hex.EncodeToString(checksum[:])
+	}
+
+	content, err := os.ReadFile(filepath.Join(moduleRoot, "go.mod"))
+	if err != nil {
+		return requirements
+	}
+
+	block := ""
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == ")" {
+			block = ""
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+
+		directive := block
+		if directive == "" {
+			directive, fields = fields[0], fields[1:]
+		}
+
+		for index := range fields {
+			if unquoted, err := strconv.Unquote(fields[index]); err == nil {
+				fields[index] = unquoted
+			}
+		}
+
+		switch directive {
+		case "require":
+			if len(fields) >= 2 {
+				requirements.Versions[fields[0]] = fields[1]
+			}
+		case "replace":
+			arrow := strings.Index(strings.Join(fields, " "), "=>")
+			if arrow < 0 {
+				continue
+			}
+
+			source := strings.Fields(strings.Join(fields, " ")[:arrow])
+			target := strings.Fields(strings.Join(fields, " ")[arrow+2:])
+
+			if len(source) == 0 || len(target) == 0 {
+				continue
+			}
+
+			if len(target) == 1 {
+				requirements.Replacements[source[0]] = target[0]
+			} else {
+				requirements.Replacements[source[0]] = target[0] + "@" + target[1]
+			}
+		}
+	}
+
+	return requirements
+}
+
+// Directory holding the sources of the package, from the vendor directory when the module is vendored,
+// or else from the module cache at the version required by the 'go.mod'.
+// The key of the package within 'dependencyPackages' is its 'path@version' for the module cache, or else its directory
+func (requirements *moduleRequirements) findPackage(importPath string) (dir string, key string, err error) {
+	if requirements.IsVendored {
+		dir = filepath.Join(requirements.Root, "vendor", filepath.FromSlash(importPath))
+		return dir, dir, nil
+	}
+
+	modulePath := ""
+	for candidate := range requirements.Versions {
+		if (importPath == candidate || strings.HasPrefix(importPath, candidate+"/")) && len(candidate) > len(modulePath) {
+			modulePath = candidate
+		}
+	}
+
+	for candidate := range requirements.Replacements {
+		if (importPath == candidate || strings.HasPrefix(importPath, candidate+"/")) && len(candidate) > len(modulePath) {
+			modulePath = candidate
+		}
+	}
+
+	if modulePath == "" {
+		return "", "", errors.New("package '" + importPath + "' is not provided by any module required by the go.mod")
+	}
+
+	relativeDir := filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(importPath, modulePath), "/"))
+
+	replacement, ok := requirements.Replacements[modulePath]
+	if ok && !strings.Contains(replacement, "@") { // local directory
+		if !filepath.IsAbs(replacement) {
+			replacement = filepath.Join(requirements.Root, replacement)
+		}
+
+		dir = filepath.Join(replacement, relativeDir)
+		return dir, dir, nil
+	}
+
+	moduleVersion := modulePath + "@" + requirements.Versions[modulePath]
+	if ok {
+		moduleVersion = replacement
+	}
+
+	modulePath, version, _ := strings.Cut(moduleVersion, "@")
+
+	dir = filepath.Join(getModuleCacheDir(), filepath.FromSlash(escapeModulePath(modulePath)+"@"+escapeModulePath(version)), relativeDir)
+	key = path.Join(modulePath+"@"+version, filepath.ToSlash(relativeDir))
+
+	return dir, key, nil
+}
+
+// Drop the vendored packages of the module checked against another 'vendor/modules.txt'.
+// Must be called while holding 'muDependencyPackages'
+func (requirements *moduleRequirements) invalidateVendorPackages() {
+	vendorDir := filepath.Join(requirements.Root, "vendor")
+	if !requirements.IsVendored || vendorStamps[vendorDir] == requirements.VendorStamp {
+		return
+	}
+
+	for key := range dependencyPackages {
+		if strings.HasPrefix(key, vendorDir+string(filepath.Separator)) {
+			delete(dependencyPackages, key)
+		}
+	}
+
+	vendorStamps[vendorDir] = requirements.VendorStamp
+}
+
+func getModuleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		gopath = build.Default.GOPATH
+	}
+
+	gopath, _, _ = strings.Cut(gopath, string(filepath.ListSeparator))
+
+	return filepath.Join(gopath, "pkg", "mod")
+}
+
+// Upper case letters are written '!' followed by the lower case letter within the module cache (case insensitive file systems)
+func escapeModulePath(modulePath string) string {
+	var builder strings.Builder
+
+	for _, char := range modulePath {
+		if unicode.IsUpper(char) {
+			builder.WriteByte('!')
+			char = unicode.ToLower(char)
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+// Resolve the imports of the packages outside of the workspace, see 'dependencyPackages'.
+// Must be used while holding 'muDependencyPackages'
+type dependencyImporter struct {
+	requirements *moduleRequirements
+	checking     map[string]bool
+}
+
+func (imp *dependencyImporter) Import(importPath string) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	if isStandardPackage(importPath) {
+		return importStandardPackage(importPath)
+	}
+
+	imp.requirements.invalidateVendorPackages()
+
+	dir, key, err := imp.requirements.findPackage(importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if pkg, ok := dependencyPackages[key]; ok {
+		return pkg, nil
+	}
+
+	if imp.checking[dir] {
+		return nil, errors.New("import cycle through package " + importPath)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.New("package '" + importPath + "' not found within the vendor directory or the module cache")
+	}
+
+	pkg := &GoPackage{Dir: dir, Fset: dependencyFset, Files: make(map[string]*ast.File)}
+
+	for _, entry := range entries {
+		filePath := filepath.Join(dir, entry.Name())
+		if entry.IsDir() || !isGoSourcePath(filePath) {
+			continue
+		}
+
+		file, _ := parser.ParseFile(dependencyFset, filePath, nil, parser.ParseComments|parser.SkipObjectResolution) // comments for the build constraints
+		if file != nil {
+			pkg.Files[filePath] = file
+		}
+	}
+
+	files := getBuildableFiles(pkg)
+	if len(files) == 0 {
+		return nil, errors.New("package '" + importPath + "' has no go file buildable on this machine")
+	}
+
+	imp.checking[dir] = true
+	defer delete(imp.checking, dir)
+
+	config := types.Config{
+		Importer:         imp,
+		Error:            func(err error) {}, // only the declarations matter, type as much as possible
+		FakeImportC:      true,
+		IgnoreFuncBodies: true,
+	}
+
+	typesPackage, _ := config.Check(importPath, dependencyFset, files, nil)
+	dependencyPackages[key] = typesPackage
+
+	return typesPackage, nil
+}
+
+// Resolve the imports of the 'go:code' comments written within the templates, without network access:
+// the packages of the workspace first, then the standard library, then the vendor directory or the module cache
+// following the 'go.mod' at the root of the workspace.
+// The packages outside of the workspace are cached for the whole session, the expanded go code for the lifetime of the importer
+type GoCodeImporter struct {
+	workspace    map[string]*types.Package // key: import path
+	requirements *moduleRequirements
+
+	mu         sync.Mutex
+	expansions map[string]goCodeExpansionResult // key: go code
+}
+
+type goCodeExpansionResult struct {
+	expansion GoCodeExpansion
+	err       error
+}
+
+// The packages must be type checked already, see 'CheckWorkspacePackages()'
+func NewGoCodeImporter(rootPath string, packages []*GoPackage) *GoCodeImporter {
+	imp := &GoCodeImporter{
+		workspace:    make(map[string]*types.Package),
+		requirements: readModuleRequirements(rootPath),
+		expansions:   make(map[string]goCodeExpansionResult),
+	}
+
+	for _, pkg := range packages {
+		if pkg.Types != nil && pkg.ImportPath != "" {
+			imp.workspace[pkg.ImportPath] = pkg.Types
+		}
+	}
+
+	return imp
+}
+
+func (imp *GoCodeImporter) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := imp.workspace[importPath]; ok {
+		return pkg, nil
+	}
+
+	muDependencyPackages.Lock()
+	defer muDependencyPackages.Unlock()
+
+	dependencies := &dependencyImporter{requirements: imp.requirements, checking: make(map[string]bool)}
+
+	return dependencies.Import(importPath)
+}
+
+// 'go:code' comment written by hand, once its imports are resolved
+type GoCodeExpansion struct {
+	// Same length than the original go code: the imports are blanked, and every imported type 'pkg.Name' is renamed 'pkg_Name'.
+	// An alias to an imported type ('type Input = pkg.Name') is blanked as well, and declared again by 'Declarations'
+	GoCode string
+	// Declaration of the imported types on a single line, along the types they depend on.
+	// Like for 'RenderInputType()', only what a template can reach is kept
+	Declarations string
+}
+
+// Resolve the imports of the go code of a 'go:code' comment, see 'GoCodeExpansion'.
+// The go code is returned as is when it import nothing, and an error is returned when an import cannot be resolved
+func (imp *GoCodeImporter) ExpandGoCode(goCode string) (GoCodeExpansion, error) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+
+	if result, ok := imp.expansions[goCode]; ok {
+		return result.expansion, result.err
+	}
+
+	expansion, err := imp.expandGoCode(goCode)
+	imp.expansions[goCode] = goCodeExpansionResult{expansion: expansion, err: err}
+
+	return expansion, err
+}
+
+func (imp *GoCodeImporter) expandGoCode(goCode string) (GoCodeExpansion, error) {
+	const header = "package p; "
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, "", header+goCode, parser.SkipObjectResolution)
+	if err != nil {
+		return GoCodeExpansion{GoCode: goCode}, err
+	}
+
+	if len(file.Imports) == 0 {
+		return GoCodeExpansion{GoCode: goCode}, nil
+	}
+
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if _, err := imp.Import(importPath); err != nil {
+			return GoCodeExpansion{GoCode: goCode}, err
+		}
+	}
+
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+
+	// 'go:code' functions have no body, and the types of the workspace might be half written
+	config := types.Config{Importer: imp, Error: func(err error) {}}
+	_, _ = config.Check("p", fset, []*ast.File{file}, info)
+
+	base := fset.File(file.Pos()).Base() + len(header)
+	offsetOf := func(pos token.Pos) int {
+		return int(pos) - base
+	}
+
+	rewritten := []byte(goCode)
+	blank := func(start token.Pos, end token.Pos) {
+		for index := offsetOf(start); index < offsetOf(end); index++ {
+			if rewritten[index] != '\n' && rewritten[index] != '\r' {
+				rewritten[index] = ' '
+			}
+		}
+	}
+
+	renderer := &goCodeRenderer{
+		names:     make(map[string]string),
+		usedNames: make(map[string]bool),
+	}
+
+	for _, object := range info.Defs {
+		if object != nil && object.Pkg() != nil && object.Parent() == object.Pkg().Scope() {
+			renderer.usedNames[object.Name()] = true
+		}
+	}
+
+	getImportedType := func(expr ast.Expr) (*ast.SelectorExpr, types.Type) {
+		selector, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return nil, nil
+		}
+
+		packageName, ok := selector.X.(*ast.Ident)
+		if !ok {
+			return nil, nil
+		}
+
+		if _, ok := info.Uses[packageName].(*types.PkgName); !ok {
+			return nil, nil
+		}
+
+		typeName, ok := info.Uses[selector.Sel].(*types.TypeName)
+		if !ok {
+			return nil, nil
+		}
+
+		return selector, types.Unalias(typeName.Type())
+	}
+
+	var aliases []string
+
+	// the imported types aliased get the name of the alias
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+
+		if genDecl.Tok == token.IMPORT {
+			blank(genDecl.Pos(), genDecl.End())
+			continue
+		}
+
+		if genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+
+			_, typ := getImportedType(typeSpec.Type)
+			named, ok := typ.(*types.Named)
+			if !typeSpec.Assign.IsValid() || !ok || typeSpec.TypeParams != nil {
+				continue
+			}
+
+			key := types.TypeString(named, nil)
+			if name, ok := renderer.names[key]; ok {
+				aliases = append(aliases, "type "+typeSpec.Name.Name+" = "+name)
+			} else {
+				renderer.names[key] = typeSpec.Name.Name
+				renderer.queue = append(renderer.queue, named)
+			}
+
+			if len(genDecl.Specs) == 1 && !genDecl.Lparen.IsValid() {
+				blank(genDecl.Pos(), genDecl.End())
+			} else {
+				blank(typeSpec.Pos(), typeSpec.End())
+			}
+		}
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if genDecl, ok := node.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			return false
+		}
+
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			return true
+		}
+
+		selector, typ := getImportedType(expr)
+		if selector == nil || rewritten[offsetOf(selector.Sel.Pos())-1] != '.' {
+			return true
+		}
+
+		rewritten[offsetOf(selector.Sel.Pos())-1] = '_'
+		name := selector.X.(*ast.Ident).Name + "_" + selector.Sel.Name
+
+		if renderer.usedNames[name] {
+			return false
+		}
+
+		renderer.usedNames[name] = true
+
+		named, ok := typ.(*types.Named)
+		if !ok {
+			aliases = append(aliases, "type "+name+" "+renderer.render(typ))
+			return false
+		}
+
+		key := types.TypeString(named, nil)
+		if localName, ok := renderer.names[key]; ok {
+			aliases = append(aliases, "type "+name+" = "+localName)
+			return false
+		}
+
+		renderer.names[key] = name
+		renderer.queue = append(renderer.queue, named)
+
+		return false
+	})
+
+	for len(renderer.queue) > 0 {
+		named := renderer.queue[0]
+		renderer.queue = renderer.queue[1:]
+
+		renderer.declareNamedType(named)
+	}
+
+	expansion := GoCodeExpansion{
+		GoCode:       string(rewritten),
+		Declarations: strings.Join(append(renderer.declarations, aliases...), "; "),
+	}
+
+	return expansion, nil
+}
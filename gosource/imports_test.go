@@ -0,0 +1,137 @@
+package gosource
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExpandGoCode(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n\nrequire example.com/Lib v1.2.0\n",
+		"models/order.go": `package models
+
+import "example.com/Lib/money"
+
+type Item struct {
+	Name  string
+	Price money.Amount
+}
+
+type OrderPage struct {
+	Items  []Item
+	Total  money.Amount
+	secret string
+}
+
+func (OrderPage) Count() int { return 0 }
+`,
+	})
+
+	moduleCache := writeWorkspace(t, map[string]string{
+		"example.com/!lib@v1.2.0/money/money.go": "package money\n\ntype Amount struct {\n\tCents    int\n\tCurrency string\n}\n",
+	})
+
+	t.Setenv("GOMODCACHE", moduleCache)
+
+	packages := ParseWorkspacePackages(rootPath, nil)
+	CheckWorkspacePackages(rootPath, packages)
+
+	importer := NewGoCodeImporter(rootPath, packages)
+
+	tests := []struct {
+		goCode           string
+		wantGoCode       string // spaces collapsed
+		wantDeclarations string
+		isErr            bool
+	}{
+		{
+			goCode:           `import "example.com/app/models"; type Input = models.OrderPage`,
+			wantGoCode:       ";",
+			wantDeclarations: "type Input struct { Items []Item; Total Amount }; func (Input) Count() int; type Item struct { Name string; Price Amount }; type Amount struct { Cents int; Currency string }",
+		},
+		{
+			goCode:           "\n\timport m \"example.com/app/models\"\n\ttype Input struct { Order m.Item }\n",
+			wantGoCode:       "type Input struct { Order m_Item }",
+			wantDeclarations: "type m_Item struct { Name string; Price Amount }; type Amount struct { Cents int; Currency string }",
+		},
+		{
+			goCode:           `import "example.com/Lib/money"; type Input struct { Total money.Amount }; func total(money.Amount) string`,
+			wantGoCode:       "; type Input struct { Total money_Amount }; func total(money_Amount) string",
+			wantDeclarations: "type money_Amount struct { Cents int; Currency string }",
+		},
+		{goCode: "type Input struct { Name string }", wantGoCode: "type Input struct { Name string }"},
+		{goCode: `import "example.com/missing"; type Input = missing.Page`, isErr: true},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got, err := importer.ExpandGoCode(test.goCode)
+			if (err != nil) != test.isErr {
+				t.Fatalf("\n Go code: %q \n Expected error: %v \n Got: %v", test.goCode, test.isErr, err)
+			}
+
+			if test.isErr {
+				return
+			}
+
+			if len(got.GoCode) != len(test.goCode) {
+				t.Errorf("\n Expected the length of the go code to be kept (%d) \n Got: %d", len(test.goCode), len(got.GoCode))
+			}
+
+			if goCode := strings.Join(strings.Fields(got.GoCode), " "); goCode != test.wantGoCode {
+				t.Errorf("\n Expected go code: %q \n Got: %q", test.wantGoCode, goCode)
+			}
+
+			if got.Declarations != test.wantDeclarations {
+				t.Errorf("\n Expected declarations: %q \n Got: %q", test.wantDeclarations, got.Declarations)
+			}
+		})
+	}
+}
+
+func TestExpandGoCodeVendorChange(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod":                                 "module example.com/app\n\ngo 1.22\n\nrequire example.com/shop v1.0.0\n",
+		"vendor/modules.txt":                     "# example.com/shop v1.0.0\n## explicit\nexample.com/shop/money\n",
+		"vendor/example.com/shop/money/money.go": "package money\n\ntype Amount struct{ Cents int }\n",
+	})
+
+	expand := func() string {
+		packages := ParseWorkspacePackages(rootPath, nil)
+		CheckWorkspacePackages(rootPath, packages)
+
+		got, err := NewGoCodeImporter(rootPath, packages).ExpandGoCode(`import "example.com/shop/money"; type Input struct { Total money.Amount }`)
+		if err != nil {
+			t.Fatalf("\n Expected: no error \n Got: %v", err)
+		}
+
+		return got.Declarations
+	}
+
+	writeFile := func(relativePath string, content string) {
+		if err := os.WriteFile(filepath.Join(rootPath, filepath.FromSlash(relativePath)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "type money_Amount struct { Cents int }"
+	if got := expand(); got != want {
+		t.Fatalf("\n Expected: %q \n Got: %q", want, got)
+	}
+
+	// the vendored packages are kept as long as the 'modules.txt' is unchanged
+	writeFile("vendor/example.com/shop/money/money.go", "package money\n\ntype Amount struct {\n\tCents    int\n\tCurrency string\n}\n")
+	if got := expand(); got != want {
+		t.Errorf("\n Expected the cached package: %q \n Got: %q", want, got)
+	}
+
+	writeFile("vendor/modules.txt", "# example.com/shop v1.1.0\n## explicit\nexample.com/shop/money\n")
+
+	want = "type money_Amount struct { Cents int; Currency string }"
+	if got := expand(); got != want {
+		t.Errorf("\n Expected: %q \n Got: %q", want, got)
+	}
+}
@@ -0,0 +1,76 @@
+package gosource
+
+import (
+	"slices"
+	"strings"
+)
+
+// Functions of a well-known library, declared like the functions of a 'FuncMap'.
+// There is no 'Definition', the source of the library is not part of the workspace
+type libraryFunction struct {
+	Name      string
+	Signature string // eg. '(s string) string'
+	Doc       string
+}
+
+type FunctionLibrary struct {
+	Functions    []TemplateFunction
+	Declarations string // go code of the types used by the signatures (eg. 'Page' of Hugo), shared by the templates like a prelude
+}
+
+// Libraries of template functions that a project can enable (see 'ProjectConfig.FunctionLibraries'), key: name of the library
+var FUNCTION_LIBRARIES = map[string]FunctionLibrary{
+	"sprig": {Functions: newLibraryFunctions(sprigFunctions)},
+	"hugo":  {Functions: newLibraryFunctions(hugoFunctions), Declarations: hugoDeclarations},
+	"helm":  {Functions: newLibraryFunctions(getHelmLibraryFunctions()), Declarations: helmDeclarations},
+}
+
+func newLibraryFunctions(functions []libraryFunction) []TemplateFunction {
+	library := make([]TemplateFunction, 0, len(functions))
+
+	for _, function := range functions {
+		library = append(library, TemplateFunction{
+			Name:   function.Name,
+			GoCode: "func " + function.Name + function.Signature,
+			Doc:    function.Doc,
+		})
+	}
+
+	return library
+}
+
+// Names of the known libraries, sorted
+func GetFunctionLibraryNames() []string {
+	names := make([]string, 0, len(FUNCTION_LIBRARIES))
+	for name := range FUNCTION_LIBRARIES {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Functions of the libraries, in the order of 'names'. Unknown names are ignored
+func GetLibraryFunctions(names []string) []TemplateFunction {
+	var functions []TemplateFunction
+
+	for _, name := range names {
+		functions = append(functions, FUNCTION_LIBRARIES[name].Functions...)
+	}
+
+	return functions
+}
+
+// Go code declared by the libraries, in the order of 'names'. Unknown names are ignored
+func GetLibraryDeclarations(names []string) string {
+	var declarations []string
+
+	for _, name := range names {
+		if code := FUNCTION_LIBRARIES[name].Declarations; code != "" && !slices.Contains(declarations, code) {
+			declarations = append(declarations, code)
+		}
+	}
+
+	return strings.Join(declarations, "\n")
+}
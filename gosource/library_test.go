@@ -0,0 +1,157 @@
+package gosource
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Every signature of the libraries must be valid Go, the name aside (eg. 'default' of Sprig is a keyword)
+func TestFunctionLibraries(t *testing.T) {
+	for _, libraryName := range GetFunctionLibraryNames() {
+		var names []string
+
+		for _, function := range FUNCTION_LIBRARIES[libraryName].Functions {
+			signature, ok := strings.CutPrefix(function.GoCode, "func "+function.Name+"(")
+			if !ok {
+				t.Errorf("\n Library: %s \n Function: %s \n Go code does not start with the name: %q", libraryName, function.Name, function.GoCode)
+				continue
+			}
+
+			_, err := parser.ParseFile(token.NewFileSet(), "", "package library; func _("+signature, 0)
+			if err != nil {
+				t.Errorf("\n Library: %s \n Function: %s \n Invalid signature: %s", libraryName, function.Name, err.Error())
+			}
+
+			if function.Doc == "" {
+				t.Errorf("\n Library: %s \n Function: %s \n Missing doc", libraryName, function.Name)
+			}
+
+			if slices.Contains(names, function.Name) {
+				t.Errorf("\n Library: %s \n Function: %s \n Declared twice", libraryName, function.Name)
+			}
+
+			names = append(names, function.Name)
+		}
+	}
+}
+
+// The types used by the signatures must be declared by the library (eg. 'Page' of Hugo)
+func TestLibraryDeclarations(t *testing.T) {
+	for _, libraryName := range GetFunctionLibraryNames() {
+		library := FUNCTION_LIBRARIES[libraryName]
+
+		source := "package library\n" + library.Declarations + "\n"
+		for index, function := range library.Functions {
+			signature := strings.TrimPrefix(function.GoCode, "func "+function.Name)
+			source += "func _" + strconv.Itoa(index) + signature + "\n"
+		}
+
+		fset := token.NewFileSet()
+
+		file, err := parser.ParseFile(fset, "", source, 0)
+		if err != nil {
+			t.Errorf("\n Library: %s \n Invalid declarations: %s", libraryName, err.Error())
+			continue
+		}
+
+		var errs []string
+		config := types.Config{Error: func(err error) {
+			if !strings.Contains(err.Error(), "missing function body") {
+				errs = append(errs, err.Error())
+			}
+		}}
+
+		_, _ = config.Check("library", fset, []*ast.File{file}, nil)
+
+		for _, err := range errs {
+			t.Errorf("\n Library: %s \n Type error: %s", libraryName, err)
+		}
+	}
+}
+
+func TestHelmLibraryFunctions(t *testing.T) {
+	functions := FUNCTION_LIBRARIES["helm"].Functions
+
+	tests := []struct {
+		name    string
+		wantDoc string // empty when the function must be missing
+	}{
+		{name: "include", wantDoc: helmFunctions[0].Doc},
+		{name: "fromJson", wantDoc: helmFunctions[slices.IndexFunc(helmFunctions, func(function libraryFunction) bool { return function.Name == "fromJson" })].Doc},
+		{name: "env", wantDoc: ""},
+		{name: "expandenv", wantDoc: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			index := slices.IndexFunc(functions, func(function TemplateFunction) bool { return function.Name == test.name })
+
+			gotDoc := ""
+			if index >= 0 {
+				gotDoc = functions[index].Doc
+			}
+
+			if gotDoc != test.wantDoc {
+				t.Errorf("\n Function: %s \n Expected doc: %q \n Got: %q", test.name, test.wantDoc, gotDoc)
+			}
+		})
+	}
+
+	for _, function := range sprigFunctions {
+		isKept := slices.ContainsFunc(functions, func(helmFunction TemplateFunction) bool { return helmFunction.Name == function.Name })
+		if !isKept && !slices.Contains(helmRemovedFunctions, function.Name) {
+			t.Errorf("\n Sprig function missing from the Helm library: %s", function.Name)
+		}
+	}
+}
+
+func TestGetLibraryFunctions(t *testing.T) {
+	sprigCount := len(FUNCTION_LIBRARIES["sprig"].Functions)
+
+	tests := []struct {
+		names []string
+		want  int
+	}{
+		{names: nil, want: 0},
+		{names: []string{"sprig"}, want: sprigCount},
+		{names: []string{"unknown", "sprig"}, want: sprigCount},
+		{names: []string{"sprig", "hugo"}, want: sprigCount + len(FUNCTION_LIBRARIES["hugo"].Functions)},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := GetLibraryFunctions(test.names)
+			if len(got) != test.want {
+				t.Errorf("\n Names: %v \n Expected: %d functions \n Got: %d", test.names, test.want, len(got))
+			}
+		})
+	}
+}
+
+func TestGetLibraryDeclarations(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{names: nil, want: ""},
+		{names: []string{"sprig"}, want: ""},
+		{names: []string{"hugo"}, want: hugoDeclarations},
+		{names: []string{"hugo", "sprig", "hugo"}, want: hugoDeclarations},
+		{names: []string{"helm", "hugo"}, want: helmDeclarations + "\n" + hugoDeclarations},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := GetLibraryDeclarations(test.names)
+			if got != test.want {
+				t.Errorf("\n Names: %v \n Expected: %d bytes \n Got: %d bytes", test.names, len(test.want), len(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package gosource
+
+// Functions of Sprig v3 (github.com/Masterminds/sprig), as returned by 'sprig.FuncMap()'.
+// The types are the ones of the Go functions, loosened to 'any' where Sprig convert the argument itself (eg. 'toString').
+// The date functions take and return 'any', 'time.Time' is not known without importing the package
+var sprigFunctions = []libraryFunction{
+	// strings
+	{"trim", "(s string) string", "Remove the white space from both sides of the string"},
+	{"trimAll", "(cutset string, s string) string", "Remove the given characters from the front and back of the string"},
+	{"trimPrefix", "(prefix string, s string) string", "Trim just the prefix from the string"},
+	{"trimSuffix", "(suffix string, s string) string", "Trim just the suffix from the string"},
+	{"upper", "(s string) string", "Convert the entire string to uppercase"},
+	{"lower", "(s string) string", "Convert the entire string to lowercase"},
+	{"title", "(s string) string", "Convert the string to title case"},
+	{"untitle", "(s string) string", "Remove the title casing of the string"},
+	{"repeat", "(count int, s string) string", "Repeat the string 'count' times"},
+	{"substr", "(start int, end int, s string) string", "Part of the string, from 'start' up to 'end' (excluded)"},
+	{"nospace", "(s string) string", "Remove all the white space from the string"},
+	{"trunc", "(length int, s string) string", "Truncate the string to 'length' characters, from the end when 'length' is negative"},
+	{"abbrev", "(width int, s string) string", "Truncate the string with ellipses ('...'), 'width' include the ellipses"},
+	{"abbrevboth", "(left int, right int, s string) string", "Abbreviate both sides of the string"},
+	{"initials", "(s string) string", "First letter of each word of the string, combined"},
+	{"randAlphaNum", "(count int) string", "Cryptographically secure random string of 'count' characters within [0-9a-zA-Z]"},
+	{"randAlpha", "(count int) string", "Cryptographically secure random string of 'count' characters within [a-zA-Z]"},
+	{"randNumeric", "(count int) string", "Cryptographically secure random string of 'count' characters within [0-9]"},
+	{"randAscii", "(count int) string", "Cryptographically secure random string of 'count' printable ASCII characters"},
+	{"wrap", "(length int, s string) string", "Wrap the text at the given column count"},
+	{"wrapWith", "(length int, separator string, s string) string", "Wrap the text at the given column count, lines are split by 'separator'"},
+	{"contains", "(substr string, s string) bool", "Whether the string 's' contains 'substr'"},
+	{"hasPrefix", "(prefix string, s string) bool", "Whether the string 's' starts with 'prefix'"},
+	{"hasSuffix", "(suffix string, s string) bool", "Whether the string 's' ends with 'suffix'"},
+	{"quote", "(values ...any) string", "Wrap each value in double quotes, separated by a space"},
+	{"squote", "(values ...any) string", "Wrap each value in single quotes, separated by a space"},
+	{"cat", "(values ...any) string", "Concatenate the values into a single string, separated by a space"},
+	{"indent", "(spaces int, s string) string", "Indent every line of the string by 'spaces' spaces"},
+	{"nindent", "(spaces int, s string) string", "Same as 'indent', but a new line is prepended to the string"},
+	{"replace", "(old string, new string, s string) string", "Replace every occurrence of 'old' by 'new'"},
+	{"plural", "(one string, many string, count int) string", "'one' when 'count' is 1, 'many' otherwise"},
+	{"snakecase", "(s string) string", "Convert the string from camelCase to snake_case"},
+	{"camelcase", "(s string) string", "Convert the string from snake_case to CamelCase"},
+	{"kebabcase", "(s string) string", "Convert the string from camelCase to kebab-case"},
+	{"swapcase", "(s string) string", "Swap the case of every letter of the string"},
+	{"shuffle", "(s string) string", "Shuffle the characters of the string"},
+	{"toString", "(value any) string", "Convert the value to a string"},
+	{"toStrings", "(list any) []string", "Convert every item of the list to a string"},
+	{"split", "(separator string, s string) map[string]string", "Split the string into a dict, keys are '_0', '_1', ..."},
+	{"splitList", "(separator string, s string) []string", "Split the string into a list of strings"},
+	{"splitn", "(separator string, n int, s string) map[string]string", "Split the string into a dict of at most 'n' items, keys are '_0', '_1', ..."},
+	{"join", "(separator string, list any) string", "Join the items of the list into a single string, separated by 'separator'"},
+	{"sortAlpha", "(list any) []string", "Sort the list of strings in alphabetical (lexicographical) order"},
+
+	// regular expressions
+	{"regexMatch", "(regex string, s string) bool", "Whether the string contains any match of the regular expression"},
+	{"mustRegexMatch", "(regex string, s string) (bool, error)", "Same as 'regexMatch', but fail the template when the regular expression is invalid"},
+	{"regexFindAll", "(regex string, s string, n int) []string", "All the matches of the regular expression, at most 'n' (all when negative)"},
+	{"mustRegexFindAll", "(regex string, s string, n int) ([]string, error)", "Same as 'regexFindAll', but fail the template when the regular expression is invalid"},
+	{"regexFind", "(regex string, s string) string", "First (left most) match of the regular expression"},
+	{"mustRegexFind", "(regex string, s string) (string, error)", "Same as 'regexFind', but fail the template when the regular expression is invalid"},
+	{"regexReplaceAll", "(regex string, s string, replacement string) string", "Replace the matches of the regular expression, '$1' within the replacement is the first submatch"},
+	{"mustRegexReplaceAll", "(regex string, s string, replacement string) (string, error)", "Same as 'regexReplaceAll', but fail the template when the regular expression is invalid"},
+	{"regexReplaceAllLiteral", "(regex string, s string, replacement string) string", "Replace the matches of the regular expression, the replacement is used as is"},
+	{"mustRegexReplaceAllLiteral", "(regex string, s string, replacement string) (string, error)", "Same as 'regexReplaceAllLiteral', but fail the template when the regular expression is invalid"},
+	{"regexSplit", "(regex string, s string, n int) []string", "Split the string by the regular expression, at most 'n' items (all when negative)"},
+	{"mustRegexSplit", "(regex string, s string, n int) ([]string, error)", "Same as 'regexSplit', but fail the template when the regular expression is invalid"},
+	{"regexQuoteMeta", "(s string) string", "Escape the special characters of regular expressions within the string"},
+
+	// math
+	{"add1", "(value any) int64", "Increment the value by 1"},
+	{"add", "(values ...any) int64", "Sum of the values"},
+	{"sub", "(a any, b any) int64", "Subtract 'b' from 'a'"},
+	{"div", "(a any, b any) int64", "Integer division of 'a' by 'b'"},
+	{"mod", "(a any, b any) int64", "Remainder of the division of 'a' by 'b'"},
+	{"mul", "(a any, values ...any) int64", "Product of the values"},
+	{"max", "(a any, values ...any) int64", "Largest of the integers"},
+	{"min", "(a any, values ...any) int64", "Smallest of the integers"},
+	{"floor", "(value any) float64", "Greatest integer value less than or equal to the value"},
+	{"ceil", "(value any) float64", "Least integer value greater than or equal to the value"},
+	{"round", "(value any, precision int, roundOn ...float64) float64", "Round the value to 'precision' decimal places, '0.5' is rounded up unless 'roundOn' is given"},
+	{"until", "(count int) []int", "List of the integers from 0 up to 'count' (excluded)"},
+	{"untilStep", "(start int, stop int, step int) []int", "List of the integers from 'start' up to 'stop' (excluded), incremented by 'step'"},
+	{"seq", "(params ...int) string", "Sequence of integers separated by a space, like the 'seq' command (eg. 'seq 1 2 10')"},
+	{"randInt", "(min int, max int) int", "Random integer from 'min' up to 'max' (excluded)"},
+	{"add1f", "(value any) float64", "Increment the float by 1"},
+	{"addf", "(values ...any) float64", "Sum of the floats"},
+	{"subf", "(a any, values ...any) float64", "Subtract the floats from 'a'"},
+	{"divf", "(a any, values ...any) float64", "Division of 'a' by the floats"},
+	{"mulf", "(a any, values ...any) float64", "Product of the floats"},
+	{"maxf", "(a any, values ...any) float64", "Largest of the floats"},
+	{"minf", "(a any, values ...any) float64", "Smallest of the floats"},
+
+	// type conversion, 'int', 'int64' and 'float64' are also Go types
+	{"atoi", "(s string) int", "Convert the string to an integer, 0 when the string is not a number"},
+	{"int", "(value any) int", "Convert the value to an 'int'"},
+	{"int64", "(value any) int64", "Convert the value to an 'int64'"},
+	{"float64", "(value any) float64", "Convert the value to a 'float64'"},
+	{"toDecimal", "(value any) int64", "Convert the unix octal string to an 'int64' (eg. '0777' is 511)"},
+
+	// defaults, 'default' is also a Go keyword
+	{"default", "(fallback any, given ...any) any", "'fallback' when the given value is empty (zero value, empty list or dict), the given value otherwise"},
+	{"empty", "(value any) bool", "Whether the value is empty (zero value, empty list or dict)"},
+	{"coalesce", "(values ...any) any", "First value that is not empty"},
+	{"all", "(values ...any) bool", "Whether every value is not empty"},
+	{"any", "(values ...any) bool", "Whether any value is not empty"},
+	{"compact", "(list any) []any", "Remove the empty items from the list"},
+	{"mustCompact", "(list any) ([]any, error)", "Same as 'compact', but fail the template when the argument is not a list"},
+	{"ternary", "(whenTrue any, whenFalse any, condition bool) any", "'whenTrue' when the condition is true, 'whenFalse' otherwise"},
+	{"fromJson", "(s string) any", "Decode the JSON string, empty when the JSON is invalid"},
+	{"mustFromJson", "(s string) (any, error)", "Decode the JSON string, fail the template when the JSON is invalid"},
+	{"toJson", "(value any) string", "Encode the value as JSON, empty when it cannot be encoded"},
+	{"mustToJson", "(value any) (string, error)", "Encode the value as JSON, fail the template when it cannot be encoded"},
+	{"toPrettyJson", "(value any) string", "Encode the value as indented JSON"},
+	{"mustToPrettyJson", "(value any) (string, error)", "Same as 'toPrettyJson', but fail the template when the value cannot be encoded"},
+	{"toRawJson", "(value any) string", "Encode the value as JSON, the HTML characters are not escaped"},
+	{"mustToRawJson", "(value any) (string, error)", "Same as 'toRawJson', but fail the template when the value cannot be encoded"},
+	{"deepCopy", "(value any) any", "Deep copy of the value, including dicts and lists"},
+	{"mustDeepCopy", "(value any) (any, error)", "Same as 'deepCopy', but fail the template when the value cannot be copied"},
+
+	// reflection
+	{"typeOf", "(value any) string", "Go type of the value (eg. '[]string')"},
+	{"typeIs", "(typeName string, value any) bool", "Whether the Go type of the value is 'typeName'"},
+	{"typeIsLike", "(typeName string, value any) bool", "Same as 'typeIs', but the pointers are dereferenced"},
+	{"kindOf", "(value any) string", "Go kind of the value (eg. 'slice')"},
+	{"kindIs", "(kind string, value any) bool", "Whether the Go kind of the value is 'kind'"},
+	{"deepEqual", "(a any, b any) bool", "Whether both values are deeply equal"},
+
+	// os and paths
+	{"env", "(name string) string", "Value of the environment variable"},
+	{"expandenv", "(s string) string", "Replace the environment variables within the string (eg. '$HOME')"},
+	{"base", "(path string) string", "Last element of the path"},
+	{"dir", "(path string) string", "Path without its last element"},
+	{"clean", "(path string) string", "Shortest path equivalent to the path"},
+	{"ext", "(path string) string", "File extension of the path"},
+	{"isAbs", "(path string) bool", "Whether the path is absolute"},
+	{"osBase", "(path string) string", "Same as 'base', using the separator of the operating system"},
+	{"osClean", "(path string) string", "Same as 'clean', using the separator of the operating system"},
+	{"osDir", "(path string) string", "Same as 'dir', using the separator of the operating system"},
+	{"osExt", "(path string) string", "Same as 'ext', using the separator of the operating system"},
+	{"osIsAbs", "(path string) bool", "Same as 'isAbs', using the separator of the operating system"},
+
+	// encoding
+	{"b64enc", "(s string) string", "Encode the string with Base64"},
+	{"b64dec", "(s string) string", "Decode the Base64 string"},
+	{"b32enc", "(s string) string", "Encode the string with Base32"},
+	{"b32dec", "(s string) string", "Decode the Base32 string"},
+
+	// lists
+	{"tuple", "(values ...any) []any", "Same as 'list'"},
+	{"list", "(values ...any) []any", "List of the values"},
+	{"first", "(list any) any", "First item of the list"},
+	{"mustFirst", "(list any) (any, error)", "Same as 'first', but fail the template when the argument is not a list"},
+	{"rest", "(list any) []any", "Every item of the list but the first"},
+	{"mustRest", "(list any) ([]any, error)", "Same as 'rest', but fail the template when the argument is not a list"},
+	{"last", "(list any) any", "Last item of the list"},
+	{"mustLast", "(list any) (any, error)", "Same as 'last', but fail the template when the argument is not a list"},
+	{"initial", "(list any) []any", "Every item of the list but the last"},
+	{"mustInitial", "(list any) ([]any, error)", "Same as 'initial', but fail the template when the argument is not a list"},
+	{"append", "(list any, value any) []any", "New list with the value appended"},
+	{"mustAppend", "(list any, value any) ([]any, error)", "Same as 'append', but fail the template when the argument is not a list"},
+	{"push", "(list any, value any) []any", "Same as 'append'"},
+	{"mustPush", "(list any, value any) ([]any, error)", "Same as 'mustAppend'"},
+	{"prepend", "(list any, value any) []any", "New list with the value prepended"},
+	{"mustPrepend", "(list any, value any) ([]any, error)", "Same as 'prepend', but fail the template when the argument is not a list"},
+	{"concat", "(lists ...any) any", "Concatenation of the lists"},
+	{"reverse", "(list any) []any", "New list with the items in reverse order"},
+	{"mustReverse", "(list any) ([]any, error)", "Same as 'reverse', but fail the template when the argument is not a list"},
+	{"uniq", "(list any) []any", "New list with the duplicates removed"},
+	{"mustUniq", "(list any) ([]any, error)", "Same as 'uniq', but fail the template when the argument is not a list"},
+	{"without", "(list any, values ...any) []any", "New list without the given values"},
+	{"mustWithout", "(list any, values ...any) ([]any, error)", "Same as 'without', but fail the template when the argument is not a list"},
+	{"has", "(value any, list any) bool", "Whether the list contains the value"},
+	{"mustHas", "(value any, list any) (bool, error)", "Same as 'has', but fail the template when the argument is not a list"},
+	{"slice", "(list any, indices ...any) any", "Part of the list, from the first index up to the second (excluded)"},
+	{"mustSlice", "(list any, indices ...any) (any, error)", "Same as 'slice', but fail the template when the argument is not a list"},
+	{"chunk", "(size int, list any) [][]any", "Split the list into chunks of 'size' items"},
+	{"mustChunk", "(size int, list any) ([][]any, error)", "Same as 'chunk', but fail the template when the argument is not a list"},
+
+	// dicts
+	{"dict", "(pairs ...any) map[string]any", "Dict from the key/value pairs (eg. 'dict \"name\" .Name'), the keys are strings"},
+	{"get", "(dict map[string]any, key string) any", "Value of the key, empty when the key is absent"},
+	{"set", "(dict map[string]any, key string, value any) map[string]any", "Set the value of the key, the dict is modified and returned"},
+	{"unset", "(dict map[string]any, key string) map[string]any", "Remove the key, the dict is modified and returned"},
+	{"hasKey", "(dict map[string]any, key string) bool", "Whether the dict contains the key"},
+	{"pluck", "(key string, dicts ...map[string]any) []any", "Values of the key within every dict holding it"},
+	{"keys", "(dicts ...map[string]any) []string", "Keys of the dicts, in no particular order"},
+	{"pick", "(dict map[string]any, keys ...string) map[string]any", "New dict with only the given keys"},
+	{"omit", "(dict map[string]any, keys ...string) map[string]any", "New dict without the given keys"},
+	{"merge", "(dst map[string]any, srcs ...map[string]any) any", "Merge the dicts into 'dst', the keys of 'dst' win"},
+	{"mergeOverwrite", "(dst map[string]any, srcs ...map[string]any) any", "Merge the dicts into 'dst', the keys of the last dict win"},
+	{"mustMerge", "(dst map[string]any, srcs ...map[string]any) (any, error)", "Same as 'merge', but fail the template when the dicts cannot be merged"},
+	{"mustMergeOverwrite", "(dst map[string]any, srcs ...map[string]any) (any, error)", "Same as 'mergeOverwrite', but fail the template when the dicts cannot be merged"},
+	{"values", "(dict map[string]any) []any", "Values of the dict, in no particular order"},
+	{"dig", "(keys ...any) any", "Value of nested dicts, the keys are followed by the default value and the dict (eg. 'dig \"a\" \"b\" \"default\" $dict')"},
+
+	// dates
+	{"now", "() any", "Current date and time"},
+	{"ago", "(date any) string", "Duration since the date, to the second"},
+	{"date", "(format string, date any) string", "Format the date, with the layout of the Go 'time' package (eg. '2006-01-02')"},
+	{"dateInZone", "(format string, date any, zone string) string", "Same as 'date', but within the time zone"},
+	{"date_in_zone", "(format string, date any, zone string) string", "Same as 'dateInZone'"},
+	{"dateModify", "(modification string, date any) any", "Date modified by the duration (eg. '-1.5h')"},
+	{"date_modify", "(modification string, date any) any", "Same as 'dateModify'"},
+	{"mustDateModify", "(modification string, date any) (any, error)", "Same as 'dateModify', but fail the template when the duration is invalid"},
+	{"duration", "(seconds any) string", "Format the number of seconds as a duration (eg. '1m35s')"},
+	{"durationRound", "(duration any) string", "Round the duration to its most significant unit (eg. '2h')"},
+	{"htmlDate", "(date any) string", "Format the date for an HTML date picker input ('2006-01-02')"},
+	{"htmlDateInZone", "(date any, zone string) string", "Same as 'htmlDate', but within the time zone"},
+	{"toDate", "(format string, s string) any", "Parse the string as a date, with the layout of the Go 'time' package"},
+	{"mustToDate", "(format string, s string) (any, error)", "Same as 'toDate', but fail the template when the date is invalid"},
+	{"unixEpoch", "(date any) string", "Seconds since the unix epoch"},
+
+	// crypto and security
+	{"sha1sum", "(s string) string", "SHA1 digest of the string, hex encoded"},
+	{"sha256sum", "(s string) string", "SHA256 digest of the string, hex encoded"},
+	{"sha512sum", "(s string) string", "SHA512 digest of the string, hex encoded"},
+	{"adler32sum", "(s string) string", "Adler-32 checksum of the string"},
+	{"bcrypt", "(s string) string", "bcrypt hash of the string"},
+	{"htpasswd", "(username string, password string) string", "Entry of a htpasswd file, the password hashed with bcrypt"},
+	{"randBytes", "(count int) string", "Cryptographically secure random sequence of 'count' bytes, Base64 encoded"},
+	{"derivePassword", "(counter uint32, passwordType string, password string, user string, site string) string", "Password derived from the master password, following the Master Password algorithm"},
+	{"genPrivateKey", "(keyType string) string", "New private key encoded as PEM, 'keyType' is one of 'rsa', 'dsa', 'ecdsa' or 'ed25519'"},
+	{"buildCustomCert", "(cert string, key string) any", "Certificate object from the Base64 encoded PEM certificate and key"},
+	{"genCA", "(commonName string, days int) any", "New self signed certificate authority, valid for 'days' days"},
+	{"genCAWithKey", "(commonName string, days int, key string) any", "Same as 'genCA', with the given PEM private key"},
+	{"genSelfSignedCert", "(commonName string, ips []any, alternateDNS []any, days int) any", "New self signed certificate"},
+	{"genSelfSignedCertWithKey", "(commonName string, ips []any, alternateDNS []any, days int, key string) any", "Same as 'genSelfSignedCert', with the given PEM private key"},
+	{"genSignedCert", "(commonName string, ips []any, alternateDNS []any, days int, ca any) any", "New certificate signed by the certificate authority"},
+	{"genSignedCertWithKey", "(commonName string, ips []any, alternateDNS []any, days int, ca any, key string) any", "Same as 'genSignedCert', with the given PEM private key"},
+	{"encryptAES", "(password string, plaintext string) string", "Encrypt the text with AES-256 CBC, Base64 encoded"},
+	{"decryptAES", "(password string, ciphertext string) string", "Decrypt the Base64 encoded text, encrypted with AES-256 CBC"},
+
+	// uuid, urls and semantic versions
+	{"uuidv4", "() string", "New random UUID (v4)"},
+	{"urlParse", "(url string) map[string]any", "Dict of the parts of the URL ('scheme', 'host', 'path', 'query', 'opaque', 'fragment', 'userinfo')"},
+	{"urlJoin", "(parts map[string]any) string", "URL from the dict of its parts, the opposite of 'urlParse'"},
+	{"urlquery", "(values ...any) string", "Escape the values to be embedded within the query of a URL"},
+	{"semver", "(version string) (any, error)", "Parse the semantic version"},
+	{"semverCompare", "(constraint string, version string) (bool, error)", "Whether the semantic version satisfy the constraint (eg. '>= 1.2.0')"},
+
+	// flow control
+	{"fail", "(message string) (string, error)", "Fail the template with the message"},
+}
@@ -0,0 +1,680 @@
+// Static analysis of the Go code of the workspace, to learn how the templates are used at runtime.
+// The code is parsed with 'go/parser', then type checked offline with 'go/types' (see 'CheckWorkspacePackages()'):
+// the imports are resolved from the workspace, the standard library, the vendor directory and the module cache.
+// Nothing is compiled nor downloaded
+package gosource
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var TEMPLATE_PACKAGE_PATHS = []string{"text/template", "html/template"}
+
+// Files parsed together by a 'template.ParseFiles()', 'ParseGlob()' or 'ParseFS()' call (or a chain of them on the same template).
+// Only those files share the same namespace at runtime, thus the same '{{ define }}'
+type TemplateSet struct {
+	Id       string   // position of the first parse call, eg. 'cmd/web/main.go:42'
+	Files    []string // os path, from 'ParseFiles()'
+	Patterns []string // os path globs, from 'ParseGlob()' and 'ParseFS()'
+	RootName string   // template run by 'Execute()', from 'template.New()' or else the first file parsed
+}
+
+func (set *TemplateSet) Contains(filePath string) bool {
+	if slices.Contains(set.Files, filePath) {
+		return true
+	}
+
+	for _, pattern := range set.Patterns {
+		if ok, _ := filepath.Match(pattern, filePath); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Go files parsed from disk, with the unsaved editor content ('overlay') taking precedence.
+// Within the overlay, a nil content mark a file deleted
+type GoPackage struct {
+	Dir   string
+	Fset  *token.FileSet
+	Files map[string]*ast.File // key: os path
+
+	// set by 'CheckWorkspacePackages()', nil until then
+	ImportPath string
+	Types      *types.Package
+	Info       *types.Info
+}
+
+// Parse every Go package below 'rootPath', test files excluded.
+// Vendored code, 'testdata' and hidden directories are skipped
+func ParseWorkspacePackages(rootPath string, overlay map[string][]byte) []*GoPackage {
+	packages := make(map[string]*GoPackage)
+	fset := token.NewFileSet()
+
+	parse := func(filePath string, content []byte) {
+		file, err := parser.ParseFile(fset, filePath, content, parser.ParseComments)
+		if file == nil {
+			slog.Warn("unable to parse go file, "+err.Error(), slog.String("path", filePath))
+			return
+		}
+
+		dir := filepath.Dir(filePath)
+		if packages[dir] == nil {
+			packages[dir] = &GoPackage{Dir: dir, Fset: fset, Files: make(map[string]*ast.File)}
+		}
+
+		packages[dir].Files[filePath] = file
+	}
+
+	_ = filepath.WalkDir(rootPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if entry.IsDir() {
+			name := entry.Name()
+			if filePath != rootPath && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if !isGoSourcePath(filePath) {
+			return nil
+		}
+
+		content, ok := overlay[filePath]
+		if !ok {
+			content, err = os.ReadFile(filePath)
+			if err != nil {
+				return nil
+			}
+		}
+
+		if content == nil { // deleted in the overlay
+			return nil
+		}
+
+		parse(filePath, content)
+
+		return nil
+	})
+
+	// files only known by the editor (never saved)
+	for filePath, content := range overlay {
+		if content == nil || !isGoSourcePath(filePath) || !strings.HasPrefix(filePath, rootPath) {
+			continue
+		}
+
+		if pkg := packages[filepath.Dir(filePath)]; pkg != nil && pkg.Files[filePath] != nil {
+			continue
+		}
+
+		parse(filePath, content)
+	}
+
+	list := make([]*GoPackage, 0, len(packages))
+	for _, pkg := range packages {
+		list = append(list, pkg)
+	}
+
+	slices.SortFunc(list, func(a, b *GoPackage) int { return strings.Compare(a.Dir, b.Dir) })
+
+	return list
+}
+
+func isGoSourcePath(filePath string) bool {
+	return strings.HasSuffix(filePath, ".go") && !strings.HasSuffix(filePath, "_test.go")
+}
+
+// Find the template sets of the Go packages.
+//
+// The relative paths given to 'ParseFiles()' and 'ParseGlob()' depend on the working directory of the program,
+// which is assumed to be the module root (directory of the nearest 'go.mod', or 'rootPath' when there is none).
+// The patterns of 'ParseFS()' are relative to the package directory, like '//go:embed', unless the file system is an 'os.DirFS()'.
+// Arguments that are not string literals (or 'filepath.Join()' of literals) cannot be resolved and are ignored
+//
+// The template executions and functions are only found on type checked packages (see 'CheckWorkspacePackages()')
+func DiscoverTemplateSets(rootPath string, packages []*GoPackage) Discovery {
+	var discovery Discovery
+
+	packagesByTypes := make(map[*types.Package]*GoPackage)
+	for _, pkg := range packages {
+		if pkg.Types != nil {
+			packagesByTypes[pkg.Types] = pkg
+		}
+	}
+
+	for _, pkg := range packages {
+		moduleRoot := findModuleRoot(pkg.Dir, rootPath)
+		packageDiscovery := discoverTemplateSetsOfPackage(pkg, moduleRoot, packagesByTypes)
+
+		discovery.Sets = append(discovery.Sets, packageDiscovery.Sets...)
+		discovery.Executions = append(discovery.Executions, packageDiscovery.Executions...)
+		discovery.Functions = append(discovery.Functions, packageDiscovery.Functions...)
+		discovery.Calls = append(discovery.Calls, packageDiscovery.Calls...)
+	}
+
+	discovery.Importer = NewGoCodeImporter(rootPath, packages)
+	discovery.Symbols = make(map[string]GoCodeSymbols)
+
+	for _, call := range discovery.Calls {
+		if _, ok := discovery.Symbols[call.GoCode]; !ok && len(call.Symbols) > 0 {
+			discovery.Symbols[call.GoCode] = call.Symbols
+		}
+	}
+
+	for _, function := range discovery.Functions {
+		if _, ok := discovery.Symbols[function.GoCode]; !ok && function.Definition.IsValid() {
+			discovery.Symbols[function.GoCode] = GoCodeSymbols{function.Name: function.Definition}
+		}
+	}
+
+	return discovery
+}
+
+// What the Go code tell about the templates
+type Discovery struct {
+	Sets       []TemplateSet
+	Executions []TemplateExecution
+	Functions  []TemplateFunction
+	Calls      []TemplateCall
+
+	// Go declarations of the symbols of the 'GoCode' of the calls and functions, key: 'GoCode'
+	Symbols map[string]GoCodeSymbols
+
+	// Resolve the imports of the 'go:code' comments written within the templates
+	Importer *GoCodeImporter
+}
+
+func discoverTemplateSetsOfPackage(pkg *GoPackage, moduleRoot string, packagesByTypes map[*types.Package]*GoPackage) Discovery {
+	setsByKey := make(map[string]*TemplateSet)
+	var keys []string
+
+	// calls linked to a template set, once every set of the package is known
+	type pendingCall struct {
+		key  string
+		call *ast.CallExpr
+		file *ast.File
+	}
+
+	var pendingExecutions []pendingCall
+	var pendingFuncs []pendingCall
+	isFuncsCallPending := make(map[*ast.CallExpr]bool)
+
+	packageLevelVars := make(map[string]bool)
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				for _, name := range spec.(*ast.ValueSpec).Names {
+					packageLevelVars[name.Name] = true
+				}
+			}
+		}
+	}
+
+	filePaths := make([]string, 0, len(pkg.Files))
+	for filePath := range pkg.Files {
+		filePaths = append(filePaths, filePath)
+	}
+
+	slices.Sort(filePaths)
+
+	for _, filePath := range filePaths {
+		file := pkg.Files[filePath]
+		templatePackageNames := getImportNames(file, TEMPLATE_PACKAGE_PATHS)
+		if len(templatePackageNames) == 0 {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			scope := strconv.Itoa(int(decl.Pos())) // local variables are only shared within the same declaration
+
+			identifierKey := func(name string) string {
+				if packageLevelVars[name] {
+					return "var:" + name
+				}
+
+				return scope + ":" + name
+			}
+
+			// 1. parse calls whose template is assigned to a variable (eg. 't := template.Must(template.ParseGlob(...))')
+			assignedKeys := make(map[*ast.CallExpr]string)
+			callKeys := make(map[*ast.CallExpr]string)
+
+			ast.Inspect(decl, func(node ast.Node) bool {
+				var names []*ast.Ident
+				var values []ast.Expr
+
+				switch node := node.(type) {
+				case *ast.AssignStmt:
+					for _, lhs := range node.Lhs {
+						ident, _ := lhs.(*ast.Ident)
+						names = append(names, ident)
+					}
+					values = node.Rhs
+				case *ast.ValueSpec:
+					names = node.Names
+					values = node.Values
+				default:
+					return true
+				}
+
+				for index, value := range values {
+					if index >= len(names) || names[index] == nil || names[index].Name == "_" {
+						continue
+					}
+
+					key := identifierKey(names[index].Name)
+
+					ast.Inspect(value, func(node ast.Node) bool {
+						if call, ok := node.(*ast.CallExpr); ok && (isParseCall(call, pkg.Info) || isFuncsCall(call)) {
+							assignedKeys[call] = key
+						}
+						return true
+					})
+				}
+
+				return true
+			})
+
+			// 2. every parse call join the set of its template
+			ast.Inspect(decl, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok || !isParseCall(call, pkg.Info) {
+					return true
+				}
+
+				position := pkg.Fset.Position(call.Pos())
+
+				key, ok := assignedKeys[call]
+				if root := getChainRootIdentifier(call); !ok && root != nil && !templatePackageNames[root.Name] {
+					key = identifierKey(root.Name) // method call on an existing template (eg. 't.ParseFiles(...)')
+				} else if !ok {
+					key = "call:" + position.String()
+				}
+
+				// parse calls earlier in the chain load the same template (eg. 'template.Must(template.ParseFiles(...)).ParseGlob(...)'),
+				// and so do the functions (eg. 'template.New("").Funcs(funcMap).ParseGlob(...)')
+				ast.Inspect(call.Fun, func(node ast.Node) bool {
+					inner, ok := node.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+
+					if _, ok := assignedKeys[inner]; !ok && isParseCall(inner, pkg.Info) {
+						assignedKeys[inner] = key
+					}
+
+					if isFuncsCall(inner) && !isFuncsCallPending[inner] {
+						isFuncsCallPending[inner] = true
+						pendingFuncs = append(pendingFuncs, pendingCall{key: key, call: inner, file: file})
+					}
+
+					return true
+				})
+
+				callKeys[call] = key
+
+				set, ok := setsByKey[key]
+				if !ok {
+					relativeFile, _ := filepath.Rel(moduleRoot, position.Filename)
+					set = &TemplateSet{Id: filepath.ToSlash(relativeFile) + ":" + strconv.Itoa(position.Line)}
+					setsByKey[key] = set
+					keys = append(keys, key)
+				}
+
+				if set.RootName == "" {
+					set.RootName = getNewTemplateName(call, file)
+				}
+
+				addParseCallToTemplateSet(set, call, file, pkg.Dir, moduleRoot)
+
+				return true
+			})
+
+			// 3. template executions and functions, linked to the set of their template when it is known
+			ast.Inspect(decl, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+
+				isFuncs := isFuncsCall(call) && !isFuncsCallPending[call]
+				if !isFuncs && !isExecuteCall(call, pkg.Info) {
+					return true
+				}
+
+				key := ""
+				if root := getChainRootIdentifier(call.Fun); root != nil && !templatePackageNames[root.Name] {
+					key = identifierKey(root.Name)
+				}
+
+				// executed on the result of a parse call (eg. 'template.Must(template.ParseFiles(...)).Execute(...)')
+				ast.Inspect(call.Fun, func(node ast.Node) bool {
+					if inner, ok := node.(*ast.CallExpr); ok && callKeys[inner] != "" {
+						key = callKeys[inner]
+						return false
+					}
+					return true
+				})
+
+				if assignedKey, ok := assignedKeys[call]; ok && isFuncs {
+					key = assignedKey // eg. 't := template.New("").Funcs(funcMap)'
+				}
+
+				if isFuncs {
+					isFuncsCallPending[call] = true
+					pendingFuncs = append(pendingFuncs, pendingCall{key: key, call: call, file: file})
+				} else {
+					pendingExecutions = append(pendingExecutions, pendingCall{key: key, call: call, file: file})
+				}
+
+				return true
+			})
+		}
+	}
+
+	sets := make([]TemplateSet, 0, len(keys))
+	for _, key := range keys {
+		set := setsByKey[key]
+		if len(set.Files) == 0 && len(set.Patterns) == 0 {
+			continue // nothing could be resolved statically
+		}
+
+		if set.RootName == "" {
+			set.RootName = getFirstParsedFileName(set)
+		}
+
+		sets = append(sets, *set)
+	}
+
+	getSet := func(key string) *TemplateSet {
+		set := setsByKey[key]
+		if set != nil && len(set.Files) == 0 && len(set.Patterns) == 0 {
+			return nil
+		}
+
+		return set
+	}
+
+	executions := make([]TemplateExecution, 0, len(pendingExecutions))
+	var calls []TemplateCall
+
+	for _, pending := range pendingExecutions {
+		call, ok := newTemplateCall(pkg, pending.call, pending.file, getSet(pending.key), packagesByTypes)
+		if !ok {
+			continue
+		}
+
+		calls = append(calls, call)
+
+		if execution, ok := newTemplateExecution(call, pkg.Fset.Position(pending.call.Pos()), moduleRoot); ok {
+			executions = append(executions, execution)
+		}
+	}
+
+	var functions []TemplateFunction
+	for _, pending := range pendingFuncs {
+		functions = append(functions, newTemplateFunctions(pkg, pending.call, pending.file, getSet(pending.key), packagesByTypes)...)
+	}
+
+	return Discovery{Sets: sets, Executions: executions, Functions: functions, Calls: calls}
+}
+
+// Name given to 'template.New()' when the parse call is chained to it (eg. 'template.New("base").ParseFiles(...)')
+func getNewTemplateName(call *ast.CallExpr, file *ast.File) string {
+	name := ""
+
+	ast.Inspect(call.Fun, func(node ast.Node) bool {
+		inner, ok := node.(*ast.CallExpr)
+		if !ok || len(inner.Args) != 1 {
+			return true
+		}
+
+		selector, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "New" {
+			return true
+		}
+
+		if value, ok := EvaluateStringExpression(inner.Args[0], file); ok {
+			name = value
+			return false
+		}
+
+		return true
+	})
+
+	return name
+}
+
+// Templates are named after the base name of their file, and the first file parsed become the template run by 'Execute()'.
+// 'filepath.Glob()' and 'fs.Glob()' both return the matches in lexical order
+func getFirstParsedFileName(set *TemplateSet) string {
+	if len(set.Files) > 0 {
+		return filepath.Base(set.Files[0])
+	}
+
+	for _, pattern := range set.Patterns {
+		matches, _ := filepath.Glob(pattern)
+		if len(matches) > 0 {
+			return filepath.Base(matches[0])
+		}
+	}
+
+	return ""
+}
+
+// Whether the call parse templates of 'text/template' or 'html/template', either the function of the package
+// or the method of '*Template' (eg. 'template.ParseGlob(...)', 't.ParseFiles(...)').
+// Without type information (or when the type of the receiver is unknown), only the name of the call is checked
+func isParseCall(call *ast.CallExpr, info *types.Info) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	switch selector.Sel.Name {
+	case "ParseFiles", "ParseGlob", "ParseFS":
+	default:
+		return false
+	}
+
+	if info == nil {
+		return true
+	}
+
+	if selection := info.Selections[selector]; selection != nil {
+		method, ok := selection.Obj().(*types.Func)
+		return ok && method.Pkg() != nil && slices.Contains(TEMPLATE_PACKAGE_PATHS, method.Pkg().Path())
+	}
+
+	if ident, ok := selector.X.(*ast.Ident); ok {
+		if packageName, ok := info.Uses[ident].(*types.PkgName); ok {
+			return slices.Contains(TEMPLATE_PACKAGE_PATHS, packageName.Imported().Path())
+		}
+	}
+
+	return info.TypeOf(selector.X) == nil
+}
+
+// Identifier at the start of the call chain, eg. 't' for 't.Funcs(f).ParseGlob(...)',
+// or 'template' for 'template.Must(template.New("").ParseGlob(...))'
+func getChainRootIdentifier(expr ast.Expr) *ast.Ident {
+	for {
+		switch node := expr.(type) {
+		case *ast.Ident:
+			return node
+		case *ast.CallExpr:
+			expr = node.Fun
+		case *ast.SelectorExpr:
+			expr = node.X
+		case *ast.ParenExpr:
+			expr = node.X
+		case *ast.StarExpr:
+			expr = node.X
+		default:
+			return nil
+		}
+	}
+}
+
+func addParseCallToTemplateSet(set *TemplateSet, call *ast.CallExpr, file *ast.File, packageDir string, moduleRoot string) {
+	method := call.Fun.(*ast.SelectorExpr).Sel.Name
+	arguments := call.Args
+
+	baseDir := moduleRoot
+
+	if method == "ParseFS" {
+		if len(arguments) == 0 {
+			return
+		}
+
+		baseDir = packageDir
+		if dir, ok := getDirFSArgument(arguments[0], file); ok {
+			baseDir = filepath.Join(moduleRoot, filepath.FromSlash(dir))
+		}
+
+		arguments = arguments[1:]
+	}
+
+	for _, argument := range arguments {
+		value, ok := EvaluateStringExpression(argument, file)
+		if !ok {
+			continue
+		}
+
+		target := filepath.FromSlash(value)
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(baseDir, target)
+		}
+
+		if method == "ParseFiles" {
+			set.Files = append(set.Files, target)
+		} else {
+			set.Patterns = append(set.Patterns, target)
+		}
+	}
+}
+
+// Directory given to 'os.DirFS("dir")', when the file system argument is such a call
+func getDirFSArgument(expr ast.Expr, file *ast.File) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return "", false
+	}
+
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "DirFS" {
+		return "", false
+	}
+
+	if pkg, ok := selector.X.(*ast.Ident); !ok || !getImportNames(file, []string{"os"})[pkg.Name] {
+		return "", false
+	}
+
+	return EvaluateStringExpression(call.Args[0], file)
+}
+
+// Value of a string literal, or of a 'filepath.Join()'/'path.Join()' of string literals
+func EvaluateStringExpression(expr ast.Expr, file *ast.File) (string, bool) {
+	switch node := expr.(type) {
+	case *ast.BasicLit:
+		if node.Kind != token.STRING {
+			return "", false
+		}
+
+		value, err := strconv.Unquote(node.Value)
+		if err != nil {
+			return "", false
+		}
+
+		return value, true
+	case *ast.ParenExpr:
+		return EvaluateStringExpression(node.X, file)
+	case *ast.CallExpr:
+		selector, ok := node.Fun.(*ast.SelectorExpr)
+		if !ok || selector.Sel.Name != "Join" {
+			return "", false
+		}
+
+		pkg, ok := selector.X.(*ast.Ident)
+		if !ok || !getImportNames(file, []string{"path", "path/filepath"})[pkg.Name] {
+			return "", false
+		}
+
+		elements := make([]string, 0, len(node.Args))
+		for _, argument := range node.Args {
+			value, ok := EvaluateStringExpression(argument, file)
+			if !ok {
+				return "", false
+			}
+
+			elements = append(elements, value)
+		}
+
+		return path.Join(elements...), true
+	}
+
+	return "", false
+}
+
+// Local names of the imported packages whose path is in 'importPaths'
+func getImportNames(file *ast.File, importPaths []string) map[string]bool {
+	names := make(map[string]bool)
+
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || !slices.Contains(importPaths, importPath) {
+			continue
+		}
+
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		if name == "_" || name == "." {
+			continue
+		}
+
+		names[name] = true
+	}
+
+	return names
+}
+
+// Directory of the nearest 'go.mod' upward from 'dir', without going above 'rootPath'
+func findModuleRoot(dir string, rootPath string) string {
+	for {
+		info, err := os.Stat(filepath.Join(dir, "go.mod"))
+		if err == nil && !info.IsDir() {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if dir == rootPath || parent == dir || !strings.HasPrefix(parent, rootPath) {
+			return rootPath
+		}
+
+		dir = parent
+	}
+}
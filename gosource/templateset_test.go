@@ -0,0 +1,188 @@
+package gosource
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	rootPath := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(rootPath, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rootPath
+}
+
+func TestDiscoverTemplateSets(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"cmd/web/main.go": `package main
+
+import (
+	"html/template"
+	"path/filepath"
+)
+
+var pages = template.Must(template.ParseGlob("views/pages/*.html"))
+
+func admin() {
+	t := template.Must(template.ParseFiles("views/admin/layout.html"))
+	t.ParseFiles(filepath.Join("views", "admin", "users.html"))
+	_ = t
+}
+
+func mail() {
+	_ = template.Must(template.ParseFiles("views/mail/base.html")).ParseGlob("views/mail/parts/*.html")
+}
+`,
+		"internal/ui/embed.go": `package ui
+
+import (
+	"embed"
+	tmpl "text/template"
+)
+
+//go:embed templates
+var files embed.FS
+
+var set = tmpl.Must(tmpl.ParseFS(files, "templates/*.tmpl"))
+`,
+		"internal/ui/embed_test.go": `package ui
+
+import "text/template"
+
+var ignored = template.Must(template.ParseGlob("ignored/*.tmpl"))
+`,
+		"vendor/lib/lib.go": `package lib
+
+import "text/template"
+
+var ignored = template.Must(template.ParseGlob("ignored/*.tmpl"))
+`,
+	})
+
+	path := func(name string) string {
+		return filepath.Join(rootPath, filepath.FromSlash(name))
+	}
+
+	sets := DiscoverTemplateSets(rootPath, ParseWorkspacePackages(rootPath, nil)).Sets
+	if len(sets) != 4 {
+		t.Fatalf("expected 4 template sets, got %d: %+v", len(sets), sets)
+	}
+
+	tests := []struct {
+		file string
+		want []int // index of the sets containing the file
+	}{
+		{file: "views/pages/home.html", want: []int{0}},
+		{file: "views/admin/layout.html", want: []int{1}},
+		{file: "views/admin/users.html", want: []int{1}},
+		{file: "views/mail/base.html", want: []int{2}},
+		{file: "views/mail/parts/footer.html", want: []int{2}},
+		{file: "internal/ui/templates/index.tmpl", want: []int{3}},
+		{file: "views/pages/nested/home.html", want: nil},
+		{file: "ignored/file.tmpl", want: nil},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			var got []int
+			for index := range sets {
+				if sets[index].Contains(path(test.file)) {
+					got = append(got, index)
+				}
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n File: %s \n Expected sets: %v \n Got sets: %v \n Sets: %+v", test.file, test.want, got, sets)
+			}
+		})
+	}
+}
+
+func TestDiscoverTemplateSetsParseReceiver(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"main.go": `package main
+
+import "html/template"
+
+type settings struct{}
+
+func (settings) ParseFiles(names ...string) error { return nil }
+
+type page struct{ *template.Template }
+
+var pages = template.Must(template.ParseGlob("views/*.html"))
+
+func main() {
+	settings{}.ParseFiles("config/settings.html")
+
+	p := page{template.New("")}
+	p.ParseFiles("layouts/base.html")
+}
+`,
+	})
+
+	packages := ParseWorkspacePackages(rootPath, nil)
+	CheckWorkspacePackages(rootPath, packages)
+
+	sets := DiscoverTemplateSets(rootPath, packages).Sets
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{file: "views/home.html", want: true},
+		{file: "layouts/base.html", want: true}, // method promoted from the embedded '*template.Template'
+		{file: "config/settings.html", want: false},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := slices.ContainsFunc(sets, func(set TemplateSet) bool {
+				return set.Contains(filepath.Join(rootPath, filepath.FromSlash(test.file)))
+			})
+			if got != test.want {
+				t.Errorf("\n File: %s \n Expected within a set: %v \n Got: %v \n Sets: %+v", test.file, test.want, got, sets)
+			}
+		})
+	}
+}
+
+func TestParseWorkspacePackagesOverlay(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod":  "module example.com/app\n",
+		"main.go": "package main\n\nimport \"text/template\"\n\nvar t = template.Must(template.ParseGlob(\"a/*.tmpl\"))\n",
+	})
+
+	overlay := map[string][]byte{
+		filepath.Join(rootPath, "main.go"): []byte("package main\n\nimport \"text/template\"\n\nvar t = template.Must(template.ParseGlob(\"b/*.tmpl\"))\n"),
+	}
+
+	sets := DiscoverTemplateSets(rootPath, ParseWorkspacePackages(rootPath, overlay)).Sets
+	if len(sets) != 1 || !sets[0].Contains(filepath.Join(rootPath, "b", "x.tmpl")) {
+		t.Errorf("expected the unsaved content to win over the disk, got %+v", sets)
+	}
+
+	overlay[filepath.Join(rootPath, "main.go")] = nil
+
+	sets = DiscoverTemplateSets(rootPath, ParseWorkspacePackages(rootPath, overlay)).Sets
+	if len(sets) != 0 {
+		t.Errorf("expected no template set once the file is deleted, got %+v", sets)
+	}
+}
@@ -0,0 +1,300 @@
+package gosource
+
+import (
+	"errors"
+	"go/ast"
+	"go/build/constraint"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The standard library is type checked from the sources of '$GOROOT/src', which never reach the network.
+// It never change during the session, so its packages are shared by every workspace and every type check
+var (
+	stdlibImporter   types.Importer
+	stdlibFset       = token.NewFileSet() // position of the objects of the standard packages
+	muStdlibImporter sync.Mutex
+)
+
+func importStandardPackage(importPath string) (*types.Package, error) {
+	muStdlibImporter.Lock()
+	defer muStdlibImporter.Unlock()
+
+	if stdlibImporter == nil {
+		stdlibImporter = importer.ForCompiler(stdlibFset, "source", nil)
+	}
+
+	return stdlibImporter.Import(importPath)
+}
+
+func isStandardPackage(importPath string) bool {
+	firstElement, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(firstElement, ".")
+}
+
+// Go module found within the workspace
+type goModule struct {
+	Root string
+	Path string
+}
+
+// Resolve the imports of the workspace packages without network access:
+//   - packages of the modules within the workspace are type checked from their parsed files
+//   - standard packages come from '$GOROOT/src'
+//   - dependencies come from the vendor directory or the module cache, see 'dependencyImporter'
+//   - every other package is replaced by an empty one, so the types depending on it become invalid (rendered as 'any')
+type workspaceImporter struct {
+	packagesByDir map[string]*GoPackage
+	modules       []goModule
+	checking      map[*GoPackage]bool
+	requirements  map[string]*moduleRequirements // key: module root
+}
+
+func (imp *workspaceImporter) Import(importPath string) (*types.Package, error) {
+	return imp.ImportFrom(importPath, "", 0)
+}
+
+func (imp *workspaceImporter) ImportFrom(importPath string, dir string, mode types.ImportMode) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	for _, module := range imp.modules {
+		if importPath != module.Path && !strings.HasPrefix(importPath, module.Path+"/") {
+			continue
+		}
+
+		packageDir := filepath.Join(module.Root, filepath.FromSlash(strings.TrimPrefix(importPath, module.Path)))
+		pkg := imp.packagesByDir[packageDir]
+
+		if pkg == nil {
+			break
+		}
+
+		if imp.checking[pkg] {
+			return nil, errors.New("import cycle through package " + importPath)
+		}
+
+		imp.check(pkg, importPath)
+
+		if pkg.Types == nil {
+			break
+		}
+
+		return pkg.Types, nil
+	}
+
+	if isStandardPackage(importPath) {
+		pkg, err := importStandardPackage(importPath)
+		if err == nil {
+			return pkg, nil
+		}
+
+		slog.Warn("unable to import standard package, "+err.Error(), slog.String("import_path", importPath))
+	} else if pkg, err := imp.importDependency(importPath, dir); err == nil {
+		return pkg, nil
+	}
+
+	// the types of the package are unknown
+	pkg := types.NewPackage(importPath, path.Base(importPath))
+	pkg.MarkComplete()
+
+	return pkg, nil
+}
+
+// Dependency required by the 'go.mod' of the module containing 'dir'
+func (imp *workspaceImporter) importDependency(importPath string, dir string) (*types.Package, error) {
+	moduleRoot := ""
+	for _, module := range imp.modules {
+		if dir == module.Root || strings.HasPrefix(dir, module.Root+string(filepath.Separator)) {
+			moduleRoot = module.Root
+			break
+		}
+	}
+
+	if moduleRoot == "" {
+		return nil, errors.New("package '" + importPath + "' is imported from outside of the workspace modules")
+	}
+
+	requirements := imp.requirements[moduleRoot]
+	if requirements == nil {
+		requirements = readModuleRequirements(moduleRoot)
+		imp.requirements[moduleRoot] = requirements
+	}
+
+	muDependencyPackages.Lock()
+	defer muDependencyPackages.Unlock()
+
+	dependencies := &dependencyImporter{requirements: requirements, checking: make(map[string]bool)}
+
+	return dependencies.Import(importPath)
+}
+
+func (imp *workspaceImporter) check(pkg *GoPackage, importPath string) {
+	if pkg.Info != nil {
+		return
+	}
+
+	pkg.ImportPath = importPath
+
+	imp.checking[pkg] = true
+	defer delete(imp.checking, pkg)
+
+	config := types.Config{
+		Importer:    imp,
+		Error:       func(err error) {}, // the code might be half written, keep going and type as much as possible
+		FakeImportC: true,
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+
+	pkg.Types, _ = config.Check(importPath, pkg.Fset, getBuildableFiles(pkg), info)
+	pkg.Info = info
+}
+
+// Type check every workspace package, the result is stored in 'GoPackage.Types' and 'GoPackage.Info'.
+// Type errors are ignored, an expression whose type cannot be found is simply left out of 'GoPackage.Info'
+func CheckWorkspacePackages(rootPath string, packages []*GoPackage) {
+	imp := &workspaceImporter{
+		packagesByDir: make(map[string]*GoPackage),
+		checking:      make(map[*GoPackage]bool),
+		requirements:  make(map[string]*moduleRequirements),
+	}
+
+	for _, pkg := range packages {
+		imp.packagesByDir[pkg.Dir] = pkg
+
+		moduleRoot := findModuleRoot(pkg.Dir, rootPath)
+		if slices.ContainsFunc(imp.modules, func(module goModule) bool { return module.Root == moduleRoot }) {
+			continue
+		}
+
+		imp.modules = append(imp.modules, goModule{Root: moduleRoot, Path: readModulePath(moduleRoot)})
+	}
+
+	// the deepest module first, since its packages are also below the root module directory
+	slices.SortFunc(imp.modules, func(a, b goModule) int { return len(b.Root) - len(a.Root) })
+
+	for _, pkg := range packages {
+		importPath := ""
+
+		for _, module := range imp.modules {
+			relativeDir, err := filepath.Rel(module.Root, pkg.Dir)
+			if err != nil || strings.HasPrefix(relativeDir, "..") {
+				continue
+			}
+
+			importPath = path.Join(module.Path, filepath.ToSlash(relativeDir))
+			break
+		}
+
+		imp.check(pkg, importPath)
+	}
+}
+
+// Module path declared in the 'go.mod' of 'moduleRoot', or a placeholder when there is none
+func readModulePath(moduleRoot string) string {
+	content, err := os.ReadFile(filepath.Join(moduleRoot, "go.mod"))
+	if err != nil {
+		return "workspace"
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+
+		modulePath := fields[1]
+		if unquoted, err := strconv.Unquote(modulePath); err == nil {
+			modulePath = unquoted
+		}
+
+		return modulePath
+	}
+
+	return "workspace"
+}
+
+// Files of the package that would be compiled on this machine.
+// When several package names are found in a directory (eg. a 'package main' generator next to the library), the most common one win
+func getBuildableFiles(pkg *GoPackage) []*ast.File {
+	filePaths := make([]string, 0, len(pkg.Files))
+	countByPackageName := make(map[string]int)
+
+	for filePath, file := range pkg.Files {
+		if !isFileBuildable(file) {
+			continue
+		}
+
+		filePaths = append(filePaths, filePath)
+		countByPackageName[file.Name.Name]++
+	}
+
+	slices.Sort(filePaths)
+
+	packageName := ""
+	for name, count := range countByPackageName {
+		if count > countByPackageName[packageName] || (count == countByPackageName[packageName] && name < packageName) {
+			packageName = name
+		}
+	}
+
+	files := make([]*ast.File, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		if pkg.Files[filePath].Name.Name == packageName {
+			files = append(files, pkg.Files[filePath])
+		}
+	}
+
+	return files
+}
+
+// Evaluate the '//go:build' line of the file against the current platform.
+// The '_GOOS'/'_GOARCH' file name suffixes are not considered
+func isFileBuildable(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				return true
+			}
+
+			return expr.Eval(func(tag string) bool {
+				switch tag {
+				case runtime.GOOS, runtime.GOARCH, "gc":
+					return true
+				case "unix":
+					return runtime.GOOS != "windows" && runtime.GOOS != "plan9" && runtime.GOOS != "js" && runtime.GOOS != "wasip1"
+				}
+
+				return strings.HasPrefix(tag, "go1.")
+			})
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,278 @@
+package lsp
+
+import "strings"
+
+// Typed view of the capabilities sent by the client during 'initialize'.
+// Only the capabilities the server adapt its behavior to are decoded, the rest is ignored
+type ClientCapabilities struct {
+	General struct {
+		PositionEncodings []string `json:"positionEncodings"`
+	} `json:"general"`
+
+	TextDocument struct {
+		Hover struct {
+			DynamicRegistration bool     `json:"dynamicRegistration"`
+			ContentFormat       []string `json:"contentFormat"`
+		} `json:"hover"`
+		Definition struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+		} `json:"definition"`
+		FoldingRange struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+			LineFoldingOnly     bool `json:"lineFoldingOnly"`
+		} `json:"foldingRange"`
+		Completion struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+		} `json:"completion"`
+		SemanticTokens struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+		} `json:"semanticTokens"`
+		SignatureHelp struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+		} `json:"signatureHelp"`
+		PublishDiagnostics struct {
+			RelatedInformation bool `json:"relatedInformation"`
+		} `json:"publishDiagnostics"`
+	} `json:"textDocument"`
+
+	Workspace struct {
+		WorkspaceFolders      bool `json:"workspaceFolders"`
+		Configuration         bool `json:"configuration"`
+		DidChangeWatchedFiles struct {
+			DynamicRegistration bool `json:"dynamicRegistration"`
+		} `json:"didChangeWatchedFiles"`
+	} `json:"workspace"`
+
+	Window struct {
+		WorkDoneProgress bool `json:"workDoneProgress"`
+	} `json:"window"`
+
+	// Not sent by the client, but negotiated by the server from 'General.PositionEncodings'
+	PositionEncoding PositionEncodingKind `json:"-"`
+}
+
+const (
+	MarkupKindMarkdown  = "markdown"
+	MarkupKindPlainText = "plaintext"
+)
+
+// Kind of the hover content, the first one the server handle within the 'contentFormat' of the client (ordered by preference).
+// Clients that do not state their preference get markdown, as it has always been,
+// and the ones listing only unknown kinds get plain text
+func (client *ClientCapabilities) GetHoverMarkupKind() string {
+	if client == nil || len(client.TextDocument.Hover.ContentFormat) == 0 {
+		return MarkupKindMarkdown
+	}
+
+	for _, kind := range client.TextDocument.Hover.ContentFormat {
+		if kind == MarkupKindMarkdown || kind == MarkupKindPlainText {
+			return kind
+		}
+	}
+
+	return MarkupKindPlainText
+}
+
+// Whether the client settings can be pulled with the 'workspace/configuration' request
+func (client *ClientCapabilities) SupportsConfigurationRequest() bool {
+	return client != nil && client.Workspace.Configuration
+}
+
+// Encoding to use when converting positions for this client
+func (client *ClientCapabilities) GetPositionEncoding() PositionEncodingKind {
+	if client == nil || client.PositionEncoding == "" {
+		return PositionEncodingUTF16
+	}
+
+	return client.PositionEncoding
+}
+
+// Remove every part of the diagnostics the client is not able to handle
+func AdaptDiagnosticsToClient(diagnostics []Diagnostic, client *ClientCapabilities) []Diagnostic {
+	if client != nil && client.TextDocument.PublishDiagnostics.RelatedInformation {
+		return diagnostics
+	}
+
+	for index := range diagnostics {
+		diagnostics[index].RelatedInformation = nil
+	}
+
+	return diagnostics
+}
+
+// Best effort conversion of the markdown produced by the analyzer into plain text,
+// only code fences are removed since the rest is readable as is
+func convertMarkdownToPlainText(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	text := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+
+		text = append(text, line)
+	}
+
+	return strings.TrimSpace(strings.Join(text, "\n"))
+}
+
+type Registration struct {
+	Id              string `json:"id"`
+	Method          string `json:"method"`
+	RegisterOptions any    `json:"registerOptions,omitempty"`
+}
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+type Unregistration struct {
+	Id     string `json:"id"`
+	Method string `json:"method"`
+}
+
+// INFO: the typo 'unregisterations' is part of the LSP specification
+type UnregistrationParams struct {
+	Unregisterations []Unregistration `json:"unregisterations"`
+}
+
+type DocumentFilter struct {
+	Language string `json:"language,omitempty"`
+	Scheme   string `json:"scheme,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+type TextDocumentRegistrationOptions struct {
+	DocumentSelector []DocumentFilter `json:"documentSelector"`
+}
+
+type CompletionRegistrationOptions struct {
+	TextDocumentRegistrationOptions
+	CompletionOptions
+}
+
+type SemanticTokensRegistrationOptions struct {
+	TextDocumentRegistrationOptions
+	SemanticTokensOptions
+}
+
+type SignatureHelpRegistrationOptions struct {
+	TextDocumentRegistrationOptions
+	SignatureHelpOptions
+}
+
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+// One watcher per file extension for the whole workspace, plus the project config file and the go code
+// (the template sets are discovered from it)
+func createFileSystemWatchers(fileExtensions []string) []FileSystemWatcher {
+	watchers := make([]FileSystemWatcher, 0, len(fileExtensions)+2)
+
+	for _, extension := range fileExtensions {
+		watchers = append(watchers, FileSystemWatcher{GlobPattern: "**/*." + extension})
+	}
+
+	watchers = append(watchers, FileSystemWatcher{GlobPattern: "**/" + PROJECT_CONFIG_FILE_NAME})
+	watchers = append(watchers, FileSystemWatcher{GlobPattern: "**/*.go"})
+
+	return watchers
+}
+
+// Document selector matching all files having one of the 'fileExtensions',
+// along the 'untitled:' buffers that have no extension yet and are analysed on their own (rootless mode)
+func createDocumentSelector(fileExtensions []string) []DocumentFilter {
+	selector := make([]DocumentFilter, 0, len(fileExtensions)+1)
+
+	for _, extension := range fileExtensions {
+		selector = append(selector, DocumentFilter{Pattern: "**/*." + extension})
+	}
+
+	selector = append(selector, DocumentFilter{Scheme: "untitled"})
+
+	return selector
+}
+
+// Features that the client prefer to register dynamically are left out of the static
+// 'ServerCapabilities' during 'initialize', and are registered here instead,
+// scoped to the template files only.
+// Return nil when there is nothing to register
+func createRegistrationParams(client *ClientCapabilities, fileExtensions []string) *RegistrationParams {
+	if client == nil {
+		return nil
+	}
+
+	options := TextDocumentRegistrationOptions{
+		DocumentSelector: createDocumentSelector(fileExtensions),
+	}
+
+	var registrations []Registration
+
+	if client.TextDocument.Hover.DynamicRegistration {
+		registrations = append(registrations, Registration{Id: "hover", Method: "textDocument/hover", RegisterOptions: options})
+	}
+
+	if client.TextDocument.Definition.DynamicRegistration {
+		registrations = append(registrations, Registration{Id: "definition", Method: "textDocument/definition", RegisterOptions: options})
+	}
+
+	if client.TextDocument.FoldingRange.DynamicRegistration {
+		registrations = append(registrations, Registration{Id: "folding-range", Method: "textDocument/foldingRange", RegisterOptions: options})
+	}
+
+	if client.TextDocument.Completion.DynamicRegistration {
+		completionOptions := CompletionRegistrationOptions{TextDocumentRegistrationOptions: options, CompletionOptions: *newCompletionOptions()}
+		registrations = append(registrations, Registration{Id: "completion", Method: "textDocument/completion", RegisterOptions: completionOptions})
+	}
+
+	if client.TextDocument.SemanticTokens.DynamicRegistration {
+		semanticTokensOptions := SemanticTokensRegistrationOptions{TextDocumentRegistrationOptions: options, SemanticTokensOptions: *newSemanticTokensOptions()}
+		registrations = append(registrations, Registration{Id: "semantic-tokens", Method: "textDocument/semanticTokens", RegisterOptions: semanticTokensOptions})
+	}
+
+	if client.TextDocument.SignatureHelp.DynamicRegistration {
+		signatureHelpOptions := SignatureHelpRegistrationOptions{TextDocumentRegistrationOptions: options, SignatureHelpOptions: *newSignatureHelpOptions()}
+		registrations = append(registrations, Registration{Id: "signature-help", Method: "textDocument/signatureHelp", RegisterOptions: signatureHelpOptions})
+	}
+
+	// this one can only be registered dynamically
+	if client.Workspace.DidChangeWatchedFiles.DynamicRegistration {
+		watcherOptions := DidChangeWatchedFilesRegistrationOptions{
+			Watchers: createFileSystemWatchers(fileExtensions),
+		}
+
+		registrations = append(registrations, Registration{Id: "watched-files", Method: "workspace/didChangeWatchedFiles", RegisterOptions: watcherOptions})
+	}
+
+	if len(registrations) == 0 {
+		return nil
+	}
+
+	params := &RegistrationParams{
+		Registrations: registrations,
+	}
+
+	return params
+}
+
+// The registrations scoped to the file extensions are replaced when the extensions change (eg. new config).
+// Return the params of 'client/unregisterCapability' and 'client/registerCapability', either can be nil
+func ProcessFileExtensionsChange(previous *RegistrationParams, client *ClientCapabilities, fileExtensions []string) (unregistration *UnregistrationParams, registration *RegistrationParams) {
+	if previous != nil && len(previous.Registrations) > 0 {
+		unregistration = &UnregistrationParams{}
+
+		for _, previousRegistration := range previous.Registrations {
+			unregistration.Unregisterations = append(unregistration.Unregisterations, Unregistration{Id: previousRegistration.Id, Method: previousRegistration.Method})
+		}
+	}
+
+	registration = createRegistrationParams(client, fileExtensions)
+
+	return unregistration, registration
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestGetHoverMarkupKind(t *testing.T) {
+	tests := []struct {
+		contentFormat []string
+		want          string
+	}{
+		{contentFormat: nil, want: MarkupKindMarkdown},
+		{contentFormat: []string{"markdown", "plaintext"}, want: MarkupKindMarkdown},
+		{contentFormat: []string{"plaintext", "markdown"}, want: MarkupKindPlainText},
+		{contentFormat: []string{"plaintext"}, want: MarkupKindPlainText},
+		{contentFormat: []string{"asciidoc", "markdown"}, want: MarkupKindMarkdown},
+		{contentFormat: []string{"asciidoc"}, want: MarkupKindPlainText},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			client := &ClientCapabilities{}
+			client.TextDocument.Hover.ContentFormat = test.contentFormat
+
+			if got := client.GetHoverMarkupKind(); got != test.want {
+				t.Errorf("\n Input: %q \n Expected: %s \n Got: %s", test.contentFormat, test.want, got)
+			}
+		})
+	}
+
+	var client *ClientCapabilities
+	if got := client.GetHoverMarkupKind(); got != MarkupKindMarkdown {
+		t.Errorf("\n Input: no capabilities \n Expected: %s \n Got: %s", MarkupKindMarkdown, got)
+	}
+}
+
+func TestCreateDocumentSelector(t *testing.T) {
+	want := []DocumentFilter{{Pattern: "**/*.html"}, {Pattern: "**/*.tmpl"}, {Scheme: "untitled"}}
+
+	got := createDocumentSelector([]string{"html", "tmpl"})
+	if !slices.Equal(got, want) {
+		t.Errorf("\n Expected: %v \n Got: %v", want, got)
+	}
+}
@@ -0,0 +1,707 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota"
+)
+
+// Name of the project configuration file, looked up at the root of every workspace folder
+const PROJECT_CONFIG_FILE_NAME = ".go-template-lsp.json"
+
+// Extension of the prelude files, whose go code is shared by the templates (see 'ProjectConfig.Preludes')
+const PRELUDE_FILE_EXTENSION = "gotypes"
+
+// Key under which the settings are nested when the client send them namespaced
+// (eg. '{"go-template-lsp": {...}}' within 'workspace/didChangeConfiguration'),
+// and section asked by the 'workspace/configuration' request
+const SETTINGS_SECTION_NAME = "go-template-lsp"
+
+const (
+	DiagnosticsScopeWorkspace = "workspace" // every file of the workspace is reported
+	DiagnosticsScopeOpenFiles = "openFiles" // only files opened by the editor are reported
+)
+
+// Diagnostics produced by the server, each one can have its own severity (or be turned off)
+const (
+	DiagnosticRuleSyntax          = "syntax"          // errors found by the parser
+	DiagnosticRuleAnalysis        = "analysis"        // errors found by the semantic analysis (type check, undefined template, ...)
+	DiagnosticRuleExecuteTemplate = "executeTemplate" // template names and data given to 'ExecuteTemplate()' by the Go code
+)
+
+// Kind of project, some frameworks have their own functions and lookup rules of the templates
+const (
+	ModeGo   = "go"   // plain 'text/template' and 'html/template', the functions come from the Go code
+	ModeHugo = "hugo" // Hugo static site, with its functions, its 'Page' and 'Site' context, and its 'layouts' lookup
+	ModeHelm = "helm" // Helm charts, with the functions of Helm and '.Values' typed from the 'values.yaml' of the chart
+)
+
+var KNOWN_MODES = []string{ModeGo, ModeHugo, ModeHelm}
+
+// Extensions of the files within the 'templates' directory of a Helm chart, analysed on top of 'ProjectConfig.Extensions'
+var HELM_FILE_EXTENSIONS = []string{"yaml", "yml", "tpl", "txt"}
+
+var KNOWN_DIAGNOSTIC_RULES = []string{DiagnosticRuleSyntax, DiagnosticRuleAnalysis, DiagnosticRuleExecuteTemplate}
+
+var DIAGNOSTIC_SEVERITIES = map[string]int{
+	"error":       1,
+	"warning":     2,
+	"information": 3,
+	"hint":        4,
+	"off":         0,
+}
+
+type DelimitersConfig struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+type DiagnosticsConfig struct {
+	Scope string `json:"scope,omitempty"`
+}
+
+// Configuration of the server, built by merging (from lowest to highest priority)
+// the defaults, the client settings ('initializationOptions' or 'workspace/didChangeConfiguration')
+// and the project file '.go-template-lsp.json'.
+//
+// Within a partial config (before merge), a nil field mean 'not set'.
+// Once merged, every field is set
+type ProjectConfig struct {
+	Extensions   []string          `json:"extensions,omitempty"`
+	Include      []string          `json:"include,omitempty"`
+	Exclude      []string          `json:"exclude,omitempty"`
+	UseGitignore *bool             `json:"useGitignore,omitempty"`
+	MaxFileSize  *int64            `json:"maxFileSize,omitempty"` // in bytes
+	MaxFileCount *int              `json:"maxFileCount,omitempty"`
+	Delimiters   *DelimitersConfig `json:"delimiters,omitempty"`
+	Preludes     []string          `json:"preludes,omitempty"` // globs of the prelude files
+	Mode         string            `json:"mode,omitempty"`
+
+	FunctionLibraries []string `json:"functionLibraries,omitempty"` // names of 'gosource.FUNCTION_LIBRARIES' (eg. 'sprig')
+
+	DelimitersOverrides []DelimitersOverride `json:"delimitersOverrides,omitempty"`
+	Rules               map[string]string    `json:"rules,omitempty"`
+	Diagnostics         DiagnosticsConfig    `json:"diagnostics,omitempty"`
+}
+
+func DefaultProjectConfig(fileExtensions []string) *ProjectConfig {
+	useGitignore := true
+	var maxFileSize int64 = 1 << 20
+	maxFileCount := 5000
+
+	config := &ProjectConfig{
+		Extensions:   slices.Clone(fileExtensions),
+		Include:      []string{},
+		Exclude:      []string{},
+		UseGitignore: &useGitignore,
+		MaxFileSize:  &maxFileSize,
+		MaxFileCount: &maxFileCount,
+		Delimiters:   &DelimitersConfig{Left: "{{", Right: "}}"},
+		Preludes:     []string{"**/*." + PRELUDE_FILE_EXTENSION},
+		Mode:         ModeGo,
+		Rules: map[string]string{
+			DiagnosticRuleSyntax:          "error",
+			DiagnosticRuleAnalysis:        "error",
+			DiagnosticRuleExecuteTemplate: "error",
+		},
+		Diagnostics:       DiagnosticsConfig{Scope: DiagnosticsScopeWorkspace},
+		FunctionLibraries: []string{},
+	}
+
+	return config
+}
+
+// Strictly decode a partial config, unknown fields and invalid values are rejected.
+// An empty or 'null' input is a valid config that change nothing
+func DecodeProjectConfig(data []byte) (*ProjectConfig, error) {
+	config := &ProjectConfig{}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return config, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(config)
+	if err != nil {
+		return nil, errors.New("malformated configuration, " + err.Error())
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("malformated configuration, unexpected data after the top-level object")
+	}
+
+	err = validateProjectConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	for index, extension := range config.Extensions {
+		config.Extensions[index] = strings.TrimPrefix(extension, ".")
+	}
+
+	return config, nil
+}
+
+func validateProjectConfig(config *ProjectConfig) error {
+	var errs []error
+
+	if config.Extensions != nil && len(config.Extensions) == 0 {
+		errs = append(errs, errors.New("'extensions' cannot be empty, omit the field to keep the defaults"))
+	}
+
+	for _, extension := range config.Extensions {
+		if strings.TrimPrefix(extension, ".") == "" || strings.ContainsAny(extension, "/\\*?[") {
+			errs = append(errs, errors.New("'extensions' contain an invalid extension '"+extension+"', expected something like 'gohtml'"))
+		}
+	}
+
+	for _, field := range []struct {
+		name     string
+		patterns []string
+	}{{"include", config.Include}, {"exclude", config.Exclude}, {"preludes", config.Preludes}} {
+		for _, pattern := range field.patterns {
+			err := ValidateGlob(pattern)
+			if err != nil {
+				errs = append(errs, errors.New("'"+field.name+"' contain an invalid glob '"+pattern+"', "+err.Error()))
+			}
+		}
+	}
+
+	for _, name := range config.FunctionLibraries {
+		if _, ok := gosource.FUNCTION_LIBRARIES[name]; !ok {
+			errs = append(errs, errors.New("'functionLibraries' contain an unknown library '"+name+"', expected one of "+strings.Join(gosource.GetFunctionLibraryNames(), ", ")))
+		}
+	}
+
+	if config.Mode != "" && !slices.Contains(KNOWN_MODES, config.Mode) {
+		errs = append(errs, errors.New("'mode' has an unknown value '"+config.Mode+"', expected one of "+strings.Join(KNOWN_MODES, ", ")))
+	}
+
+	if config.MaxFileSize != nil && *config.MaxFileSize <= 0 {
+		errs = append(errs, errors.New("'maxFileSize' must be a positive number of bytes"))
+	}
+
+	if config.MaxFileCount != nil && *config.MaxFileCount <= 0 {
+		errs = append(errs, errors.New("'maxFileCount' must be a positive number"))
+	}
+
+	if config.Delimiters != nil {
+		err := validateDelimiters(config.Delimiters.Left, config.Delimiters.Right)
+		if err != nil {
+			errs = append(errs, errors.New("'delimiters' "+err.Error()))
+		}
+	}
+
+	for index, override := range config.DelimitersOverrides {
+		name := "'delimitersOverrides[" + strconv.Itoa(index) + "]'"
+
+		err := ValidateGlob(override.Files)
+		if err != nil {
+			errs = append(errs, errors.New(name+" has an invalid 'files' glob '"+override.Files+"', "+err.Error()))
+		}
+
+		err = validateDelimiters(override.Left, override.Right)
+		if err != nil {
+			errs = append(errs, errors.New(name+" "+err.Error()))
+		}
+	}
+
+	for rule, severity := range config.Rules {
+		if !slices.Contains(KNOWN_DIAGNOSTIC_RULES, rule) {
+			errs = append(errs, errors.New("'rules' contain an unknown rule '"+rule+"', expected one of "+strings.Join(KNOWN_DIAGNOSTIC_RULES, ", ")))
+		}
+
+		if _, ok := DIAGNOSTIC_SEVERITIES[severity]; !ok {
+			errs = append(errs, errors.New("'rules."+rule+"' has an unknown severity '"+severity+"', expected one of error, warning, information, hint, off"))
+		}
+	}
+
+	switch config.Diagnostics.Scope {
+	case "", DiagnosticsScopeWorkspace, DiagnosticsScopeOpenFiles:
+	default:
+		errs = append(errs, errors.New("'diagnostics.scope' has an unknown value '"+config.Diagnostics.Scope+"', expected '"+DiagnosticsScopeWorkspace+"' or '"+DiagnosticsScopeOpenFiles+"'"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// The translation of custom delimiters into '{{' and '}}' keep the length of the file,
+// a delimiter of a single character borrow a space next to it (see 'TranslateDelimiters()')
+func validateDelimiters(left string, right string) error {
+	if left == "" || right == "" {
+		return errors.New("must have a 'left' and 'right', got '" + left + "' and '" + right + "'")
+	}
+
+	if strings.ContainsAny(left+right, " \t\r\n") {
+		return errors.New("cannot contain whitespace, got '" + left + "' and '" + right + "'")
+	}
+
+	return nil
+}
+
+// Return a new config where every field set in 'override' replace the one of 'base'.
+// Rules are merged one by one
+func MergeProjectConfig(base *ProjectConfig, override *ProjectConfig) *ProjectConfig {
+	merged := &ProjectConfig{}
+	if base != nil {
+		*merged = *base
+		merged.Rules = maps.Clone(base.Rules)
+	}
+
+	if override == nil {
+		return merged
+	}
+
+	if override.Extensions != nil {
+		merged.Extensions = slices.Clone(override.Extensions)
+	}
+
+	if override.Include != nil {
+		merged.Include = slices.Clone(override.Include)
+	}
+
+	if override.Exclude != nil {
+		merged.Exclude = slices.Clone(override.Exclude)
+	}
+
+	if override.UseGitignore != nil {
+		merged.UseGitignore = override.UseGitignore
+	}
+
+	if override.MaxFileSize != nil {
+		merged.MaxFileSize = override.MaxFileSize
+	}
+
+	if override.MaxFileCount != nil {
+		merged.MaxFileCount = override.MaxFileCount
+	}
+
+	if override.Preludes != nil {
+		merged.Preludes = slices.Clone(override.Preludes)
+	}
+
+	if override.Mode != "" {
+		merged.Mode = override.Mode
+	}
+
+	if override.FunctionLibraries != nil {
+		merged.FunctionLibraries = slices.Clone(override.FunctionLibraries)
+	}
+
+	if override.Delimiters != nil {
+		delimiters := *override.Delimiters
+		merged.Delimiters = &delimiters
+	}
+
+	if override.DelimitersOverrides != nil {
+		merged.DelimitersOverrides = slices.Clone(override.DelimitersOverrides)
+	}
+
+	for rule, severity := range override.Rules {
+		if merged.Rules == nil {
+			merged.Rules = make(map[string]string)
+		}
+
+		merged.Rules[rule] = severity
+	}
+
+	if override.Diagnostics.Scope != "" {
+		merged.Diagnostics.Scope = override.Diagnostics.Scope
+	}
+
+	return merged
+}
+
+// Whether both configs hold the same values, eg. to skip the reload of the workspace when the settings did not change
+func (config *ProjectConfig) Equal(other *ProjectConfig) bool {
+	return reflect.DeepEqual(config, other)
+}
+
+// LSP severity of the rule, 0 when the rule is turned off
+func (config *ProjectConfig) GetRuleSeverity(rule string) int {
+	if config == nil {
+		return 1
+	}
+
+	severity, ok := config.Rules[rule]
+	if !ok {
+		return 1
+	}
+
+	return DIAGNOSTIC_SEVERITIES[severity]
+}
+
+func (config *ProjectConfig) IsGitignoreUsed() bool {
+	return config == nil || config.UseGitignore == nil || *config.UseGitignore
+}
+
+// 0 when there is no limit
+func (config *ProjectConfig) GetMaxFileSize() int64 {
+	if config == nil || config.MaxFileSize == nil {
+		return 0
+	}
+
+	return *config.MaxFileSize
+}
+
+// 0 when there is no limit
+func (config *ProjectConfig) GetMaxFileCount() int {
+	if config == nil || config.MaxFileCount == nil {
+		return 0
+	}
+
+	return *config.MaxFileCount
+}
+
+func (config *ProjectConfig) IsHugoMode() bool {
+	return config != nil && config.Mode == ModeHugo
+}
+
+func (config *ProjectConfig) IsHelmMode() bool {
+	return config != nil && config.Mode == ModeHelm
+}
+
+// Extensions of the template files, those of the Helm charts included in Helm mode
+func (config *ProjectConfig) GetExtensions() []string {
+	if config == nil {
+		return nil
+	}
+
+	if !config.IsHelmMode() {
+		return config.Extensions
+	}
+
+	extensions := slices.Clone(config.Extensions)
+	for _, extension := range HELM_FILE_EXTENSIONS {
+		if !slices.Contains(extensions, extension) {
+			extensions = append(extensions, extension)
+		}
+	}
+
+	return extensions
+}
+
+// Names of the libraries enabled by the project, the one of the mode included (eg. 'hugo')
+func (config *ProjectConfig) getLibraryNames() []string {
+	if config == nil {
+		return nil
+	}
+
+	names := slices.Clone(config.FunctionLibraries)
+	if config.IsHugoMode() && !slices.Contains(names, ModeHugo) {
+		names = append(names, ModeHugo)
+	}
+
+	// the Helm library already hold the functions of Sprig that Helm keep
+	if config.IsHelmMode() {
+		names = slices.DeleteFunc(names, func(name string) bool { return name == "sprig" || name == ModeHelm })
+		names = append(names, ModeHelm)
+	}
+
+	return names
+}
+
+// Functions of the libraries enabled by the project, declared like the functions of a 'FuncMap'
+func (config *ProjectConfig) GetLibraryFunctions() []gosource.TemplateFunction {
+	return gosource.GetLibraryFunctions(config.getLibraryNames())
+}
+
+// Go code of the types used by the library functions (eg. 'Page' of Hugo), empty when there is none
+func (config *ProjectConfig) GetLibraryDeclarations() string {
+	return gosource.GetLibraryDeclarations(config.getLibraryNames())
+}
+
+// Whether diagnostics of the file must be sent to the client, 'openedDocuments' are the ones of the session
+func (config *ProjectConfig) IsDiagnosticReported(fileUri string, openedDocuments *OpenedDocuments) bool {
+	if config == nil || config.Diagnostics.Scope != DiagnosticsScopeOpenFiles {
+		return true
+	}
+
+	return openedDocuments.Contains(fileUri)
+}
+
+// Whether the file belong to the workspace rooted at 'rootUri', according to the extensions and include/exclude globs.
+// Globs are matched against the path relative to the root
+func (config *ProjectConfig) IsFileIncluded(rootUri string, fileUri string) bool {
+	if config == nil {
+		return false
+	}
+
+	if !strings.HasPrefix(fileUri, rootUri+"/") {
+		return false
+	}
+
+	if !gota.HasFileExtension(fileUri, config.GetExtensions()) {
+		return false
+	}
+
+	relativePath := strings.TrimPrefix(fileUri, rootUri+"/")
+	if unescaped, err := url.PathUnescape(relativePath); err == nil {
+		relativePath = unescaped
+	}
+
+	return config.IsPathIncluded(relativePath)
+}
+
+// Same as 'IsFileIncluded()' for a path already relative to the root (separated by '/'), the extension is not checked
+func (config *ProjectConfig) IsPathIncluded(relativePath string) bool {
+	if config == nil {
+		return false
+	}
+
+	if len(config.Include) > 0 && !slices.ContainsFunc(config.Include, func(pattern string) bool { return MatchGlob(pattern, relativePath) }) {
+		return false
+	}
+
+	if slices.ContainsFunc(config.Exclude, func(pattern string) bool { return MatchGlob(pattern, relativePath) }) {
+		return false
+	}
+
+	return config.IsModeTemplatePath(relativePath)
+}
+
+// Whether the path can hold a template in the mode of the config, whatever the globs
+func (config *ProjectConfig) IsModeTemplatePath(relativePath string) bool {
+	// the other files of a chart are plain YAML (eg. 'values.yaml', 'Chart.yaml')
+	if config.IsHelmMode() && !isHelmTemplatePath(relativePath) {
+		return false
+	}
+
+	return true
+}
+
+// Whether the file is a prelude of the workspace rooted at 'rootUri', whose go code is shared by the templates.
+// The exclude globs apply, but not the extensions nor the include globs
+func (config *ProjectConfig) IsPreludeFile(rootUri string, fileUri string) bool {
+	if config == nil || rootUri == "" || !strings.HasPrefix(fileUri, rootUri+"/") {
+		return false
+	}
+
+	relativePath := strings.TrimPrefix(fileUri, rootUri+"/")
+	if unescaped, err := url.PathUnescape(relativePath); err == nil {
+		relativePath = unescaped
+	}
+
+	return config.IsPreludePath(relativePath)
+}
+
+// Same as 'IsPreludeFile()' for a path already relative to the root (separated by '/').
+// In Helm mode, the values of the charts are preludes too, see 'isHelmValuesPath()'
+func (config *ProjectConfig) IsPreludePath(relativePath string) bool {
+	if config == nil {
+		return false
+	}
+
+	isPrelude := slices.ContainsFunc(config.Preludes, func(pattern string) bool { return MatchGlob(pattern, relativePath) })
+	if !isPrelude && !(config.IsHelmMode() && isHelmValuesPath(relativePath)) {
+		return false
+	}
+
+	return !slices.ContainsFunc(config.Exclude, func(pattern string) bool { return MatchGlob(pattern, relativePath) })
+}
+
+// Whether the whole directory (relative to the root) is excluded, and thus do not need to be walked
+func (config *ProjectConfig) IsDirectoryExcluded(relativePath string) bool {
+	if config == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(config.Exclude, func(pattern string) bool { return MatchGlob(pattern, relativePath) })
+}
+
+// Every pattern segment must be understood by 'path.Match()', or be '**'
+func ValidateGlob(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+
+	if strings.HasPrefix(pattern, "/") {
+		return errors.New("pattern must be relative to the workspace root")
+	}
+
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+
+		_, err := path.Match(segment, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Match a '/' separated path against a glob.
+// '*', '?' and '[...]' behave like 'path.Match()' within a single segment,
+// while a '**' segment match zero or more whole segments (eg. 'node_modules/**', '**/*.html')
+func MatchGlob(pattern string, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(patterns []string, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			patterns = patterns[1:]
+
+			if len(patterns) == 0 {
+				return true
+			}
+
+			for skip := 0; skip <= len(names); skip++ {
+				if matchGlobSegments(patterns, names[skip:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(patterns[0], names[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		patterns = patterns[1:]
+		names = names[1:]
+	}
+
+	return len(names) == 0
+}
+
+type DidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+// Return the client settings, unwrapped from their section.
+// The notification usually carry the settings of every tool of the editor, so a missing section mean no settings.
+// A nil result mean the client sent no settings (eg. pull model clients)
+func ProcessDidChangeConfigurationNotification(data []byte) json.RawMessage {
+	var request RequestMessage[DidChangeConfigurationParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		slog.Warn("error while unmarshalling data during 'workspace/didChangeConfiguration' phase, "+err.Error(),
+			slog.String("received_req", string(data)),
+		)
+		return nil
+	}
+
+	section, ok := getSettingsSection(request.Params.Settings)
+	if !ok {
+		return nil
+	}
+
+	return section
+}
+
+// Return the 'initializationOptions' sent by the client during 'initialize'.
+// They are only meant for this server, so they may or may not be nested under their section
+func GetInitializationOptions(data []byte) json.RawMessage {
+	var request RequestMessage[InitializeParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		return nil
+	}
+
+	if section, ok := getSettingsSection(request.Params.InitializationOptions); ok {
+		return section
+	}
+
+	return request.Params.InitializationOptions
+}
+
+// Settings nested under 'SETTINGS_SECTION_NAME', 'ok' is false when the section is missing
+func getSettingsSection(settings json.RawMessage) (section json.RawMessage, ok bool) {
+	var sections map[string]json.RawMessage
+
+	err := json.Unmarshal(settings, &sections)
+	if err != nil {
+		return nil, false
+	}
+
+	section, ok = sections[SETTINGS_SECTION_NAME]
+	return section, ok
+}
+
+type ConfigurationItem struct {
+	Section string `json:"section,omitempty"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+// Params of the 'workspace/configuration' request, which pull the settings from the clients
+// that do not push them along 'workspace/didChangeConfiguration'
+func NewConfigurationParams() ConfigurationParams {
+	return ConfigurationParams{Items: []ConfigurationItem{{Section: SETTINGS_SECTION_NAME}}}
+}
+
+// Return the settings found in the result of the 'workspace/configuration' request.
+// A nil result mean the client has no settings for the server
+func ProcessConfigurationResponse(result json.RawMessage) json.RawMessage {
+	var sections []json.RawMessage
+
+	err := json.Unmarshal(result, &sections)
+	if err != nil {
+		slog.Warn("error while unmarshalling the result of 'workspace/configuration', "+err.Error(),
+			slog.String("received_res", string(result)),
+		)
+		return nil
+	}
+
+	if len(sections) == 0 || bytes.Equal(bytes.TrimSpace(sections[0]), []byte("null")) {
+		return nil
+	}
+
+	return sections[0]
+}
+
+type ShowMessageParams struct {
+	Type    int    `json:"type"` // 1 = Error, 2 = Warning, 3 = Info, 4 = Log
+	Message string `json:"message"`
+}
+
+// Display a message to the user ('window/showMessage')
+func SendShowMessage(output io.Writer, messageType int, message string) {
+	notification := NotificationMessage[ShowMessageParams]{
+		JsonRpc: "2.0",
+		Method:  "window/showMessage",
+		Params: ShowMessageParams{
+			Type:    messageType,
+			Message: message,
+		},
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		msg := ("error while marshalling 'window/showMessage' notification, " + err.Error())
+		slog.Error(msg, slog.Any("notification", notification))
+		panic(msg)
+	}
+
+	SendToLspClient(output, data)
+}
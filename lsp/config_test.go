@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.html", name: "index.html", want: true},
+		{pattern: "*.html", name: "views/index.html", want: false},
+		{pattern: "**/*.html", name: "index.html", want: true},
+		{pattern: "**/*.html", name: "views/admin/index.html", want: true},
+		{pattern: "node_modules/**", name: "node_modules/pkg/index.html", want: true},
+		{pattern: "node_modules/**", name: "src/node_modules/index.html", want: false},
+		{pattern: "**/node_modules/**", name: "src/node_modules/pkg/index.html", want: true},
+		{pattern: "views/**/partials/*.tmpl", name: "views/partials/nav.tmpl", want: true},
+		{pattern: "views/**/partials/*.tmpl", name: "views/a/b/partials/nav.tmpl", want: true},
+		{pattern: "views/**/partials/*.tmpl", name: "views/a/b/nav.tmpl", want: false},
+		{pattern: "page?.[gt]mpl", name: "page1.tmpl", want: true},
+		{pattern: "page?.[gt]mpl", name: "page12.tmpl", want: false},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := MatchGlob(test.pattern, test.name)
+			if got != test.want {
+				t.Errorf("\n Pattern: %s \n Name: %s \n Expected: %v \n Got: %v", test.pattern, test.name, test.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeProjectConfig(t *testing.T) {
+	tests := []struct {
+		input   string
+		isError bool
+	}{
+		{input: "", isError: false},
+		{input: "null", isError: false},
+		{input: `{"extensions": [".gohtml", "tmpl"], "exclude": ["node_modules/**"]}`, isError: false},
+		{input: `{"rules": {"syntax": "warning", "analysis": "off"}, "diagnostics": {"scope": "openFiles"}}`, isError: false},
+		{input: `{"extension": ["gohtml"]}`, isError: true},
+		{input: `{"extensions": []}`, isError: true},
+		{input: `{"include": ["views/[a-"]}`, isError: true},
+		{input: `{"preludes": ["types/*.gotypes"]}`, isError: false},
+		{input: `{"preludes": ["types/[a-"]}`, isError: true},
+		{input: `{"functionLibraries": ["sprig"]}`, isError: false},
+		{input: `{"functionLibraries": ["lodash"]}`, isError: true},
+		{input: `{"mode": "hugo"}`, isError: false},
+		{input: `{"mode": "jekyll"}`, isError: true},
+		{input: `{"mode": "helm", "functionLibraries": ["sprig"]}`, isError: false},
+		{input: `{"delimiters": {"left": "[["}}`, isError: true},
+		{input: `{"delimiters": {"left": "<", "right": ">"}}`, isError: false},
+		{input: `{"delimitersOverrides": [{"files": "admin/**", "left": "[[", "right": "]]"}]}`, isError: false},
+		{input: `{"delimitersOverrides": [{"files": "admin/[", "left": "[[", "right": "]]"}]}`, isError: true},
+		{input: `{"rules": {"syntax": "fatal"}}`, isError: true},
+		{input: `{"rules": {"unused": "error"}}`, isError: true},
+		{input: `{"diagnostics": {"scope": "everything"}}`, isError: true},
+		{input: `{} {}`, isError: true},
+		{input: `{"useGitignore": false, "maxFileSize": 4096, "maxFileCount": 100}`, isError: false},
+		{input: `{"maxFileSize": 0}`, isError: true},
+		{input: `{"maxFileCount": -3}`, isError: true},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			_, err := DecodeProjectConfig([]byte(test.input))
+			if (err != nil) != test.isError {
+				t.Errorf("\n Input: %s \n Expected error: %v \n Got: %v", test.input, test.isError, err)
+			}
+		})
+	}
+}
+
+func TestClientSettings(t *testing.T) {
+	tests := []struct {
+		method string
+		input  string
+		want   string
+	}{
+		{method: "workspace/didChangeConfiguration", input: `{"settings": {"go-template-lsp": {"mode": "hugo"}}}`, want: `{"mode": "hugo"}`},
+		{method: "workspace/didChangeConfiguration", input: `{"settings": {"gopls": {"gofumpt": true}}}`, want: ""},
+		{method: "workspace/didChangeConfiguration", input: `{"settings": {"mode": "hugo"}}`, want: ""},
+		{method: "workspace/didChangeConfiguration", input: `{"settings": null}`, want: ""},
+		{method: "initialize", input: `{"initializationOptions": {"go-template-lsp": {"mode": "hugo"}}}`, want: `{"mode": "hugo"}`},
+		{method: "initialize", input: `{"initializationOptions": {"mode": "hugo"}}`, want: `{"mode": "hugo"}`},
+		{method: "workspace/configuration", input: `[{"mode": "hugo"}]`, want: `{"mode": "hugo"}`},
+		{method: "workspace/configuration", input: `[null]`, want: ""},
+		{method: "workspace/configuration", input: `[]`, want: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			data := []byte(`{"jsonrpc": "2.0", "method": "` + test.method + `", "params": ` + test.input + `}`)
+
+			var got []byte
+			switch test.method {
+			case "workspace/didChangeConfiguration":
+				got = ProcessDidChangeConfigurationNotification(data)
+			case "initialize":
+				got = GetInitializationOptions(data)
+			case "workspace/configuration":
+				got = ProcessConfigurationResponse([]byte(test.input))
+			}
+
+			if string(got) != test.want {
+				t.Errorf("\n Method: %s \n Input: %s \n Expected: %s \n Got: %s", test.method, test.input, test.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeProjectConfig(t *testing.T) {
+	base := DefaultProjectConfig([]string{"html"})
+
+	override, err := DecodeProjectConfig([]byte(`{"extensions": [".gohtml"], "rules": {"analysis": "warning"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	merged := MergeProjectConfig(base, override)
+
+	if len(merged.Extensions) != 1 || merged.Extensions[0] != "gohtml" {
+		t.Errorf("extensions not overridden, got %v", merged.Extensions)
+	}
+
+	if merged.GetRuleSeverity(DiagnosticRuleAnalysis) != 2 || merged.GetRuleSeverity(DiagnosticRuleSyntax) != 1 {
+		t.Errorf("rules not merged one by one, got %v", merged.Rules)
+	}
+
+	if base.Rules[DiagnosticRuleAnalysis] != "error" {
+		t.Errorf("base config modified by the merge, got %v", base.Rules)
+	}
+
+	if merged.Diagnostics.Scope != DiagnosticsScopeWorkspace || merged.Delimiters.Left != "{{" {
+		t.Errorf("fields not set by the override must be kept, got %+v", merged)
+	}
+
+	// the same settings sent again are merged into an equal config
+	sameOverride, _ := DecodeProjectConfig([]byte(`{"rules": {"analysis": "warning"}, "extensions": ["gohtml"]}`))
+	if same := MergeProjectConfig(base, sameOverride); !same.Equal(merged) {
+		t.Errorf("\n Expected: %+v \n Got: %+v", merged, same)
+	}
+
+	if merged.Equal(base) {
+		t.Errorf("config merged with an override must differ from its base, got %+v", merged)
+	}
+}
@@ -0,0 +1,220 @@
+package lsp
+
+import (
+	"bytes"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Call of a template by a function given its name as a string literal, along the context given to it.
+// eg. '{{ partial "header.html" . }}' of Hugo, or '{{ include "chart.labels" . }}' of Helm
+type contextCall struct {
+	Name      string
+	NameStart int    // offset of the string literal, quotes included
+	NameEnd   int    // offset right after the string literal
+	InputType string // go type of the context given to the template, empty when unknown
+}
+
+// '.' and '$' within a block of the template
+type contextScope struct {
+	dot  string
+	root string
+}
+
+// Whether the content might call a template whose context is found at its call sites (see 'GetContextCallInputs()'),
+// eg. 'partial' of Hugo or 'include' of Helm
+func (storage *WorkSpaceStore) HasContextCalls(content []byte) bool {
+	switch {
+	case storage.Config.IsHugoMode():
+		return bytes.Contains(content, []byte("partial"))
+	case storage.Config.IsHelmMode():
+		return bytes.Contains(content, []byte("include")) || bytes.Contains(content, []byte("template"))
+	}
+
+	return false
+}
+
+// Go type of the context of the templates found at their call sites, by the Hugo or the Helm mode.
+// Key: uri of the file holding the templates, the value change whenever the type of one of them change.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GetContextCallInputs() map[string]string {
+	if storage.Config.IsHugoMode() {
+		return storage.GetHugoPartialInputs()
+	}
+
+	inputs := make(map[string]string)
+	if !storage.Config.IsHelmMode() {
+		return inputs
+	}
+
+	charts := storage.getHelmCharts()
+	for topChartDir, templateInputs := range storage.GetHelmTemplateInputs() {
+		for uri, content := range charts[topChartDir] {
+			var fileInputs []string
+			for _, scope := range findTemplateScopes(content)[1:] {
+				if inputType := templateInputs[scope.Name]; inputType != "" {
+					fileInputs = append(fileInputs, scope.Name+" "+inputType)
+				}
+			}
+
+			if len(fileInputs) > 0 {
+				inputs[uri] = strings.Join(fileInputs, "\n")
+			}
+		}
+	}
+
+	return inputs
+}
+
+// Lexical scan of the calls of the content (delimiters translated) matched by 'callPattern', whose first group is the name literal.
+// '.' is tracked through the blocks of the file: 'scopeType' give its type at the root of the file (empty name),
+// and within the '{{ define }}' of that name. Within a 'with' block, it is the type of the argument when known.
+// A 'range' over pages (eg. '.Pages', '.Site.RegularPages' of Hugo) give a 'Page', the other blocks an unknown type
+func findContextCalls(content []byte, callPattern *regexp.Regexp, scopeType func(name string) string) []contextCall {
+	var calls []contextCall
+
+	rootType := scopeType("")
+	scopes := []contextScope{{dot: rootType, root: rootType}} // blocks open at the current action
+
+	// type of an argument evaluated within the scope
+	typeOf := func(word string, scope contextScope) string {
+		context := scope.dot
+		if strings.HasPrefix(word, "$") {
+			context = scope.root
+		}
+
+		switch {
+		case word == "." || word == "$":
+			return context
+		case word == "page" || ((word == ".Page" || word == "$.Page") && (context == "Page" || context == "Shortcode")):
+			return "Page"
+		case word == "site" || ((word == ".Site" || word == "$.Site") && (context == "Page" || context == "Shortcode")):
+			return "Site"
+		case strings.HasPrefix(word, "(dict ") || word == "(dict)":
+			return "map[string]any"
+		}
+
+		return ""
+	}
+
+	for offset := 0; offset < len(content); {
+		start := bytes.Index(content[offset:], []byte("{{"))
+		if start < 0 {
+			break
+		}
+
+		start += offset + 2
+
+		end := bytes.Index(content[start:], []byte("}}"))
+		if end < 0 {
+			break
+		}
+
+		end += start
+		offset = end + 2
+
+		action := content[start:end]
+		if bytes.HasPrefix(bytes.TrimLeft(bytes.TrimPrefix(action, []byte("-")), " \t\r\n"), []byte("/*")) {
+			if commentEnd := bytes.Index(content[start:], []byte("*/")); commentEnd >= 0 {
+				if closing := bytes.Index(content[start+commentEnd:], []byte("}}")); closing >= 0 {
+					offset = start + commentEnd + closing + 2
+				}
+			}
+
+			continue
+		}
+
+		scope := scopes[len(scopes)-1]
+
+		for _, match := range callPattern.FindAllSubmatchIndex(action, -1) {
+			name, err := strconv.Unquote(string(action[match[2]:match[3]]))
+			if err != nil {
+				continue
+			}
+
+			inputType := ""
+
+			arguments := splitCommandWords(action[match[3]:])
+			if len(arguments) > 0 {
+				argument := arguments[0]
+				if !strings.HasPrefix(argument, "(") {
+					argument = strings.TrimRight(argument, ")")
+				}
+
+				inputType = typeOf(argument, scope)
+			}
+
+			calls = append(calls, contextCall{Name: name, NameStart: start + match[2], NameEnd: start + match[3], InputType: inputType})
+		}
+
+		words := splitCommandWords(bytes.Trim(action, "- \t\r\n"))
+		if len(words) == 0 {
+			continue
+		}
+
+		// the variables declared by 'range' and 'with' do not change '.'
+		pipeline := words[1:]
+		if index := slices.IndexFunc(pipeline, func(word string) bool { return word == ":=" || word == "=" }); index >= 0 {
+			pipeline = pipeline[index+1:]
+		}
+
+		switch words[0] {
+		case "if":
+			scopes = append(scopes, scope)
+		case "with":
+			inputType := ""
+			if len(pipeline) == 1 {
+				inputType = typeOf(pipeline[0], scope)
+			}
+
+			scopes = append(scopes, contextScope{dot: inputType, root: scope.root})
+		case "range":
+			inputType := ""
+			if len(pipeline) > 0 && strings.HasSuffix(pipeline[0], "Pages") {
+				inputType = "Page"
+			} else if len(pipeline) > 1 && slices.Contains([]string{"where", "first", "last", "after", "sort", "shuffle", "uniq"}, pipeline[0]) &&
+				slices.ContainsFunc(pipeline[1:min(3, len(pipeline))], func(word string) bool { return strings.HasSuffix(word, "Pages") }) {
+				inputType = "Page"
+			}
+
+			scopes = append(scopes, contextScope{dot: inputType, root: scope.root})
+		case "define":
+			name := ""
+			if len(words) > 1 {
+				name, _ = strconv.Unquote(words[1])
+			}
+
+			inputType := scopeType(name)
+			scopes = append(scopes, contextScope{dot: inputType, root: inputType})
+		case "block":
+			inputType := ""
+			if len(words) > 2 {
+				inputType = typeOf(words[2], scope)
+			}
+
+			scopes = append(scopes, contextScope{dot: inputType, root: inputType})
+		case "else":
+			if len(scopes) < 2 {
+				break
+			}
+
+			// '.' of the 'else' branch is the one outside of the block, unless it is an 'else with'
+			parent := scopes[len(scopes)-2]
+			scopes[len(scopes)-1] = parent
+
+			if len(pipeline) == 2 && pipeline[0] == "with" {
+				scopes[len(scopes)-1].dot = typeOf(pipeline[1], parent)
+			} else if len(pipeline) > 0 && pipeline[0] == "with" {
+				scopes[len(scopes)-1].dot = ""
+			}
+		case "end":
+			if len(scopes) > 1 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+	}
+
+	return calls
+}
@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Go declaration of the symbol found at 'position' within the 'go:code' injected into the template file (see 'InjectGoCode()'),
+// eg. the struct field of '.Name', the Go function of a 'FuncMap', or the declaration within a prelude file.
+// The position is expected beyond the content of the file.
+// Invalid when the position is not within an injected 'go:code', or when the symbol is not declared by the Go code (eg. basic type).
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) FindInjectedGoDeclaration(uri string, position lexer.Position) token.Position {
+	content, ok := storage.RawFiles[uri]
+	if !ok || (len(storage.GoSource.Symbols) == 0 && len(storage.Preludes) == 0) {
+		return token.Position{}
+	}
+
+	executions, functions := storage.GetGoSourceOfFile(uri)
+	prelude, preludeUris := storage.GetPreludeOfFile(uri)
+
+	content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+	content = InjectGoCode(content, path.Base(uri), executions, functions, prelude)
+
+	lineIndex := NewLineIndex(content, PositionEncodingUTF8)
+	if position.Line < 0 || position.Line >= len(lineIndex.lineStarts) {
+		return token.Position{}
+	}
+
+	offset := lineIndex.lineStarts[position.Line] + position.Character
+	if offset >= len(content) {
+		return token.Position{}
+	}
+
+	const header = "{{/* go:code "
+
+	start := bytes.LastIndex(content[:offset+1], []byte(header))
+	if start < 0 {
+		return token.Position{}
+	}
+
+	start += len(header)
+
+	end := bytes.Index(content[start:], []byte(" */}}"))
+	if end < 0 || start+end < offset {
+		return token.Position{}
+	}
+
+	goCode := string(content[start : start+end])
+	offset -= start
+
+	// the prelude declare its symbols itself
+	if prelude != "" && goCode == prelude {
+		return storage.getPreludePosition(preludeUris, offset)
+	}
+
+	candidates := make([]string, 0, len(executions)+len(functions))
+	for _, execution := range executions {
+		candidates = append(candidates, execution.GoCode)
+	}
+
+	for _, function := range functions {
+		candidates = append(candidates, function.GoCode)
+	}
+
+	// the comment hold either the 'Input' type of an execution, or the signatures of the functions joined by ';'
+	for _, candidate := range candidates {
+		index := strings.Index(goCode, candidate)
+		if candidate == "" || index < 0 || offset < index || offset >= index+len(candidate) {
+			continue
+		}
+
+		symbols, ok := storage.GoSource.Symbols[candidate]
+		if !ok {
+			continue
+		}
+
+		symbolPath := gosource.FindSymbolPath(candidate, offset-index)
+		if symbolPath == "" {
+			continue
+		}
+
+		return symbols[symbolPath]
+	}
+
+	return token.Position{}
+}
+
+// String literal of an 'ExecuteTemplate()' call of the Go code, and the template it execute
+type TemplateCallLink struct {
+	Uri         string // go file
+	Range       Range  // string literal, quotes included
+	TargetUri   string
+	TargetRange Range // '{{ define }}' action, or the start of the file for the template named after the file
+}
+
+// Links under the cursor of the 'textDocument/definition' request
+func FindTemplateCallLinksAt(data []byte, links []TemplateCallLink) []TemplateCallLink {
+	var request RequestMessage[DefinitionParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/definition' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	cursor := Range{Start: request.Params.Position, End: request.Params.Position}
+
+	var found []TemplateCallLink
+	for _, link := range links {
+		if link.Uri == fileUri && isRangeOverlapping(link.Range, cursor) {
+			found = append(found, link)
+		}
+	}
+
+	return found
+}
+
+// Jump from the template name of an 'ExecuteTemplate()' call to the template definition
+func ProcessGoSourceDefinition(data []byte, links []TemplateCallLink) []byte {
+	var request RequestMessage[DefinitionParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/definition' request of go file, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	response := ResponseMessage[[]DefinitionResults]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+		Result:  []DefinitionResults{},
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	cursor := Range{Start: request.Params.Position, End: request.Params.Position}
+
+	for _, link := range links {
+		if link.Uri != fileUri || !isRangeOverlapping(link.Range, cursor) {
+			continue
+		}
+
+		result := DefinitionResults{Location: Location{Uri: link.TargetUri, Range: link.TargetRange}}
+		response.Result = append(response.Result, result)
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessGoSourceDefinition(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestFindInjectedGoDeclaration(t *testing.T) {
+	inputType := "type Input struct { Name string }"
+	upper := "func upper(string) string"
+
+	declaration := func(line int) token.Position {
+		return token.Position{Filename: "/app/main.go", Line: line, Column: 6}
+	}
+
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Config:   DefaultProjectConfig([]string{"html"}),
+		RawFiles: map[string][]byte{
+			"file:///app/views/home.html": []byte("{{ upper .Name }}\n"),
+		},
+		GoSource: gosource.Discovery{
+			Executions: []gosource.TemplateExecution{{TemplateName: "home.html", GoCode: inputType}},
+			Functions:  []gosource.TemplateFunction{{Name: "upper", GoCode: upper}},
+			Symbols: map[string]gosource.GoCodeSymbols{
+				inputType: {"Input": declaration(3), "Input.Name": declaration(4)},
+				upper:     {"upper": declaration(10)},
+			},
+		},
+	}
+
+	// injected: line 2 '{{/* go:code func upper(string) string */}}', line 3 '{{/* go:code type Input struct { Name string } */}}'
+	tests := []struct {
+		position lexer.Position
+		want     token.Position
+	}{
+		{position: lexer.Position{Line: 2, Character: 18}, want: declaration(10)},
+		{position: lexer.Position{Line: 3, Character: 18}, want: declaration(3)},
+		{position: lexer.Position{Line: 3, Character: 35}, want: declaration(4)},
+		{position: lexer.Position{Line: 3, Character: 38}}, // basic type
+		{position: lexer.Position{Line: 0, Character: 4}},  // template content
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := storage.FindInjectedGoDeclaration("file:///app/views/home.html", test.position)
+			if got != test.want {
+				t.Errorf("\n Position: %+v \n Expected: %v \n Got: %v", test.position, test.want, got)
+			}
+		})
+	}
+}
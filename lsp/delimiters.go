@@ -0,0 +1,247 @@
+package lsp
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+var DEFAULT_DELIMITERS = DelimitersConfig{Left: "{{", Right: "}}"}
+
+// Delimiters used by the files matching the glob 'Files' (relative to the workspace root).
+// A whole directory is targeted with a glob like 'admin/**'
+type DelimitersOverride struct {
+	Files string `json:"files"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// Delimiters of the file, the last matching override win over the 'delimiters' of the config.
+// In rootless mode ('rootUri' is empty) only the 'delimiters' of the config apply
+func (config *ProjectConfig) GetDelimiters(rootUri string, fileUri string) DelimitersConfig {
+	if config == nil {
+		return DEFAULT_DELIMITERS
+	}
+
+	delimiters := DEFAULT_DELIMITERS
+	if config.Delimiters != nil {
+		delimiters = *config.Delimiters
+	}
+
+	if rootUri == "" || !strings.HasPrefix(fileUri, rootUri+"/") {
+		return delimiters
+	}
+
+	relativePath := strings.TrimPrefix(fileUri, rootUri+"/")
+	if unescaped, err := url.PathUnescape(relativePath); err == nil {
+		relativePath = unescaped
+	}
+
+	for _, override := range config.DelimitersOverrides {
+		if MatchGlob(override.Files, relativePath) {
+			delimiters = DelimitersConfig{Left: override.Left, Right: override.Right}
+		}
+	}
+
+	return delimiters
+}
+
+// Rewrite the actions delimited by the custom delimiters into '{{' and '}}', while preserving the byte length.
+// The parser only understand '{{' and '}}', so this is done before parsing (see 'WorkSpaceStore.ParseFile()')
+//
+//   - delimiters longer than 2 bytes are padded with spaces outside of the action ('<<<' -> ' {{', '>>>' -> '}} '),
+//     so the trim markers ('{{-', '-}}') and comments ('{{/*') still stick to the delimiters
+//   - a delimiter of a single byte borrow its missing byte from a space next to it, outside of the action ('a [.Name] b' -> 'a{{.Name}}b')
+//     or else within it ('[ .Name ]' -> '{{.Name}}'). The positions within the action are kept, and the rest of the text is untouched.
+//     An action with neither (eg. '[.Name]') cannot be seen by the parser, it is reported instead (see 'newDelimitersErrors()')
+//   - the right delimiter is not looked for within the string literals and the comments of the action, like 'text/template' do
+//   - the '{{' found outside of the custom actions (eg. Vue/Alpine markup) are neutralised into '{ ',
+//     otherwise the parser would see actions where 'text/template' see plain text
+//
+// The content is returned as is for the default delimiters
+func TranslateDelimiters(content []byte, delimiters DelimitersConfig) []byte {
+	translated, _ := translateDelimiters(content, delimiters)
+	return translated
+}
+
+// Action left as plain text by 'TranslateDelimiters()', from its left delimiter to the end of its right delimiter
+type untranslatedAction struct {
+	Start int
+	End   int
+}
+
+// Error of an action that the parser cannot see, see 'TranslateDelimiters()'
+type DelimitersError struct {
+	Message string
+	Range   lexer.Range
+}
+
+func (err DelimitersError) GetError() string {
+	return err.Message
+}
+
+func (err DelimitersError) GetRange() lexer.Range {
+	return err.Range
+}
+
+// Errors of the actions that 'TranslateDelimiters()' cannot translate, ie. an action with a delimiter of a single byte
+// but without space to borrow. 'text/template' execute them, but the parser cannot check them
+func newDelimitersErrors(content []byte, untranslated []untranslatedAction, delimiters DelimitersConfig) []lexer.Error {
+	if len(untranslated) == 0 {
+		return nil
+	}
+
+	lineIndex := NewLineIndex(content, PositionEncodingUTF8)
+	errs := make([]lexer.Error, 0, len(untranslated))
+
+	for _, action := range untranslated {
+		errs = append(errs, DelimitersError{
+			Message: "action not checked, the delimiters '" + delimiters.Left + "' and '" + delimiters.Right +
+				"' need a space next to them to be translated (eg. '" + delimiters.Left + " .Name " + delimiters.Right + "')",
+			Range: lexer.Range{Start: lineIndex.PositionOfOffset(action.Start), End: lineIndex.PositionOfOffset(action.End)},
+		})
+	}
+
+	return errs
+}
+
+// Same as 'TranslateDelimiters()', along the actions left untranslated
+func translateDelimiters(content []byte, delimiters DelimitersConfig) ([]byte, []untranslatedAction) {
+	if delimiters == DEFAULT_DELIMITERS || delimiters.Left == "" || delimiters.Right == "" {
+		return content, nil
+	}
+
+	left := []byte(delimiters.Left)
+	right := []byte(delimiters.Right)
+
+	translated := bytes.Clone(content)
+	borrowedEnd := 0 // the bytes before it belong to a translated action, they cannot be borrowed
+
+	var untranslated []untranslatedAction
+
+	isSpace := func(char byte) bool { return char == ' ' || char == '\t' }
+
+	// space of the plain text that a single byte delimiter can take over, a new line would shift every line below
+	isBorrowable := func(index int) bool {
+		return index >= borrowedEnd && index < len(content) && isSpace(content[index])
+	}
+
+	// text of the file that is not an action, or an action that cannot be translated
+	neutraliseText := func(start int, end int) {
+		for index := start; index+1 < end; index++ {
+			if translated[index] == '{' && translated[index+1] == '{' {
+				translated[index+1] = ' '
+				index++
+			}
+		}
+	}
+
+	textStart := 0
+
+	for index := 0; index < len(content); {
+		if !bytes.HasPrefix(content[index:], left) {
+			index++
+			continue
+		}
+
+		actionStart := index + len(left)
+		rightStart := findActionEnd(content, actionStart, right)
+
+		// where the translated delimiters are written, before the borrowing
+		leftAt, rightAt := index, rightStart
+		isTranslatable := true
+
+		if len(left) == 1 {
+			switch {
+			case isBorrowable(index - 1):
+				leftAt = index - 1
+			case actionStart < rightStart && isSpace(content[actionStart]):
+				actionStart++
+			default:
+				isTranslatable = false
+			}
+		}
+
+		actionEnd := rightStart + len(right) // end of the action, borrowed byte included
+		if rightStart == len(content) {
+			actionEnd = len(content)
+		} else if len(right) == 1 {
+			switch {
+			case isBorrowable(rightStart + 1):
+				actionEnd++
+			case rightStart > actionStart && isSpace(content[rightStart-1]):
+				rightAt--
+			default:
+				isTranslatable = false
+			}
+		}
+
+		if !isTranslatable {
+			untranslated = append(untranslated, untranslatedAction{Start: index, End: min(rightStart+len(right), len(content))})
+			index = rightStart + len(right)
+			continue
+		}
+
+		neutraliseText(textStart, leftAt)
+		if leftAt > 0 && translated[leftAt-1] == '{' {
+			translated[leftAt-1] = ' ' // '{{{' would open the action one byte too early
+		}
+
+		copy(translated[leftAt:], append(bytes.Repeat([]byte(" "), max(0, len(left)-2)), '{', '{'))
+		if rightStart < len(content) {
+			copy(translated[rightAt:], append([]byte("}}"), bytes.Repeat([]byte(" "), max(0, len(right)-2))...))
+		}
+
+		index = actionEnd
+		textStart = actionEnd
+		borrowedEnd = actionEnd
+	}
+
+	neutraliseText(textStart, len(content))
+
+	return translated, untranslated
+}
+
+// Start of the right delimiter closing the action whose content start at 'start', the end of the content when not closed.
+// The string literals, the raw strings and the comment ('/* */' right after the left delimiter) are skipped
+func findActionEnd(content []byte, start int, right []byte) int {
+	index := start
+
+	if comment := bytes.TrimLeft(bytes.TrimPrefix(content[start:], []byte("-")), " \t\r\n"); bytes.HasPrefix(comment, []byte("/*")) {
+		commentStart := len(content) - len(comment)
+
+		commentEnd := bytes.Index(content[commentStart+2:], []byte("*/"))
+		if commentEnd < 0 {
+			return len(content)
+		}
+
+		index = commentStart + 2 + commentEnd + 2
+	}
+
+	for ; index < len(content); index++ {
+		if bytes.HasPrefix(content[index:], right) {
+			return index
+		}
+
+		switch quote := content[index]; quote {
+		case '"', '\'':
+			// an unterminated literal end at the end of the line, like the lexer of 'text/template' do
+			for index++; index < len(content) && content[index] != quote && content[index] != '\n'; index++ {
+				if content[index] == '\\' {
+					index++
+				}
+			}
+		case '`':
+			end := bytes.IndexByte(content[index+1:], '`')
+			if end < 0 {
+				return len(content)
+			}
+
+			index += 1 + end
+		}
+	}
+
+	return len(content)
+}
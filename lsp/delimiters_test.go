@@ -0,0 +1,111 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestTranslateDelimiters(t *testing.T) {
+	tests := []struct {
+		delimiters       DelimitersConfig
+		input            string
+		want             string
+		wantUntranslated []string
+	}{
+		{delimiters: DEFAULT_DELIMITERS, input: "<p>{{ .Name }}</p>", want: "<p>{{ .Name }}</p>"},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "<p>[[ .Name ]]</p>", want: "<p>{{ .Name }}</p>"},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "[[- .Name -]]", want: "{{- .Name -}}"},
+		// vue markup is plain text for 'text/template', it must stay so for the parser
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "<p>{{ msg }}</p>[[ .Name ]]", want: "<p>{  msg }}</p>{{ .Name }}"},
+		{delimiters: DelimitersConfig{Left: "<<<", Right: ">>>"}, input: "a<<<- .Name ->>>b", want: "a {{- .Name -}} b"},
+		{delimiters: DelimitersConfig{Left: "<%", Right: "%>"}, input: "<%/* comment */%>", want: "{{/* comment */}}"},
+		// the right delimiter within strings and comments do not close the action
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: `[[ printf "]]" ]]`, want: `{{ printf "]]" }}`},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: `[[ printf '\'' "\"]]" ]]`, want: `{{ printf '\'' "\"]]" }}`},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "[[ printf `a\n]]` ]]", want: "{{ printf `a\n]]` }}"},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "[[- /* ]] */ -]] {{ x }}", want: "{{- /* ]] */ -}} {  x }}"},
+		{delimiters: DelimitersConfig{Left: "[[", Right: "]]"}, input: "[[ .Name ", want: "{{ .Name "},
+		// a delimiter of a single character borrow a space next to it, the actions without one are reported
+		{delimiters: DelimitersConfig{Left: "[", Right: "]"}, input: "a [.Name] b", want: "a{{.Name}}b"},
+		{delimiters: DelimitersConfig{Left: "[", Right: "]"}, input: "[ .Name ]", want: "{{.Name}}"},
+		{delimiters: DelimitersConfig{Left: "[", Right: "]"}, input: "a[.Name]b", want: "a[.Name]b", wantUntranslated: []string{"[.Name]"}},
+		{
+			delimiters:       DelimitersConfig{Left: "[", Right: "]"},
+			input:            "<p>[.A][ .B ]\n[ \"]\" ]</p>",
+			want:             "<p>[.A]{{.B}}\n{{\"]\"}}</p>",
+			wantUntranslated: []string{"[.A]"},
+		},
+		{delimiters: DelimitersConfig{Left: "[", Right: "]"}, input: "{[ .Name ]", want: " {{.Name}}"},
+		// a space is only borrowed once
+		{delimiters: DelimitersConfig{Left: "[", Right: "]"}, input: "[ .A] [.B ]", want: "{{.A}}[.B ]", wantUntranslated: []string{"[.B ]"}},
+		{delimiters: DelimitersConfig{Left: "<", Right: ">"}, input: "<.Name>\n{{ x }}", want: "<.Name>\n{  x }}", wantUntranslated: []string{"<.Name>"}},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			translated, untranslated := translateDelimiters([]byte(test.input), test.delimiters)
+
+			got := string(translated)
+			if got != test.want {
+				t.Errorf("\n Input: %q \n Expected: %q \n Got: %q", test.input, test.want, got)
+			}
+
+			var gotUntranslated []string
+			for _, action := range untranslated {
+				gotUntranslated = append(gotUntranslated, test.input[action.Start:action.End])
+			}
+
+			if !slices.Equal(gotUntranslated, test.wantUntranslated) {
+				t.Errorf("\n Input: %q \n Expected untranslated: %q \n Got: %q", test.input, test.wantUntranslated, gotUntranslated)
+			}
+
+			if len(got) != len(test.input) {
+				t.Errorf("\n translation must keep the length, input %d bytes but got %d bytes", len(test.input), len(got))
+			}
+		})
+	}
+}
+
+func TestGetDelimiters(t *testing.T) {
+	config := DefaultProjectConfig([]string{"html"})
+	config.DelimitersOverrides = []DelimitersOverride{
+		{Files: "admin/**", Left: "[[", Right: "]]"},
+		{Files: "admin/legacy/*.html", Left: "<%", Right: "%>"},
+	}
+
+	tests := []struct {
+		rootUri string
+		fileUri string
+		want    DelimitersConfig
+	}{
+		{rootUri: "file:///app", fileUri: "file:///app/index.html", want: DEFAULT_DELIMITERS},
+		{rootUri: "file:///app", fileUri: "file:///app/admin/users/list.html", want: DelimitersConfig{Left: "[[", Right: "]]"}},
+		{rootUri: "file:///app", fileUri: "file:///app/admin/legacy/old.html", want: DelimitersConfig{Left: "<%", Right: "%>"}},
+		{rootUri: "", fileUri: "untitled:Untitled-1", want: DEFAULT_DELIMITERS},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := config.GetDelimiters(test.rootUri, test.fileUri)
+			if got != test.want {
+				t.Errorf("\n File: %s \n Expected: %v \n Got: %v", test.fileUri, test.want, got)
+			}
+		})
+	}
+}
+
+func TestNewDelimitersErrors(t *testing.T) {
+	delimiters := DelimitersConfig{Left: "[", Right: "]"}
+	content := []byte("<p>\n  [.Name]</p>")
+
+	_, untranslated := translateDelimiters(content, delimiters)
+	errs := newDelimitersErrors(content, untranslated, delimiters)
+
+	want := lexer.Range{Start: lexer.Position{Line: 1, Character: 2}, End: lexer.Position{Line: 1, Character: 9}}
+	if len(errs) != 1 || errs[0].GetRange() != want {
+		t.Fatalf("\n Expected: a single error at %v \n Got: %v", want, errs)
+	}
+}
@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"strings"
+)
+
+// One line of a '.gitignore' file, converted to a glob relative to the workspace root
+type GitignoreRule struct {
+	Glob      string
+	IsNegated bool // '!pattern', re-include what a previous rule ignored
+	IsDirOnly bool // 'pattern/', only match directories
+}
+
+// Convert the content of the '.gitignore' found in 'directory' (relative to the workspace root, empty for the root itself).
+// Only the common subset of the syntax is supported, invalid patterns are dropped
+func ParseGitignore(content []byte, directory string) []GitignoreRule {
+	var rules []GitignoreRule
+
+	base := ""
+	if directory != "" && directory != "." {
+		base = strings.TrimSuffix(directory, "/") + "/"
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := GitignoreRule{}
+
+		if strings.HasPrefix(line, "!") {
+			rule.IsNegated = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.IsDirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+
+		// a pattern with a slash in the middle (or at the start) is relative to the '.gitignore',
+		// otherwise it match at any depth below it
+		if strings.Contains(line, "/") {
+			rule.Glob = base + strings.TrimPrefix(line, "/")
+		} else {
+			rule.Glob = base + "**/" + line
+		}
+
+		if ValidateGlob(rule.Glob) != nil {
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// Whether the path (relative to the workspace root) is ignored, the last matching rule win like in git
+func IsIgnoredByGitignore(rules []GitignoreRule, relativePath string, isDir bool) bool {
+	isIgnored := false
+
+	for _, rule := range rules {
+		if rule.IsDirOnly && !isDir {
+			continue
+		}
+
+		if MatchGlob(rule.Glob, relativePath) {
+			isIgnored = !rule.IsNegated
+		}
+	}
+
+	return isIgnored
+}
@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestIsIgnoredByGitignore(t *testing.T) {
+	rules := ParseGitignore([]byte("# build output\n"+"dist/\n"+"/tmp\n"+"*.bak.html\n"+"!keep.bak.html\n"+"docs/generated/*.tmpl\n"), "")
+	rules = append(rules, ParseGitignore([]byte("vendor\n"), "themes")...)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "dist", isDir: true, want: true},
+		{path: "web/dist", isDir: true, want: true},
+		{path: "dist", isDir: false, want: false}, // 'dist/' only match directories
+		{path: "tmp", isDir: true, want: true},
+		{path: "web/tmp", isDir: true, want: false}, // anchored to the root
+		{path: "views/page.bak.html", isDir: false, want: true},
+		{path: "views/keep.bak.html", isDir: false, want: false},
+		{path: "docs/generated/api.tmpl", isDir: false, want: true},
+		{path: "docs/api.tmpl", isDir: false, want: false},
+		{path: "themes/vendor", isDir: true, want: true},
+		{path: "themes/dark/vendor", isDir: true, want: true},
+		{path: "vendor", isDir: true, want: false}, // only below 'themes'
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := IsIgnoredByGitignore(rules, test.path, test.isDir)
+			if got != test.want {
+				t.Errorf("\n Path: %s (dir: %v) \n Expected: %v \n Got: %v", test.path, test.isDir, test.want, got)
+			}
+		})
+	}
+}
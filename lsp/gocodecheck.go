@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Codes of the diagnostics found within the 'go:code' comments, see 'CheckGoCode()'
+const (
+	DiagnosticCodeGoSyntax     = "go-syntax"     // rejected by 'go/parser'
+	DiagnosticCodeGoUndefined  = "go-undefined"  // unknown type or identifier
+	DiagnosticCodeGoRedeclared = "go-redeclared" // same name declared twice within a template
+	DiagnosticCodeGoImport     = "go-import"     // package not found, or imported and not used
+	DiagnosticCodeGoType       = "go-type"       // any other error of 'go/types'
+)
+
+// Error of the Go code written by hand within a 'go:code' comment of the template file
+type GoCodeError struct {
+	Code    string // one of 'DiagnosticCodeGo*'
+	Message string
+	Range   lexer.Range
+	Comment lexer.Range // whole action holding the comment
+}
+
+func (err GoCodeError) IsSyntaxError() bool {
+	return err.Code == DiagnosticCodeGoSyntax
+}
+
+// Errors of the Go code written within the 'go:code' comments of the file, as reported by 'go/parser' and 'go/types'.
+// The comments of a template (root of the file, or a '{{ define }}') are type checked together, along the prelude of the file.
+// The type check of a template is skipped when one of its comments does not parse, the errors would only follow from the syntax.
+// The ranges are found on the original content, like the ranges of the parser.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) CheckGoCode(uri string, content []byte) []GoCodeError {
+	if !bytes.Contains(content, []byte("go:code")) {
+		return nil
+	}
+
+	content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+	lineIndex := NewLineIndex(content, PositionEncodingUTF8)
+
+	// the range cover the word found at 'offset' of the go code, or a single character.
+	// At the end of the go code (eg. unexpected EOF), it cover the last character written instead
+	newGoCodeError := func(code string, message string, comment goCodeComment, offset int) GoCodeError {
+		start := min(max(comment.CodeStart+offset-len(goCodePackageClause), comment.CodeStart), comment.CodeEnd)
+
+		end := start
+		for end < comment.CodeEnd && isGoIdentifierByte(content[end]) {
+			end++
+		}
+
+		if end == start && end < comment.CodeEnd && content[end] != '\n' && content[end] != '\r' {
+			end++
+		}
+
+		if end == start {
+			for start > comment.CodeStart && bytes.ContainsAny(content[start-1:start], " \t\r\n") {
+				start--
+			}
+
+			end = start
+			start = max(start-1, comment.CodeStart)
+		}
+
+		return GoCodeError{
+			Code:    code,
+			Message: message,
+			Range:   lexer.Range{Start: lineIndex.PositionOfOffset(start), End: lineIndex.PositionOfOffset(end)},
+			Comment: lexer.Range{Start: lineIndex.PositionOfOffset(comment.Start), End: lineIndex.PositionOfOffset(comment.End)},
+		}
+	}
+
+	var errs []GoCodeError
+
+	for _, document := range storage.projectGoCode(uri, content) {
+		isSyntaxValid := true
+
+		for index, syntaxErrs := range document.syntaxErrs {
+			for _, syntaxErr := range syntaxErrs {
+				errs = append(errs, newGoCodeError(DiagnosticCodeGoSyntax, syntaxErr.Msg, document.comments[index], syntaxErr.Pos.Offset))
+				isSyntaxValid = false
+			}
+		}
+
+		if !isSyntaxValid {
+			continue
+		}
+
+		for _, typeErr := range document.typeErrs {
+			index := document.indexOfPos(typeErr.Pos)
+			if index < 0 {
+				continue
+			}
+
+			// only the signature of the functions is written, generic ones included.
+			// The continuation of an error (eg. 'other declaration of ...') is not an error of its own
+			if strings.Contains(typeErr.Msg, "missing function body") || strings.HasPrefix(typeErr.Msg, "\t") {
+				continue
+			}
+
+			offset := document.fset.Position(typeErr.Pos).Offset
+			errs = append(errs, newGoCodeError(getGoTypeErrorCode(typeErr.Msg), typeErr.Msg, document.comments[index], offset))
+		}
+	}
+
+	return errs
+}
+
+func getGoTypeErrorCode(message string) string {
+	switch {
+	case strings.HasPrefix(message, "undefined:"):
+		return DiagnosticCodeGoUndefined
+	case strings.Contains(message, "redeclared"):
+		return DiagnosticCodeGoRedeclared
+	case strings.HasPrefix(message, "could not import") || strings.HasSuffix(message, "imported and not used"):
+		return DiagnosticCodeGoImport
+	default:
+		return DiagnosticCodeGoType
+	}
+}
+
+func isGoIdentifierByte(char byte) bool {
+	return char == '_' || char >= 0x80 || ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') || ('0' <= char && char <= '9')
+}
@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestCheckGoCode(t *testing.T) {
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Config:   DefaultProjectConfig([]string{"html"}),
+		Preludes: map[string][]byte{
+			"file:///app/types.gotypes": []byte("type User struct { Name string }"),
+		},
+	}
+
+	at := func(line int, start int, end int) lexer.Range {
+		return lexer.Range{Start: lexer.Position{Line: line, Character: start}, End: lexer.Position{Line: line, Character: end}}
+	}
+
+	tests := []struct {
+		input    string
+		wantCode string
+		want     lexer.Range
+	}{
+		{input: "{{/* go:code type Input struct { Users []User } */}}"},
+		{input: "{{/* go:code func first[T any]([]T) T */}}"},
+		{input: "{{/* go:code type Input struct { Age int } */}}{{ define \"a\" }}{{/* go:code type Input struct{} */}}{{ end }}"},
+		{input: "{{/* not go code */}}{{ .Name }}"},
+		{input: "{{/* go:code type Input struct { Friends []Friend } */}}", wantCode: DiagnosticCodeGoUndefined, want: at(0, 43, 49)},
+		{input: "{{/* go:code type Input struct {} */}}\n{{/* go:code\n\ttype Input int */}}", wantCode: DiagnosticCodeGoRedeclared, want: at(2, 6, 11)},
+		{input: "{{/* go:code type Input struct { Age int */}}", wantCode: DiagnosticCodeGoSyntax, want: at(0, 39, 40)},
+		{input: "{{/* go:code import \"example.com/models\" */}}", wantCode: DiagnosticCodeGoImport, want: at(0, 20, 21)},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			errs := storage.CheckGoCode("file:///app/views/home.html", []byte(test.input))
+
+			if test.wantCode == "" {
+				if len(errs) > 0 {
+					t.Errorf("\n Input: %q \n Expected no error \n Got: %+v", test.input, errs)
+				}
+
+				return
+			}
+
+			if len(errs) != 1 || errs[0].Code != test.wantCode || errs[0].Range != test.want {
+				t.Errorf("\n Input: %q \n Expected: %s %+v \n Got: %+v", test.input, test.wantCode, test.want, errs)
+			}
+		})
+	}
+}
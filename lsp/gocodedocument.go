@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"errors"
+	"go/ast"
+	goparser "go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"slices"
+)
+
+// The go code of a comment is parsed as a file of its own
+const goCodePackageClause = "package gocode;"
+
+// Virtual Go document projected from the 'go:code' comments of a template (root of the file, or a '{{ define }}').
+// Every comment is parsed as a file of its own, and the files are type checked together along the prelude of the template file.
+// The offsets within a file are shifted by the package clause written before the go code (see 'goCodePackageClause')
+type goCodeDocument struct {
+	fset       *token.FileSet
+	comments   []goCodeComment
+	files      []*ast.File         // same index as 'comments', partial when the syntax is invalid
+	syntaxErrs []scanner.ErrorList // same index as 'comments'
+	pkg        *types.Package
+	info       *types.Info
+	typeErrs   []types.Error
+}
+
+// Virtual Go documents of the templates of the file holding 'go:code' comments.
+// 'content' must have its delimiters translated already (see 'TranslateDelimiters()').
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) projectGoCode(uri string, content []byte) []*goCodeDocument {
+	var importer types.Importer = noGoCodeImporter{}
+	if storage.GoSource.Importer != nil {
+		importer = storage.GoSource.Importer
+	}
+
+	fset := token.NewFileSet()
+
+	// the errors of the prelude belong to the prelude file, a broken prelude is left out
+	var preludeFile *ast.File
+	if prelude, _ := storage.GetPreludeOfFile(uri); prelude != "" {
+		file, err := goparser.ParseFile(fset, "", goCodePackageClause+prelude, goparser.SkipObjectResolution)
+		if err == nil {
+			preludeFile = file
+		}
+	}
+
+	var documents []*goCodeDocument
+
+	for _, scope := range findTemplateScopes(content) {
+		if len(scope.Comments) == 0 {
+			continue
+		}
+
+		document := &goCodeDocument{
+			fset:       fset,
+			comments:   scope.Comments,
+			files:      make([]*ast.File, len(scope.Comments)),
+			syntaxErrs: make([]scanner.ErrorList, len(scope.Comments)),
+			info: &types.Info{
+				Defs:      make(map[*ast.Ident]types.Object),
+				Uses:      make(map[*ast.Ident]types.Object),
+				Implicits: make(map[ast.Node]types.Object),
+			},
+		}
+
+		files := make([]*ast.File, 0, len(scope.Comments)+1)
+
+		for index, comment := range scope.Comments {
+			goCode := goCodePackageClause + string(content[comment.CodeStart:comment.CodeEnd])
+
+			// the file is kept even when partial, it is all there is while the go code is being written
+			file, err := goparser.ParseFile(fset, "", goCode, goparser.AllErrors|goparser.SkipObjectResolution)
+			if file == nil {
+				file, _ = goparser.ParseFile(fset, "", goCodePackageClause, goparser.SkipObjectResolution)
+			}
+
+			var syntaxErrs scanner.ErrorList
+			if errors.As(err, &syntaxErrs) {
+				syntaxErrs.RemoveMultiples() // one error per line, the first is the one that matter
+				document.syntaxErrs[index] = syntaxErrs
+			}
+
+			document.files[index] = file
+			files = append(files, file)
+		}
+
+		if preludeFile != nil {
+			files = append(files, preludeFile)
+		}
+
+		config := types.Config{
+			Importer: importer,
+			Error: func(err error) {
+				var typeErr types.Error
+				if errors.As(err, &typeErr) {
+					document.typeErrs = append(document.typeErrs, typeErr)
+				}
+			},
+		}
+
+		document.pkg, _ = config.Check("gocode", fset, files, document.info)
+		documents = append(documents, document)
+	}
+
+	return documents
+}
+
+// Index of the comment (and file) holding the position, -1 for the prelude or an unknown position
+func (document *goCodeDocument) indexOfPos(pos token.Pos) int {
+	tokenFile := document.fset.File(pos)
+	if tokenFile == nil {
+		return -1
+	}
+
+	return slices.IndexFunc(document.files, func(file *ast.File) bool { return document.fset.File(file.Package) == tokenFile })
+}
+
+// Offset within the template file of a position of the go code. Invalid (false) for the prelude
+func (document *goCodeDocument) templateOffset(pos token.Pos) (int, bool) {
+	index := document.indexOfPos(pos)
+	if index < 0 {
+		return 0, false
+	}
+
+	comment := document.comments[index]
+	offset := comment.CodeStart + document.fset.Position(pos).Offset - len(goCodePackageClause)
+
+	return min(max(offset, comment.CodeStart), comment.CodeEnd), true
+}
+
+// Position within the go code of an offset of the template file, along the index of the comment holding it.
+// The end of the go code (right before '*/') belong to the comment. Invalid (-1) out of the go code
+func (document *goCodeDocument) goCodePos(offset int) (int, token.Pos) {
+	index := slices.IndexFunc(document.comments, func(comment goCodeComment) bool {
+		return comment.CodeStart <= offset && offset <= comment.CodeEnd
+	})
+
+	if index < 0 {
+		return -1, token.NoPos
+	}
+
+	tokenFile := document.fset.File(document.files[index].Package)
+	fileOffset := offset - document.comments[index].CodeStart + len(goCodePackageClause)
+
+	if fileOffset > tokenFile.Size() {
+		return -1, token.NoPos
+	}
+
+	return index, tokenFile.Pos(fileOffset)
+}
+
+// Document holding the offset of the template file within its go code, nil when there is none
+func findGoCodeDocument(documents []*goCodeDocument, offset int) *goCodeDocument {
+	for _, document := range documents {
+		if index, _ := document.goCodePos(offset); index >= 0 {
+			return document
+		}
+	}
+
+	return nil
+}
+
+// Object of the identifier found at 'pos' (the end of the identifier included), nil when there is none
+func (document *goCodeDocument) objectAt(fileIndex int, pos token.Pos) (*ast.Ident, types.Object) {
+	var found *ast.Ident
+
+	ast.Inspect(document.files[fileIndex], func(node ast.Node) bool {
+		if node == nil || found != nil || pos < node.Pos() || pos > node.End() {
+			return false
+		}
+
+		if ident, ok := node.(*ast.Ident); ok {
+			found = ident
+		}
+
+		return true
+	})
+
+	if found == nil {
+		return nil, nil
+	}
+
+	if obj := document.info.Defs[found]; obj != nil {
+		return found, obj
+	}
+
+	return found, document.info.Uses[found]
+}
+
+// Packages imported by the file of the comment, key: name used within the go code
+func (document *goCodeDocument) importedPackages(fileIndex int) map[string]*types.Package {
+	packages := make(map[string]*types.Package)
+
+	for _, spec := range document.files[fileIndex].Imports {
+		var obj types.Object
+		if spec.Name != nil {
+			obj = document.info.Defs[spec.Name]
+		} else {
+			obj = document.info.Implicits[spec]
+		}
+
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			packages[pkgName.Name()] = pkgName.Imported()
+		}
+	}
+
+	return packages
+}
+
+// Qualify the objects of the other packages by the package name, like they are written within the go code
+func (document *goCodeDocument) qualifier(pkg *types.Package) string {
+	if pkg == document.pkg {
+		return ""
+	}
+
+	return pkg.Name()
+}
+
+// Importer of the files outside of any workspace (rootless mode), where no package can be resolved
+type noGoCodeImporter struct{}
+
+func (noGoCodeImporter) Import(importPath string) (*types.Package, error) {
+	return nil, errors.New("no go module to resolve the package from")
+}
@@ -0,0 +1,498 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Hover of the Go identifier under the cursor, within the 'go:code' comments of the template file ('content' of 'uri').
+// Empty when the position is not on a Go identifier.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GoCodeHover(uri string, content []byte, position lexer.Position) (string, lexer.Range) {
+	content, lineIndex, offset, ok := storage.getGoCodeOffset(uri, content, position)
+	if !ok {
+		return "", lexer.Range{}
+	}
+
+	document := findGoCodeDocument(storage.projectGoCode(uri, content), offset)
+	if document == nil {
+		return "", lexer.Range{}
+	}
+
+	fileIndex, pos := document.goCodePos(offset)
+
+	ident, obj := document.objectAt(fileIndex, pos)
+	if obj == nil {
+		return "", lexer.Range{}
+	}
+
+	start, _ := document.templateOffset(ident.Pos())
+	end, _ := document.templateOffset(ident.End())
+
+	hover := "```go\n" + types.ObjectString(obj, document.qualifier) + "\n```"
+	reach := lexer.Range{Start: lineIndex.PositionOfOffset(start), End: lineIndex.PositionOfOffset(end)}
+
+	return hover, reach
+}
+
+// Template content with its delimiters translated, and the byte offset of the position,
+// as long as the file hold 'go:code' comments
+func (storage *WorkSpaceStore) getGoCodeOffset(uri string, content []byte, position lexer.Position) ([]byte, *LineIndex, int, bool) {
+	if !bytes.Contains(content, []byte("go:code")) {
+		return nil, nil, 0, false
+	}
+
+	content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+	lineIndex := NewLineIndex(content, PositionEncodingUTF8)
+
+	if position.Line < 0 || position.Line >= len(lineIndex.lineStarts) {
+		return nil, nil, 0, false
+	}
+
+	offset := min(lineIndex.lineStarts[position.Line]+position.Character, len(content))
+
+	return content, lineIndex, offset, true
+}
+
+const (
+	CompletionItemKindFunction      = 3
+	CompletionItemKindField         = 5
+	CompletionItemKindVariable      = 6
+	CompletionItemKindClass         = 7
+	CompletionItemKindInterface     = 8
+	CompletionItemKindModule        = 9
+	CompletionItemKindKeyword       = 14
+	CompletionItemKindConstant      = 21
+	CompletionItemKindStruct        = 22
+	CompletionItemKindTypeParameter = 25
+)
+
+type CompletionItem struct {
+	Label  string `json:"label"`
+	Kind   int    `json:"kind,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// Keywords that can start a declaration or a type, the only Go written within a 'go:code' comment
+var goCodeKeywords = []string{"chan", "func", "import", "interface", "map", "struct", "type"}
+
+// Completion within the 'go:code' comments of the template file: after 'pkg.' the exported symbols of the imported package,
+// otherwise the builtin types, the types declared by the template (and its prelude), the imported packages and the keywords.
+// Nil when the position is not within the go code of a comment.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GoCodeCompletion(uri string, content []byte, position lexer.Position) []CompletionItem {
+	content, _, offset, ok := storage.getGoCodeOffset(uri, content, position)
+	if !ok {
+		return nil
+	}
+
+	document := findGoCodeDocument(storage.projectGoCode(uri, content), offset)
+	if document == nil {
+		return nil
+	}
+
+	fileIndex, _ := document.goCodePos(offset)
+	comment := document.comments[fileIndex]
+
+	start := offset
+	for start > comment.CodeStart && isGoIdentifierByte(content[start-1]) {
+		start--
+	}
+
+	items := []CompletionItem{}
+
+	// member of an imported package
+	if start > comment.CodeStart && content[start-1] == '.' {
+		qualifierStart := start - 1
+		for qualifierStart > comment.CodeStart && isGoIdentifierByte(content[qualifierStart-1]) {
+			qualifierStart--
+		}
+
+		pkg := document.importedPackages(fileIndex)[string(content[qualifierStart:start-1])]
+		if pkg == nil {
+			return items
+		}
+
+		for _, name := range pkg.Scope().Names() {
+			if obj := pkg.Scope().Lookup(name); obj.Exported() {
+				items = append(items, newGoCodeCompletionItem(obj, document.qualifier))
+			}
+		}
+
+		return items
+	}
+
+	for _, name := range types.Universe.Names() {
+		if obj, ok := types.Universe.Lookup(name).(*types.TypeName); ok {
+			items = append(items, newGoCodeCompletionItem(obj, document.qualifier))
+		}
+	}
+
+	if document.pkg != nil {
+		for _, name := range document.pkg.Scope().Names() {
+			if obj, ok := document.pkg.Scope().Lookup(name).(*types.TypeName); ok {
+				items = append(items, newGoCodeCompletionItem(obj, document.qualifier))
+			}
+		}
+	}
+
+	for name, pkg := range document.importedPackages(fileIndex) {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindModule, Detail: "package " + pkg.Path()})
+	}
+
+	for _, keyword := range goCodeKeywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionItemKindKeyword})
+	}
+
+	slices.SortStableFunc(items, func(a CompletionItem, b CompletionItem) int { return strings.Compare(a.Label, b.Label) })
+
+	return items
+}
+
+func newGoCodeCompletionItem(obj types.Object, qualifier types.Qualifier) CompletionItem {
+	item := CompletionItem{Label: obj.Name(), Detail: types.ObjectString(obj, qualifier)}
+
+	switch obj := obj.(type) {
+	case *types.TypeName:
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			item.Kind = CompletionItemKindStruct
+		case *types.Interface:
+			item.Kind = CompletionItemKindInterface
+		default:
+			item.Kind = CompletionItemKindClass
+		}
+
+		if _, ok := obj.Type().(*types.TypeParam); ok {
+			item.Kind = CompletionItemKindTypeParameter
+		}
+	case *types.Func:
+		item.Kind = CompletionItemKindFunction
+	case *types.Const:
+		item.Kind = CompletionItemKindConstant
+	case *types.Var:
+		item.Kind = CompletionItemKindVariable
+		if obj.IsField() {
+			item.Kind = CompletionItemKindField
+		}
+	}
+
+	return item
+}
+
+// The go code is completed on the most recent content of the file, the analysis might lag behind the editor
+func ProcessCompletionRequest(data []byte, storage *WorkSpaceStore, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) []byte {
+	var request RequestMessage[TextDocumentPositionParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/completion' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	muTextFromClient.Lock()
+	content := getMostRecentContent(fileUri, storage, textFromClient)
+	lineIndex := NewLineIndex(content, storage.Client.GetPositionEncoding())
+	items := storage.GoCodeCompletion(fileUri, content, lineIndex.FromLspPosition(request.Params.Position))
+	muTextFromClient.Unlock()
+
+	response := ResponseMessage[*CompletionList]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+		Result:  &CompletionList{IsIncomplete: false, Items: items},
+	}
+
+	// outside of the go code, there is nothing to complete
+	if items == nil {
+		response.Result = nil
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessCompletionRequest(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
+// Legend of the semantic tokens, the index of each type (and modifier bit) is what the client receive
+var SEMANTIC_TOKEN_TYPES = []string{"namespace", "type", "function", "method", "parameter", "property", "variable", "keyword", "string", "number", "comment"}
+var SEMANTIC_TOKEN_MODIFIERS = []string{"declaration", "defaultLibrary"}
+
+const (
+	semanticTokenNamespace = iota
+	semanticTokenType
+	semanticTokenFunction
+	semanticTokenMethod
+	semanticTokenParameter
+	semanticTokenProperty
+	semanticTokenVariable
+	semanticTokenKeyword
+	semanticTokenString
+	semanticTokenNumber
+	semanticTokenComment
+)
+
+const (
+	semanticModifierDeclaration = 1 << iota
+	semanticModifierDefaultLibrary
+)
+
+type semanticToken struct {
+	Start     int // offset within the template file
+	End       int
+	Type      int
+	Modifiers int
+}
+
+// Semantic tokens of the go code written within the 'go:code' comments, encoded relative to each other as the LSP expect.
+// The rest of the template is left to the editor.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GoCodeSemanticTokens(uri string, content []byte, encoding PositionEncodingKind) []uint {
+	if !bytes.Contains(content, []byte("go:code")) {
+		return []uint{}
+	}
+
+	content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+
+	var tokens []semanticToken
+	for _, document := range storage.projectGoCode(uri, content) {
+		tokens = append(tokens, document.semanticTokens(content)...)
+	}
+
+	slices.SortFunc(tokens, func(a semanticToken, b semanticToken) int { return a.Start - b.Start })
+
+	return encodeSemanticTokens(tokens, NewLineIndex(content, encoding))
+}
+
+// Semantic tokens of the go code of every comment, the identifiers are classified by the type check
+func (document *goCodeDocument) semanticTokens(content []byte) []semanticToken {
+	var tokens []semanticToken
+
+	for index, comment := range document.comments {
+		goCode := content[comment.CodeStart:comment.CodeEnd]
+
+		fset := token.NewFileSet()
+		file := fset.AddFile("", fset.Base(), len(goCode))
+
+		var goScanner scanner.Scanner
+		goScanner.Init(file, goCode, nil, scanner.ScanComments)
+
+		for {
+			pos, tok, literal := goScanner.Scan()
+			if tok == token.EOF {
+				break
+			}
+
+			start := file.Offset(pos)
+			end := start + len(literal)
+			tokenType, modifiers := -1, 0
+
+			switch {
+			case tok == token.IDENT:
+				_, goCodePos := document.goCodePos(comment.CodeStart + start)
+				tokenType, modifiers = document.classifyIdentifier(index, goCodePos)
+			case tok.IsKeyword():
+				tokenType, end = semanticTokenKeyword, start+len(tok.String())
+			case tok == token.STRING || tok == token.CHAR:
+				tokenType = semanticTokenString
+			case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+				tokenType = semanticTokenNumber
+			case tok == token.COMMENT:
+				tokenType = semanticTokenComment
+			}
+
+			if tokenType < 0 || start >= end {
+				continue
+			}
+
+			// the literals of raw strings and comments are stripped of their '\r', their end is found on the go code
+			if (tok == token.STRING && goCode[start] == '`') || (tok == token.COMMENT && goCode[start+1] == '*') {
+				closing := "`"
+				if tok == token.COMMENT {
+					closing = "*/"
+				}
+
+				end = len(goCode)
+				if closingIndex := bytes.Index(goCode[start+1:], []byte(closing)); closingIndex >= 0 {
+					end = start + 1 + closingIndex + len(closing)
+				}
+			}
+
+			tokens = append(tokens, semanticToken{Start: comment.CodeStart + start, End: comment.CodeStart + min(end, len(goCode)), Type: tokenType, Modifiers: modifiers})
+		}
+	}
+
+	return tokens
+}
+
+// Token type of the identifier at 'pos', -1 when the type check know nothing about it
+func (document *goCodeDocument) classifyIdentifier(fileIndex int, pos token.Pos) (int, int) {
+	ident, obj := document.objectAt(fileIndex, pos)
+	if obj == nil || ident.Pos() != pos {
+		return -1, 0
+	}
+
+	modifiers := 0
+	if document.info.Defs[ident] != nil {
+		modifiers |= semanticModifierDeclaration
+	}
+
+	if obj.Pkg() == nil {
+		modifiers |= semanticModifierDefaultLibrary
+	}
+
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		return semanticTokenNamespace, modifiers
+	case *types.TypeName:
+		return semanticTokenType, modifiers
+	case *types.Builtin:
+		return semanticTokenFunction, modifiers
+	case *types.Func:
+		if signature, ok := obj.Type().(*types.Signature); ok && signature.Recv() != nil {
+			return semanticTokenMethod, modifiers
+		}
+
+		return semanticTokenFunction, modifiers
+	case *types.Var:
+		if obj.IsField() {
+			return semanticTokenProperty, modifiers
+		}
+
+		// the parameters of the signatures are scoped to their function
+		if document.pkg != nil && obj.Parent() != document.pkg.Scope() {
+			return semanticTokenParameter, modifiers
+		}
+
+		return semanticTokenVariable, modifiers
+	case *types.Const:
+		return semanticTokenVariable, modifiers
+	default:
+		return -1, 0
+	}
+}
+
+// Tokens sorted by offset, split on every line (the client might not support multiline tokens)
+func encodeSemanticTokens(tokens []semanticToken, lineIndex *LineIndex) []uint {
+	data := make([]uint, 0, 5*len(tokens))
+	previous := Position{}
+
+	for _, tok := range tokens {
+		for start := tok.Start; start < tok.End; {
+			end := tok.End
+			if newLine := bytes.IndexByte(lineIndex.content[start:tok.End], '\n'); newLine >= 0 {
+				end = start + newLine
+			}
+
+			startPosition := lineIndex.ToLspPosition(lineIndex.PositionOfOffset(start))
+			endPosition := lineIndex.ToLspPosition(lineIndex.PositionOfOffset(end))
+
+			if endPosition.Character > startPosition.Character {
+				deltaCharacter := startPosition.Character
+				if startPosition.Line == previous.Line {
+					deltaCharacter -= previous.Character
+				}
+
+				data = append(data, startPosition.Line-previous.Line, deltaCharacter, endPosition.Character-startPosition.Character, uint(tok.Type), uint(tok.Modifiers))
+				previous = startPosition
+			}
+
+			start = end + 1
+		}
+	}
+
+	return data
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SemanticTokens struct {
+	Data []uint `json:"data"`
+}
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Full   bool                 `json:"full"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// Only the go code of the 'go:code' comments is completed, where a '.' select the member of a package
+func newCompletionOptions() *CompletionOptions {
+	return &CompletionOptions{TriggerCharacters: []string{"."}}
+}
+
+func newSemanticTokensOptions() *SemanticTokensOptions {
+	return &SemanticTokensOptions{
+		Legend: SemanticTokensLegend{TokenTypes: SEMANTIC_TOKEN_TYPES, TokenModifiers: SEMANTIC_TOKEN_MODIFIERS},
+		Full:   true,
+	}
+}
+
+func ProcessSemanticTokensRequest(data []byte, storage *WorkSpaceStore, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) []byte {
+	var request RequestMessage[SemanticTokensParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/semanticTokens/full' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	muTextFromClient.Lock()
+	tokens := storage.GoCodeSemanticTokens(fileUri, getMostRecentContent(fileUri, storage, textFromClient), storage.Client.GetPositionEncoding())
+	muTextFromClient.Unlock()
+
+	response := ResponseMessage[SemanticTokens]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+		Result:  SemanticTokens{Data: tokens},
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessSemanticTokensRequest(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func newGoCodeTestStorage(t *testing.T) *WorkSpaceStore {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.22\n",
+		"models/user.go": "package models\n\ntype User struct{ Name string }\n\nfunc Format(User) string { return \"\" }\n\ntype hidden int\n",
+	})
+
+	packages := gosource.ParseWorkspacePackages(rootPath, nil)
+	gosource.CheckWorkspacePackages(rootPath, packages)
+
+	return &WorkSpaceStore{
+		RootPath: rootPath,
+		RootUri:  "file://" + filepath.ToSlash(rootPath),
+		Config:   DefaultProjectConfig([]string{"html"}),
+		GoSource: gosource.Discovery{Importer: gosource.NewGoCodeImporter(rootPath, packages)},
+	}
+}
+
+func TestGoCodeHover(t *testing.T) {
+	storage := newGoCodeTestStorage(t)
+	content := "{{/* go:code\n\timport \"example.com/app/models\"\n\ttype Input struct { Author models.User; Tags []Tag }\n\ttype Tag string\n*/}}\n{{ .Author }}"
+
+	tests := []struct {
+		position lexer.Position
+		want     string
+		reach    lexer.Range
+	}{
+		{position: lexer.Position{Line: 2, Character: 8}, want: "```go\ntype Input struct{Author models.User; Tags []Tag}\n```", reach: lexer.Range{Start: lexer.Position{Line: 2, Character: 6}, End: lexer.Position{Line: 2, Character: 11}}},
+		{position: lexer.Position{Line: 2, Character: 36}, want: "```go\ntype models.User struct{Name string}\n```", reach: lexer.Range{Start: lexer.Position{Line: 2, Character: 35}, End: lexer.Position{Line: 2, Character: 39}}},
+		{position: lexer.Position{Line: 2, Character: 30}, want: "```go\npackage models (\"example.com/app/models\")\n```", reach: lexer.Range{Start: lexer.Position{Line: 2, Character: 28}, End: lexer.Position{Line: 2, Character: 34}}},
+		{position: lexer.Position{Line: 3, Character: 10}, want: "```go\ntype string\n```", reach: lexer.Range{Start: lexer.Position{Line: 3, Character: 10}, End: lexer.Position{Line: 3, Character: 16}}},
+		{position: lexer.Position{Line: 5, Character: 5}}, // template content
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got, reach := storage.GoCodeHover("file:///app/home.html", []byte(content), test.position)
+			if got != test.want || reach != test.reach {
+				t.Errorf("\n Position: %+v \n Expected: %q %+v \n Got: %q %+v", test.position, test.want, test.reach, got, reach)
+			}
+		})
+	}
+}
+
+func TestGoCodeCompletion(t *testing.T) {
+	storage := newGoCodeTestStorage(t)
+
+	tests := []struct {
+		input     string
+		position  lexer.Position
+		want      []string // expected among the labels
+		wantNotIn []string
+	}{
+		{
+			input:     "{{/* go:code import \"example.com/app/models\"; type Input struct { Author models. } */}}",
+			position:  lexer.Position{Line: 0, Character: 80},
+			want:      []string{"Format", "User"},
+			wantNotIn: []string{"hidden", "string"},
+		},
+		{
+			input:     "{{/* go:code import \"example.com/app/models\"; type Tag int; type Input struct { Tags []T } */}}",
+			position:  lexer.Position{Line: 0, Character: 88},
+			want:      []string{"Input", "Tag", "int", "string", "any", "models", "struct"},
+			wantNotIn: []string{"User", "len"},
+		},
+		{
+			input:    "{{/* go:code type Input struct{} */}}{{ .Name }}",
+			position: lexer.Position{Line: 0, Character: 42},
+		},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			items := storage.GoCodeCompletion("file:///app/home.html", []byte(test.input), test.position)
+
+			labels := make([]string, 0, len(items))
+			for _, item := range items {
+				labels = append(labels, item.Label)
+			}
+
+			if test.want == nil && items != nil {
+				t.Errorf("\n Input: %q \n Expected no completion \n Got: %q", test.input, labels)
+			}
+
+			for _, label := range test.want {
+				if !slices.Contains(labels, label) {
+					t.Errorf("\n Input: %q \n Expected: %q \n Got: %q", test.input, label, labels)
+				}
+			}
+
+			for _, label := range test.wantNotIn {
+				if slices.Contains(labels, label) {
+					t.Errorf("\n Input: %q \n Not expected: %q \n Got: %q", test.input, label, labels)
+				}
+			}
+		})
+	}
+}
+
+func TestGoCodeSemanticTokens(t *testing.T) {
+	storage := &WorkSpaceStore{Config: DefaultProjectConfig([]string{"html"})}
+
+	tests := []struct {
+		input    string
+		encoding PositionEncodingKind
+		want     []uint
+	}{
+		{input: "{{ .Name }}", want: []uint{}},
+		{
+			input: "{{/* go:code type Input struct { Name string } */}}",
+			want: []uint{
+				0, 13, 4, semanticTokenKeyword, 0,
+				0, 5, 5, semanticTokenType, semanticModifierDeclaration,
+				0, 6, 6, semanticTokenKeyword, 0,
+				0, 9, 4, semanticTokenProperty, semanticModifierDeclaration,
+				0, 5, 6, semanticTokenType, semanticModifierDefaultLibrary,
+			},
+		},
+		// multi-line raw string split per line, positions in utf-16
+		{
+			input:    "{{/* go:code\n\ttype Té struct { A int `x\n\ty` }\n\tfunc f(n int) */}}",
+			encoding: PositionEncodingUTF16,
+			want: []uint{
+				1, 1, 4, semanticTokenKeyword, 0,
+				0, 5, 2, semanticTokenType, semanticModifierDeclaration,
+				0, 3, 6, semanticTokenKeyword, 0,
+				0, 9, 1, semanticTokenProperty, semanticModifierDeclaration,
+				0, 2, 3, semanticTokenType, semanticModifierDefaultLibrary,
+				0, 4, 2, semanticTokenString, 0,
+				1, 0, 3, semanticTokenString, 0,
+				1, 1, 4, semanticTokenKeyword, 0,
+				0, 5, 1, semanticTokenFunction, semanticModifierDeclaration,
+				0, 2, 1, semanticTokenParameter, semanticModifierDeclaration,
+				0, 2, 3, semanticTokenType, semanticModifierDefaultLibrary,
+			},
+		},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := storage.GoCodeSemanticTokens("file:///app/home.html", []byte(test.input), test.encoding)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n Input: %q \n Expected: %v \n Got: %v", test.input, test.want, got)
+			}
+		})
+	}
+}
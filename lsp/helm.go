@@ -0,0 +1,317 @@
+package lsp
+
+import (
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Whether the path (relative to the root) is a template of a Helm chart, ie. a file below a 'templates' directory.
+// eg. 'templates/deployment.yaml', 'charts/redis/templates/_helpers.tpl'
+func isHelmTemplatePath(relativePath string) bool {
+	return getHelmChartDir(relativePath) != ""
+}
+
+// Whether the path is the values of a Helm chart, ie. its 'values.yaml' or its 'values.schema.json' outside of the 'templates'
+func isHelmValuesPath(relativePath string) bool {
+	name := path.Base(relativePath)
+	if name != "values.yaml" && name != "values.schema.json" {
+		return false
+	}
+
+	return !slices.Contains(strings.Split(path.Dir(relativePath), "/"), "templates")
+}
+
+// Directory of the chart of a template, before its last 'templates' directory ('.' for the chart at the root).
+// Empty when the path is not a template of a chart
+func getHelmChartDir(relativePath string) string {
+	segments := strings.Split(relativePath, "/")
+
+	for index := len(segments) - 2; index >= 0; index-- {
+		if segments[index] == "templates" {
+			return path.Join(append([]string{"."}, segments[:index]...)...)
+		}
+	}
+
+	return ""
+}
+
+// Directory of the top-level chart, the subcharts (eg. 'charts/redis') belong to their parent when 'isChartDir' tell it is a chart.
+// Helm render a chart along its subcharts, their templates share the same names.
+// A directory of charts that is not itself a chart is common (eg. 'charts/web' and 'charts/api' of a repository of charts)
+func getHelmTopChartDir(chartDir string, isChartDir func(dir string) bool) string {
+	for {
+		parent, name := path.Split(chartDir)
+		parent = strings.TrimSuffix(parent, "/")
+
+		if name == "" || path.Base(parent) != "charts" || !isChartDir(path.Dir(parent)) {
+			return chartDir
+		}
+
+		chartDir = path.Dir(parent)
+	}
+}
+
+// Go code of the context of the templates of the chart: '.Values' from the nearest values of the chart
+// (the 'values.schema.json' is preferred over the 'values.yaml'), along the built-in objects of Helm (see 'gosource.helmDeclarations').
+// Empty outside of the Helm mode, or for the files outside of any chart.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) getHelmContextCode(uri string) string {
+	chartDir := getHelmChartDir(storage.getRelativePath(uri))
+	if !storage.Config.IsHelmMode() || chartDir == "" {
+		return ""
+	}
+
+	values := make(map[string][]byte) // key: path relative to the root
+	for preludeUri, content := range storage.Preludes {
+		if relativePath := storage.getRelativePath(preludeUri); isHelmValuesPath(relativePath) {
+			values[relativePath] = content
+		}
+	}
+
+	valuesType := helmMapType
+
+	for _, name := range []string{"values.schema.json", "values.yaml"} {
+		content, ok := values[path.Join(chartDir, name)]
+		if !ok {
+			continue
+		}
+
+		if name == "values.schema.json" {
+			valuesType = parseHelmValuesSchema(content)
+		} else {
+			valuesType = parseHelmValuesYaml(content)
+		}
+
+		break
+	}
+
+	return "type Values " + valuesType.String() + "\n" +
+		"type Root struct { Values Values; Release Release; Chart Chart; Capabilities Capabilities; Files Files; Template Template; Subcharts map[string]any }"
+}
+
+var helmTemplateCallPattern = regexp.MustCompile("(?:^|[\\s(])(?:include|template)\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)")
+
+// Templates of the charts, grouped by top-level chart (see 'getHelmTopChartDir()'). Their delimiters are translated.
+// A directory is a chart when it hold templates or values.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) getHelmCharts() map[string]map[string][]byte {
+	chartDirs := make(map[string]bool)
+	for uri := range storage.RawFiles {
+		if chartDir := getHelmChartDir(storage.getRelativePath(uri)); chartDir != "" {
+			chartDirs[chartDir] = true
+		}
+	}
+
+	for preludeUri := range storage.Preludes {
+		if relativePath := storage.getRelativePath(preludeUri); isHelmValuesPath(relativePath) {
+			chartDirs[path.Dir(relativePath)] = true
+		}
+	}
+
+	isChartDir := func(dir string) bool { return chartDirs[dir] }
+
+	charts := make(map[string]map[string][]byte) // key: directory of the top-level chart, then uri
+
+	for uri, content := range storage.RawFiles {
+		chartDir := getHelmChartDir(storage.getRelativePath(uri))
+		if chartDir == "" {
+			continue
+		}
+
+		topChartDir := getHelmTopChartDir(chartDir, isChartDir)
+		if charts[topChartDir] == nil {
+			charts[topChartDir] = make(map[string][]byte)
+		}
+
+		charts[topChartDir][uri] = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+	}
+
+	return charts
+}
+
+// Go type of the context of the '{{ define }}' of the charts, found at their 'include' and 'template' call sites.
+// A type is kept only when every call site agree. The templates called from other templates are found pass after pass,
+// from the type of the caller found by the previous pass.
+// Key: directory of the top-level chart, then name of the template.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GetHelmTemplateInputs() map[string]map[string]string {
+	inputs := make(map[string]map[string]string)
+	if !storage.Config.IsHelmMode() {
+		return inputs
+	}
+
+	for topChartDir, contents := range storage.getHelmCharts() {
+		inputs[topChartDir] = findHelmChartInputs(contents)
+	}
+
+	return inputs
+}
+
+// Inputs of the templates of a single top-level chart, see 'GetHelmTemplateInputs()'
+func findHelmChartInputs(contents map[string][]byte) map[string]string {
+	inputs := make(map[string]string)
+
+	const maxPassCount = 4
+
+	for range maxPassCount {
+		previousInputs := inputs
+		inputs = make(map[string]string)
+		isConflicting := make(map[string]bool)
+
+		scopeType := func(name string) string {
+			if name == "" {
+				return "Root"
+			}
+
+			return previousInputs[name]
+		}
+
+		for _, content := range contents {
+			for _, call := range findContextCalls(content, helmTemplateCallPattern, scopeType) {
+				if isConflicting[call.Name] {
+					continue
+				}
+
+				if previous, ok := inputs[call.Name]; call.InputType == "" || (ok && previous != call.InputType) {
+					isConflicting[call.Name] = true
+					delete(inputs, call.Name)
+					continue
+				}
+
+				inputs[call.Name] = call.InputType
+			}
+		}
+
+		if maps.Equal(inputs, previousInputs) {
+			break
+		}
+	}
+
+	return inputs
+}
+
+// Executions of the templates of a Helm chart: the context of a file is the 'Root' of the chart,
+// the one of its '{{ define }}' is found at their call sites (see 'GetHelmTemplateInputs()').
+// Empty outside of the Helm mode.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) getHelmExecutions(uri string) []gosource.TemplateExecution {
+	relativePath := storage.getRelativePath(uri)
+	if !storage.Config.IsHelmMode() || !isHelmTemplatePath(relativePath) {
+		return nil
+	}
+
+	newExecution := func(name string, inputType string) gosource.TemplateExecution {
+		return gosource.TemplateExecution{TemplateName: name, GoCode: "type Input " + inputType, CallSite: "helm:" + relativePath}
+	}
+
+	executions := []gosource.TemplateExecution{newExecution(path.Base(uri), "Root")}
+
+	var inputs map[string]string
+	for _, contents := range storage.getHelmCharts() {
+		if _, ok := contents[uri]; ok {
+			inputs = findHelmChartInputs(contents)
+			break
+		}
+	}
+
+	content := TranslateDelimiters(storage.RawFiles[uri], storage.Config.GetDelimiters(storage.RootUri, uri))
+	for _, scope := range findTemplateScopes(content)[1:] {
+		if inputType := inputs[scope.Name]; inputType != "" {
+			executions = append(executions, newExecution(scope.Name, inputType))
+		}
+	}
+
+	return executions
+}
+
+// Template sets of the Helm charts: the templates of a chart and of its subcharts are rendered together, each top-level chart is a set.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) DiscoverHelmTemplateSets() []gosource.TemplateSet {
+	var sets []gosource.TemplateSet
+
+	for topChartDir, contents := range storage.getHelmCharts() {
+		set := gosource.TemplateSet{Id: "helm:" + topChartDir}
+		for uri := range contents {
+			set.Files = append(set.Files, storage.getFilePath(uri))
+		}
+
+		slices.Sort(set.Files)
+		sets = append(sets, set)
+	}
+
+	slices.SortFunc(sets, func(a gosource.TemplateSet, b gosource.TemplateSet) int { return strings.Compare(a.Id, b.Id) })
+
+	return sets
+}
+
+// Links of the Helm template for 'go-to-definition', from the name given to 'include' or 'template'
+// to the '{{ define }}' of that name within the chart and its subcharts.
+// Empty outside of the Helm mode.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) FindHelmTemplateLinks(uri string) []TemplateCallLink {
+	if !storage.Config.IsHelmMode() || !isHelmTemplatePath(storage.getRelativePath(uri)) {
+		return nil
+	}
+
+	var contents map[string][]byte
+	for _, chartContents := range storage.getHelmCharts() {
+		if _, ok := chartContents[uri]; ok {
+			contents = chartContents
+			break
+		}
+	}
+
+	encoding := storage.Client.GetPositionEncoding()
+
+	toRange := func(lineIndex *LineIndex, start int, end int) Range {
+		return lineIndex.ToLspRange(lexer.Range{Start: lineIndex.PositionOfOffset(start), End: lineIndex.PositionOfOffset(end)})
+	}
+
+	content := contents[uri]
+	lineIndex := NewLineIndex(content, encoding)
+
+	calls := findContextCalls(content, helmTemplateCallPattern, func(string) string { return "" })
+	if len(calls) == 0 {
+		return nil
+	}
+
+	// templates defined within the chart, along their range
+	type definition struct {
+		uri   string
+		name  string
+		Range Range
+	}
+
+	var definitions []definition
+
+	for targetUri, targetContent := range contents {
+		targetLineIndex := NewLineIndex(targetContent, encoding)
+
+		for _, scope := range findTemplateScopes(targetContent)[1:] {
+			definitions = append(definitions, definition{uri: targetUri, name: scope.Name, Range: toRange(targetLineIndex, scope.Start, scope.BodyStart)})
+		}
+	}
+
+	slices.SortFunc(definitions, func(a definition, b definition) int { return strings.Compare(a.uri, b.uri) })
+
+	var links []TemplateCallLink
+
+	for _, call := range calls {
+		for _, target := range definitions {
+			if target.name != call.Name {
+				continue
+			}
+
+			links = append(links, TemplateCallLink{Uri: uri, Range: toRange(lineIndex, call.NameStart, call.NameEnd), TargetUri: target.uri, TargetRange: target.Range})
+		}
+	}
+
+	return links
+}
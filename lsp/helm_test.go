@@ -0,0 +1,215 @@
+package lsp
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHelmPaths(t *testing.T) {
+	tests := []struct {
+		relativePath string
+		wantChartDir string
+		wantTopDir   string
+		wantIsValues bool
+	}{
+		{relativePath: "templates/deployment.yaml", wantChartDir: ".", wantTopDir: "."},
+		{relativePath: "templates/_helpers.tpl", wantChartDir: ".", wantTopDir: "."},
+		{relativePath: "templates/tests/test-connection.yaml", wantChartDir: ".", wantTopDir: "."},
+		{relativePath: "charts/web/templates/service.yaml", wantChartDir: "charts/web", wantTopDir: "charts/web"},
+		{relativePath: "charts/web/charts/redis/templates/service.yaml", wantChartDir: "charts/web/charts/redis", wantTopDir: "charts/web"},
+		{relativePath: "deploy/app/charts/redis/templates/_helpers.tpl", wantChartDir: "deploy/app/charts/redis", wantTopDir: "deploy/app"},
+		{relativePath: "values.yaml", wantIsValues: true},
+		{relativePath: "charts/web/values.schema.json", wantIsValues: true},
+		{relativePath: "templates/values.yaml", wantChartDir: ".", wantTopDir: "."},
+		{relativePath: "Chart.yaml"},
+		{relativePath: "templates"},
+	}
+
+	// 'charts' at the root is a directory of charts, not the subcharts of a chart
+	isChartDir := func(dir string) bool { return dir == "charts/web" || dir == "deploy/app" }
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			chartDir := getHelmChartDir(test.relativePath)
+			if chartDir != test.wantChartDir {
+				t.Errorf("\n Path: %s \n Expected chart: %q \n Got: %q", test.relativePath, test.wantChartDir, chartDir)
+			}
+
+			if topDir := getHelmTopChartDir(chartDir, isChartDir); chartDir != "" && topDir != test.wantTopDir {
+				t.Errorf("\n Path: %s \n Expected top-level chart: %q \n Got: %q", test.relativePath, test.wantTopDir, topDir)
+			}
+
+			if isValues := isHelmValuesPath(test.relativePath); isValues != test.wantIsValues {
+				t.Errorf("\n Path: %s \n Expected values: %v \n Got: %v", test.relativePath, test.wantIsValues, isValues)
+			}
+		})
+	}
+}
+
+func TestParseHelmValuesYaml(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "", want: "map[string]any"},
+		{content: "# only a comment\n", want: "map[string]any"},
+		{content: "replicaCount: 1\nname: web # the name\nenabled: true\nempty:\n", want: "struct { replicaCount float64; name string; enabled bool; empty any }"},
+		{
+			content: "image:\n  repository: nginx\n  tag: \"1.25\"\n  pullPolicy: IfNotPresent\nservice:\n  port: 80\n",
+			want:    "struct { image struct { repository string; tag string; pullPolicy string }; service struct { port float64 } }",
+		},
+		{content: "podAnnotations: {}\nresources: {}\ntolerations: []\n", want: "struct { podAnnotations map[string]any; resources map[string]any; tolerations []any }"},
+		{content: "labels:\n  app.kubernetes.io/name: web\n", want: "struct { labels map[string]any }"},
+		{content: "ports: [80, 443]\nhosts: [\"a\", 2]\n", want: "struct { ports []float64; hosts []any }"},
+		{
+			content: "ingress:\n  hosts:\n    - host: a.local\n      paths:\n        - path: /\n    - host: b.local\n      tls: yes\n",
+			want:    "struct { ingress struct { hosts []struct { host string; paths []struct { path string }; tls bool } } }",
+		},
+		{content: "args:\n- --verbose\n- --port=80\nnext: 1\n", want: "struct { args []string; next float64 }"},
+		{content: "script: |\n  echo 'a: b'\n  # not a comment\nafter: 1.5\n", want: "struct { script string; after float64 }"},
+		{content: "base: &base\n  size: 1\nother: *base\ntag: !!str 10\n", want: "struct { base struct { size float64 }; other any; tag string }"},
+		{content: "'quoted key': 1\n", want: "map[string]any"},
+		{content: "type: web\n", want: "map[string]any"},
+		{content: "- a\n- b\n", want: "map[string]any"},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := parseHelmValuesYaml([]byte(test.content)).String()
+			if got != test.want {
+				t.Errorf("\n Content: %q \n Expected: %s \n Got: %s", test.content, test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseHelmValuesSchema(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: `{`, want: "map[string]any"},
+		{content: `{"type": "object"}`, want: "map[string]any"},
+		{
+			content: `{"properties": {"replicaCount": {"type": "integer"}, "image": {"type": "object", "properties": {"tag": {"type": ["string", "null"]}}}}}`,
+			want:    "struct { image struct { tag string }; replicaCount float64 }",
+		},
+		{
+			content: `{"type": "object", "properties": {"hosts": {"type": "array", "items": {"type": "string"}}, "extra": {"type": "array"}, "port": {"anyOf": [{"type": "string"}, {"type": "integer"}]}}}`,
+			want:    "struct { extra []any; hosts []string; port any }",
+		},
+		{content: `{"type": "object", "properties": {"enabled": {"type": ["boolean", "string"]}}}`, want: "struct { enabled any }"},
+		{content: `{"type": "array", "items": {"type": "string"}}`, want: "map[string]any"},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := parseHelmValuesSchema([]byte(test.content)).String()
+			if got != test.want {
+				t.Errorf("\n Content: %s \n Expected: %s \n Got: %s", test.content, test.want, got)
+			}
+		})
+	}
+}
+
+func TestGetHelmTemplateInputs(t *testing.T) {
+	storage := newModeTestStorage(ModeHelm, "/chart", map[string]string{
+		"values.yaml":                   "replicaCount: 1\n",
+		"templates/_helpers.tpl":        `{{ define "web.name" }}{{ .Chart.Name }}{{ end }}{{ define "web.labels" }}{{ include "web.name" . }}{{ end }}{{ define "web.port" }}{{ . }}{{ end }}`,
+		"templates/deployment.yaml":     `{{ include "web.labels" . }}{{ include "web.port" .Values.port }}{{ template "web.name" $ }}`,
+		"templates/service.yaml":        `{{ with .Values.service }}{{ include "web.port" 80 }}{{ include "web.name" $ }}{{ end }}`,
+		"charts/redis/templates/a.yaml": `{{ include "redis.name" (dict "a" 1) }}`,
+		"charts/redis/values.yaml":      "port: 6379\n",
+		"Chart.yaml":                    "name: web\n",
+	})
+
+	want := map[string]map[string]string{
+		".": {"web.labels": "Root", "web.name": "Root", "redis.name": "map[string]any"}, // 'web.name' is called by 'web.labels' too
+	}
+
+	got := storage.GetHelmTemplateInputs()
+	if !maps.EqualFunc(got, want, func(a map[string]string, b map[string]string) bool { return maps.Equal(a, b) }) {
+		t.Errorf("\n Expected: %v \n Got: %v", want, got)
+	}
+
+	executions := storage.getHelmExecutions("file:///chart/templates/_helpers.tpl")
+
+	var gotExecutions []string
+	for _, execution := range executions {
+		gotExecutions = append(gotExecutions, execution.TemplateName+": "+execution.GoCode)
+	}
+
+	wantExecutions := []string{"_helpers.tpl: type Input Root", "web.name: type Input Root", "web.labels: type Input Root"}
+	if !slices.Equal(gotExecutions, wantExecutions) {
+		t.Errorf("\n Expected: %q \n Got: %q", wantExecutions, gotExecutions)
+	}
+
+	prelude, preludeUris := storage.GetPreludeOfFile("file:///chart/charts/redis/templates/a.yaml")
+	if len(preludeUris) != 0 || !slices.Contains(strings.Split(prelude, "\n"), "type Values struct { port float64 }") {
+		t.Errorf("\n Expected: the values of the 'redis' chart, without prelude file \n Got: %v \n %s", preludeUris, prelude)
+	}
+
+	storage.Config.Mode = ModeGo
+	if got := storage.GetHelmTemplateInputs(); len(got) != 0 {
+		t.Errorf("\n Expected: no input outside of the Helm mode \n Got: %v", got)
+	}
+}
+
+func TestFindHelmTemplateLinks(t *testing.T) {
+	storage := newModeTestStorage(ModeHelm, "/chart", map[string]string{
+		"templates/_helpers.tpl":            "{{ define \"web.name\" }}web{{ end }}\n{{- define \"web.labels\" -}}\n{{ include \"web.name\" . }}{{ end }}",
+		"templates/deployment.yaml":         "name: {{ include \"web.name\" . }}\nredis: {{ include \"redis.name\" . }}",
+		"charts/redis/templates/_names.tpl": "{{ define \"redis.name\" }}redis{{ end }}",
+		"other/templates/_helpers.tpl":      "{{ define \"web.name\" }}other{{ end }}",
+	})
+
+	type link struct {
+		line        uint
+		start       uint
+		end         uint
+		targetUri   string
+		targetStart Position
+	}
+
+	tests := []struct {
+		uri  string
+		want []link
+	}{
+		{
+			uri: "file:///chart/templates/deployment.yaml",
+			want: []link{
+				{line: 0, start: 17, end: 27, targetUri: "file:///chart/templates/_helpers.tpl"},
+				{line: 1, start: 18, end: 30, targetUri: "file:///chart/charts/redis/templates/_names.tpl"},
+			},
+		},
+		{
+			uri: "file:///chart/templates/_helpers.tpl",
+			want: []link{
+				{line: 2, start: 11, end: 21, targetUri: "file:///chart/templates/_helpers.tpl"},
+			},
+		},
+		{uri: "file:///chart/charts/redis/templates/_names.tpl", want: nil},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			var got []link
+			for _, found := range storage.FindHelmTemplateLinks(test.uri) {
+				got = append(got, link{
+					line:        found.Range.Start.Line,
+					start:       found.Range.Start.Character,
+					end:         found.Range.End.Character,
+					targetUri:   found.TargetUri,
+					targetStart: found.TargetRange.Start,
+				})
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n Uri: %s \n Expected: %v \n Got: %v", test.uri, test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,499 @@
+package lsp
+
+import (
+	"encoding/json"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Go type of a value of a Helm chart, found from its 'values.yaml' or its 'values.schema.json'.
+// Helm decode the values as JSON would, every number is a 'float64'
+type helmValueType struct {
+	goType string           // type of a scalar or of an opaque value (eg. 'string', 'map[string]any'), empty for a struct or a list
+	fields []helmValueField // struct, in the order of the file
+	elem   *helmValueType   // list
+}
+
+type helmValueField struct {
+	name      string
+	valueType *helmValueType
+}
+
+var helmAnyType = &helmValueType{goType: "any"}
+var helmMapType = &helmValueType{goType: "map[string]any"}
+
+func (valueType *helmValueType) String() string {
+	switch {
+	case valueType.goType != "":
+		return valueType.goType
+	case valueType.elem != nil:
+		return "[]" + valueType.elem.String()
+	}
+
+	fields := make([]string, 0, len(valueType.fields))
+	for _, field := range valueType.fields {
+		fields = append(fields, field.name+" "+field.valueType.String())
+	}
+
+	return "struct { " + strings.Join(fields, "; ") + " }"
+}
+
+// Struct of the fields, unless a key cannot be a go field (eg. 'app.kubernetes.io/name', 'type'): the values are then reached
+// with 'index' or '.key' on a map, like Helm do
+func newHelmStructType(fields []helmValueField) *helmValueType {
+	if len(fields) == 0 {
+		return helmMapType
+	}
+
+	for _, field := range fields {
+		if !token.IsIdentifier(field.name) {
+			return helmMapType
+		}
+	}
+
+	return &helmValueType{fields: fields}
+}
+
+// Type holding both values, eg. the items of a list. The fields of the structs are merged, the conflicting types become 'any'
+func mergeHelmValueTypes(a *helmValueType, b *helmValueType) *helmValueType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.goType == "any" || b.goType == "any":
+		return helmAnyType
+	case a.goType != "" || b.goType != "":
+		if a.goType == b.goType {
+			return a
+		}
+
+		return helmAnyType
+	case a.elem != nil || b.elem != nil:
+		if a.elem == nil || b.elem == nil {
+			return helmAnyType
+		}
+
+		return &helmValueType{elem: mergeHelmValueTypes(a.elem, b.elem)}
+	}
+
+	fields := slices.Clone(a.fields)
+	for _, field := range b.fields {
+		index := slices.IndexFunc(fields, func(existing helmValueField) bool { return existing.name == field.name })
+		if index < 0 {
+			fields = append(fields, field)
+			continue
+		}
+
+		fields[index].valueType = mergeHelmValueTypes(fields[index].valueType, field.valueType)
+	}
+
+	return &helmValueType{fields: fields}
+}
+
+// Type of the items of a list, 'any' for an empty list
+func newHelmListType(items []*helmValueType) *helmValueType {
+	var elem *helmValueType
+	for _, item := range items {
+		elem = mergeHelmValueTypes(elem, item)
+	}
+
+	if elem == nil {
+		elem = helmAnyType
+	}
+
+	return &helmValueType{elem: elem}
+}
+
+// Line of a YAML document, stripped of its comment
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+// Go type of the YAML document (eg. 'values.yaml'), 'map[string]any' when it is not a mapping.
+// Only what a 'values.yaml' commonly hold is understood: mappings, lists, flow collections and block scalars.
+// The anchors are ignored and the aliases are of an unknown type
+func parseHelmValuesYaml(content []byte) *helmValueType {
+	lines := splitYamlLines(string(content))
+	if len(lines) == 0 {
+		return helmMapType
+	}
+
+	valueType, _ := parseYamlBlock(lines, 0)
+	if valueType.goType != "" || valueType.elem != nil {
+		return helmMapType
+	}
+
+	return valueType
+}
+
+// Lines holding a value of the document. The comments, the blank lines, the document markers and the lines of the block scalars are left out
+func splitYamlLines(content string) []yamlLine {
+	var lines []yamlLine
+
+	blockScalarIndent := -1 // indent of the line holding the '|' or '>' whose lines are skipped
+
+	for _, text := range strings.Split(content, "\n") {
+		text = strings.TrimRight(text, " \t\r")
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+
+		if blockScalarIndent >= 0 {
+			if strings.TrimSpace(text) == "" || indent > blockScalarIndent {
+				continue
+			}
+
+			blockScalarIndent = -1
+		}
+
+		text = strings.TrimSpace(stripYamlComment(text))
+		if text == "" || text == "---" || text == "..." || strings.HasPrefix(text, "%") {
+			continue
+		}
+
+		if value := getYamlEntryValue(text); strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockScalarIndent = indent
+		}
+
+		lines = append(lines, yamlLine{indent: indent, text: text})
+	}
+
+	return lines
+}
+
+// Line without its comment, a '#' starting a comment only at the start of the line or after a space, and outside of quotes
+func stripYamlComment(text string) string {
+	var quote byte
+
+	for index := 0; index < len(text); index++ {
+		switch char := text[index]; {
+		case quote != 0:
+			if char == '\\' && quote == '"' {
+				index++
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			if index == 0 || strings.ContainsRune(" \t:-[{,", rune(text[index-1])) {
+				quote = char
+			}
+		case char == '#':
+			if index == 0 || text[index-1] == ' ' || text[index-1] == '\t' {
+				return text[:index]
+			}
+		}
+	}
+
+	return text
+}
+
+// Split the entry of a mapping 'key: value' (the value might be empty). Invalid (false) when the line is not an entry
+func splitYamlEntry(text string) (key string, value string, ok bool) {
+	start := 0
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		end := findClosingQuote([]byte(text), 0)
+		if end < 0 {
+			return "", "", false
+		}
+
+		start = end + 1
+	}
+
+	for index := start; index < len(text); index++ {
+		if text[index] != ':' || (index+1 < len(text) && text[index+1] != ' ' && text[index+1] != '\t') {
+			continue
+		}
+
+		key = strings.TrimSpace(text[:index])
+		if unquoted, err := strconv.Unquote(key); err == nil && strings.HasPrefix(key, "\"") {
+			key = unquoted
+		} else if len(key) > 1 && strings.HasPrefix(key, "'") && strings.HasSuffix(key, "'") {
+			key = strings.ReplaceAll(key[1:len(key)-1], "''", "'")
+		}
+
+		return key, strings.TrimSpace(text[index+1:]), true
+	}
+
+	return "", "", false
+}
+
+// Value of the line, be it a mapping entry, a list item or a scalar
+func getYamlEntryValue(text string) string {
+	for isYamlListItem(text) {
+		text = strings.TrimSpace(text[1:])
+	}
+
+	if _, value, ok := splitYamlEntry(text); ok {
+		return value
+	}
+
+	return text
+}
+
+func isYamlListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// Type of the mapping or of the list starting at 'lines[start]', along the index of the first line after it
+func parseYamlBlock(lines []yamlLine, start int) (*helmValueType, int) {
+	indent := lines[start].indent
+
+	if isYamlListItem(lines[start].text) {
+		var items []*helmValueType
+
+		index := start
+		for index < len(lines) && lines[index].indent == indent && isYamlListItem(lines[index].text) {
+			content := strings.TrimSpace(lines[index].text[1:])
+
+			var item *helmValueType
+
+			_, rest := trimYamlProperties(content)
+
+			switch _, _, isEntry := splitYamlEntry(content); {
+			case rest == "":
+				item, index = parseYamlNestedValue(lines, index, indent)
+			case isEntry || isYamlListItem(content):
+				// the item is a block starting on the line of its dash, eg. '- name: web'
+				nested := slices.Clone(lines[index:])
+				nested[0] = yamlLine{indent: indent + len(lines[index].text) - len(content), text: content}
+
+				var end int
+				item, end = parseYamlBlock(nested, 0)
+				index += end
+			default:
+				item = parseYamlScalar(content)
+				index = skipYamlLines(lines, index+1, indent)
+			}
+
+			items = append(items, item)
+		}
+
+		return newHelmListType(items), index
+	}
+
+	var fields []helmValueField
+
+	index := start
+	for index < len(lines) && lines[index].indent == indent && !isYamlListItem(lines[index].text) {
+		key, value, ok := splitYamlEntry(lines[index].text)
+		if !ok {
+			index = skipYamlLines(lines, index+1, indent)
+			continue
+		}
+
+		var valueType *helmValueType
+
+		if _, rest := trimYamlProperties(value); rest == "" {
+			valueType, index = parseYamlNestedValue(lines, index, indent)
+		} else {
+			valueType = parseYamlScalar(value)
+			index = skipYamlLines(lines, index+1, indent)
+		}
+
+		// the last of the duplicated keys win
+		fields = slices.DeleteFunc(fields, func(field helmValueField) bool { return field.name == key })
+		fields = append(fields, helmValueField{name: key, valueType: valueType})
+	}
+
+	return newHelmStructType(fields), index
+}
+
+// Type of the value below the line 'lines[index]' whose own value is empty: a block more indented,
+// a list at the same indent (eg. 'ports:' followed by '- 80'), or else null
+func parseYamlNestedValue(lines []yamlLine, index int, indent int) (*helmValueType, int) {
+	next := index + 1
+
+	switch {
+	case next < len(lines) && lines[next].indent > indent:
+		return parseYamlBlock(lines, next)
+	case next < len(lines) && lines[next].indent == indent && isYamlListItem(lines[next].text) && !isYamlListItem(lines[index].text):
+		return parseYamlBlock(lines, next)
+	}
+
+	return helmAnyType, next
+}
+
+// Index of the first line at or below the indent, from 'index'. The lines more indented continue a multi-line scalar
+func skipYamlLines(lines []yamlLine, index int, indent int) int {
+	for index < len(lines) && lines[index].indent > indent {
+		index++
+	}
+
+	return index
+}
+
+// Type of a scalar or of a flow collection, following the YAML 1.1 resolution used by Helm (eg. 'yes' is a bool)
+func parseYamlScalar(value string) *helmValueType {
+	tag, value := trimYamlProperties(value)
+
+	switch tag {
+	case "!!str", "!!binary", "!!timestamp":
+		return &helmValueType{goType: "string"}
+	case "!!int", "!!float":
+		return &helmValueType{goType: "float64"}
+	case "!!bool":
+		return &helmValueType{goType: "bool"}
+	case "!!map":
+		if value == "" {
+			return helmMapType
+		}
+	case "!!seq":
+		if value == "" {
+			return newHelmListType(nil)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(value, "*"):
+		return helmAnyType
+	case strings.HasPrefix(value, "\"") || strings.HasPrefix(value, "'") || strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+		return &helmValueType{goType: "string"}
+	case strings.HasPrefix(value, "{"):
+		return helmMapType
+	case strings.HasPrefix(value, "["):
+		var items []*helmValueType
+		for _, item := range splitYamlFlowItems(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")) {
+			items = append(items, parseYamlScalar(item))
+		}
+
+		return newHelmListType(items)
+	}
+
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return helmAnyType
+	case "true", "True", "TRUE", "false", "False", "FALSE", "yes", "Yes", "YES", "no", "No", "NO", "on", "On", "ON", "off", "Off", "OFF", "y", "Y", "n", "N":
+		return &helmValueType{goType: "bool"}
+	case ".inf", ".Inf", ".INF", "-.inf", "-.Inf", "-.INF", ".nan", ".NaN", ".NAN":
+		return &helmValueType{goType: "float64"}
+	}
+
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+		return &helmValueType{goType: "float64"}
+	}
+
+	if _, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 0, 64); err == nil {
+		return &helmValueType{goType: "float64"}
+	}
+
+	return &helmValueType{goType: "string"}
+}
+
+// Value without the anchor and the tag coming before it (eg. '&default', '!!str'), along that tag
+func trimYamlProperties(value string) (tag string, rest string) {
+	for strings.HasPrefix(value, "&") || strings.HasPrefix(value, "!") {
+		word, after, _ := strings.Cut(value, " ")
+		if strings.HasPrefix(word, "!") {
+			tag = word
+		}
+
+		value = strings.TrimSpace(after)
+	}
+
+	return tag, value
+}
+
+// Items of a flow list, split at the commas outside of the nested collections and the quotes
+func splitYamlFlowItems(content string) []string {
+	var items []string
+
+	depth := 0
+	var quote byte
+	start := 0
+
+	for index := 0; index < len(content); index++ {
+		switch char := content[index]; {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '[' || char == '{':
+			depth++
+		case char == ']' || char == '}':
+			depth--
+		case char == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(content[start:index]))
+			start = index + 1
+		}
+	}
+
+	if last := strings.TrimSpace(content[start:]); last != "" {
+		items = append(items, last)
+	}
+
+	return items
+}
+
+// Go type of the values described by the JSON schema (eg. 'values.schema.json'), 'map[string]any' when it cannot be decoded
+func parseHelmValuesSchema(content []byte) *helmValueType {
+	var schema map[string]any
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return helmMapType
+	}
+
+	valueType := getHelmSchemaType(schema)
+	if valueType.fields == nil {
+		return helmMapType
+	}
+
+	return valueType
+}
+
+// Type of a value of the schema. The properties of an object are sorted by name,
+// and the values mixing several types (eg. 'anyOf', '"type": ["string", "integer"]') are of the 'any' type
+func getHelmSchemaType(schema map[string]any) *helmValueType {
+	schemaType, _ := schema["type"].(string)
+
+	if types, ok := schema["type"].([]any); ok {
+		for _, item := range types {
+			if name, _ := item.(string); name != "null" && schemaType == "" {
+				schemaType = name
+			} else if name != "null" {
+				return helmAnyType
+			}
+		}
+	}
+
+	if _, ok := schema["properties"]; ok && schemaType == "" {
+		schemaType = "object"
+	}
+
+	switch schemaType {
+	case "string":
+		return &helmValueType{goType: "string"}
+	case "integer", "number":
+		return &helmValueType{goType: "float64"}
+	case "boolean":
+		return &helmValueType{goType: "bool"}
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if items == nil {
+			return newHelmListType(nil)
+		}
+
+		return newHelmListType([]*helmValueType{getHelmSchemaType(items)})
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		var fields []helmValueField
+		for _, name := range names {
+			property, _ := properties[name].(map[string]any)
+			fields = append(fields, helmValueField{name: name, valueType: getHelmSchemaType(property)})
+		}
+
+		return newHelmStructType(fields)
+	}
+
+	return helmAnyType
+}
@@ -0,0 +1,440 @@
+package lsp
+
+import (
+	"bytes"
+	"maps"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Kind of a template of a Hugo project, from its path within the 'layouts' directory (see 'getHugoTemplateKind()')
+const (
+	hugoKindPage      = "page"      // rendered for a page, eg. 'layouts/_default/single.html'
+	hugoKindBaseof    = "baseof"    // base template of the pages, eg. 'layouts/_default/baseof.html'
+	hugoKindPartial   = "partial"   // executed by 'partial', eg. 'layouts/partials/header.html'
+	hugoKindShortcode = "shortcode" // executed from the content files, eg. 'layouts/shortcodes/figure.html'
+	hugoKindHook      = "hook"      // render hook of the markdown, eg. 'layouts/_default/_markup/render-link.html'
+)
+
+// Split the path (relative to the root) of a Hugo template into its 'layouts' directory ('layouts' or 'themes/<name>/layouts')
+// and its path within that directory. Invalid (false) for the files outside of any 'layouts' directory
+func splitHugoLayoutPath(relativePath string) (layoutsDir string, layoutPath string, ok bool) {
+	if layoutPath, ok := strings.CutPrefix(relativePath, "layouts/"); ok {
+		return "layouts", layoutPath, true
+	}
+
+	segments := strings.SplitN(relativePath, "/", 4)
+	if len(segments) == 4 && segments[0] == "themes" && segments[2] == "layouts" {
+		return strings.Join(segments[:3], "/"), segments[3], true
+	}
+
+	return "", "", false
+}
+
+// Kind of the Hugo template, empty for the files outside of any 'layouts' directory
+func getHugoTemplateKind(relativePath string) string {
+	_, layoutPath, ok := splitHugoLayoutPath(relativePath)
+	if !ok {
+		return ""
+	}
+
+	segments := strings.Split(layoutPath, "/")
+	name, _, _ := strings.Cut(segments[len(segments)-1], ".")
+
+	switch {
+	case segments[0] == "partials" || segments[0] == "_partials":
+		return hugoKindPartial
+	case segments[0] == "shortcodes" || segments[0] == "_shortcodes":
+		return hugoKindShortcode
+	case slices.Contains(segments, "_markup") || strings.HasPrefix(name, "render-"):
+		return hugoKindHook
+	case name == "baseof" || strings.HasSuffix(name, "-baseof"):
+		return hugoKindBaseof
+	}
+
+	return hugoKindPage
+}
+
+// Template files of a Hugo project, to follow its lookup rules
+type hugoLayouts struct {
+	uris        map[string]string // key: path relative to the root (eg. 'layouts/_default/single.html')
+	layoutsDirs []string          // 'layouts' first, then the 'layouts' of the themes sorted by name. The project override the themes
+}
+
+func newHugoLayouts(uris map[string]string) hugoLayouts {
+	layouts := hugoLayouts{uris: uris, layoutsDirs: []string{"layouts"}}
+
+	var themeDirs []string
+	for relativePath := range uris {
+		layoutsDir, _, ok := splitHugoLayoutPath(relativePath)
+		if ok && layoutsDir != "layouts" && !slices.Contains(themeDirs, layoutsDir) {
+			themeDirs = append(themeDirs, layoutsDir)
+		}
+	}
+
+	slices.Sort(themeDirs)
+	layouts.layoutsDirs = append(layouts.layoutsDirs, themeDirs...)
+
+	return layouts
+}
+
+// Template files of the workspace, by path relative to the root.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) getHugoLayouts() hugoLayouts {
+	uris := make(map[string]string, len(storage.RawFiles))
+
+	for uri := range storage.RawFiles {
+		if relativePath := storage.getRelativePath(uri); relativePath != "" {
+			uris[relativePath] = uri
+		}
+	}
+
+	return newHugoLayouts(uris)
+}
+
+// Path relative to the root of a file of the workspace, empty for files outside of the root (or in rootless mode)
+func (storage *WorkSpaceStore) getRelativePath(uri string) string {
+	relativePath, ok := strings.CutPrefix(uri, storage.RootUri+"/")
+	if storage.RootUri == "" || !ok {
+		return ""
+	}
+
+	if unescaped, err := url.PathUnescape(relativePath); err == nil {
+		relativePath = unescaped
+	}
+
+	return relativePath
+}
+
+// First of the layouts (within the 'layouts' directories) that exist, the candidates are tried in order.
+// Return its path relative to the root, empty when none exist
+func (layouts hugoLayouts) lookup(candidates []string) string {
+	for _, candidate := range candidates {
+		for _, layoutsDir := range layouts.layoutsDirs {
+			if relativePath := layoutsDir + "/" + candidate; layouts.uris[relativePath] != "" {
+				return relativePath
+			}
+		}
+	}
+
+	return ""
+}
+
+// Base template of the page layout, looked up like Hugo do: 'name-baseof.ext' then 'baseof.ext' of its directory,
+// of the directories above, and at last of '_default'. Empty when the page has no base template
+func (layouts hugoLayouts) findBaseof(relativePath string) string {
+	_, layoutPath, ok := splitHugoLayoutPath(relativePath)
+	if !ok || getHugoTemplateKind(relativePath) != hugoKindPage {
+		return ""
+	}
+
+	name, extension, _ := strings.Cut(path.Base(layoutPath), ".")
+
+	var candidates []string
+	for dir := path.Dir(layoutPath); ; dir = path.Dir(dir) {
+		candidates = append(candidates, path.Join(dir, name+"-baseof."+extension), path.Join(dir, "baseof."+extension))
+
+		if dir == "." {
+			break
+		}
+	}
+
+	candidates = append(candidates, "_default/"+name+"-baseof."+extension, "_default/baseof."+extension)
+
+	return layouts.lookup(candidates)
+}
+
+// Partial template executed by 'partial' under that name, eg. 'header.html' or 'header' for 'layouts/partials/header.html'.
+// Empty when not found
+func (layouts hugoLayouts) findPartial(name string) string {
+	name = strings.TrimPrefix(name, "/")
+
+	candidates := []string{"partials/" + name, "_partials/" + name}
+	if path.Ext(name) == "" {
+		candidates = append(candidates, "partials/"+name+".html", "_partials/"+name+".html")
+	}
+
+	return layouts.lookup(candidates)
+}
+
+// Executions of the Hugo templates, from their kind: the context of the pages and base templates is the 'Page',
+// the one of the shortcodes is the 'Shortcode', and the one of a partial is found at its call sites (see 'GetHugoPartialInputs()').
+// Empty outside of the Hugo mode.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) getHugoExecutions(uri string) []gosource.TemplateExecution {
+	relativePath := storage.getRelativePath(uri)
+	if !storage.Config.IsHugoMode() || relativePath == "" {
+		return nil
+	}
+
+	newExecution := func(name string, inputType string) gosource.TemplateExecution {
+		return gosource.TemplateExecution{TemplateName: name, GoCode: "type Input " + inputType, CallSite: "hugo:" + relativePath}
+	}
+
+	var executions []gosource.TemplateExecution
+
+	switch getHugoTemplateKind(relativePath) {
+	case hugoKindPage, hugoKindBaseof:
+		executions = append(executions, newExecution(path.Base(uri), "Page"))
+
+		content := TranslateDelimiters(storage.RawFiles[uri], storage.Config.GetDelimiters(storage.RootUri, uri))
+		for _, scope := range findTemplateScopes(content)[1:] {
+			executions = append(executions, newExecution(scope.Name, "Page"))
+		}
+	case hugoKindShortcode:
+		executions = append(executions, newExecution(path.Base(uri), "Shortcode"))
+	case hugoKindPartial:
+		if inputType := storage.GetHugoPartialInputs()[uri]; inputType != "" {
+			executions = append(executions, newExecution(path.Base(uri), inputType))
+		}
+	}
+
+	return executions
+}
+
+var hugoPartialCallPattern = regexp.MustCompile("(?:^|[\\s(])(?:partialCached|partials\\.IncludeCached|partials\\.Include|partial)\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)")
+
+// Partial calls of the content (delimiters translated), along the type of their context.
+// '.' is 'rootType' at the root of the file and within its '{{ define }}' (eg. 'Page' for a page layout)
+func findHugoPartialCalls(content []byte, rootType string) []contextCall {
+	return findContextCalls(content, hugoPartialCallPattern, func(string) string { return rootType })
+}
+
+// Type of '.' at the root of the Hugo template, empty when unknown
+func getHugoRootType(kind string) string {
+	switch kind {
+	case hugoKindPage, hugoKindBaseof:
+		return "Page"
+	case hugoKindShortcode:
+		return "Shortcode"
+	}
+
+	return ""
+}
+
+// Go type of the context of the partial templates, found at their call sites. A type is kept only when every call site agree.
+// The partials called by other partials are found pass after pass, from the type of the caller found by the previous pass.
+// Key: uri of the partial.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GetHugoPartialInputs() map[string]string {
+	inputs := make(map[string]string)
+	if !storage.Config.IsHugoMode() {
+		return inputs
+	}
+
+	layouts := storage.getHugoLayouts()
+
+	contents := make(map[string][]byte)
+	for relativePath, uri := range layouts.uris {
+		if getHugoTemplateKind(relativePath) != "" && bytes.Contains(storage.RawFiles[uri], []byte("partial")) {
+			contents[relativePath] = TranslateDelimiters(storage.RawFiles[uri], storage.Config.GetDelimiters(storage.RootUri, uri))
+		}
+	}
+
+	const maxPassCount = 4
+
+	for range maxPassCount {
+		previousInputs := inputs
+		inputs = findHugoPartialInputs(layouts, contents, previousInputs)
+
+		if maps.Equal(inputs, previousInputs) {
+			break
+		}
+	}
+
+	return inputs
+}
+
+// Single pass of 'GetHugoPartialInputs()', the context of the calling partials is the one of 'partialInputs'
+func findHugoPartialInputs(layouts hugoLayouts, contents map[string][]byte, partialInputs map[string]string) map[string]string {
+	inputs := make(map[string]string)
+	isConflicting := make(map[string]bool)
+
+	for relativePath, content := range contents {
+		kind := getHugoTemplateKind(relativePath)
+
+		rootType := getHugoRootType(kind)
+		if kind == hugoKindPartial {
+			rootType = partialInputs[layouts.uris[relativePath]]
+		}
+
+		for _, call := range findHugoPartialCalls(content, rootType) {
+			partialUri := layouts.uris[layouts.findPartial(call.Name)]
+			if partialUri == "" || isConflicting[partialUri] {
+				continue
+			}
+
+			if previous, ok := inputs[partialUri]; call.InputType == "" || (ok && previous != call.InputType) {
+				isConflicting[partialUri] = true
+				delete(inputs, partialUri)
+				continue
+			}
+
+			inputs[partialUri] = call.InputType
+		}
+	}
+
+	return inputs
+}
+
+// Template sets of the Hugo project: a page layout is executed along its base template only, each one is a set of its own.
+// The files out of every set (partials, shortcodes, ...) are analysed together.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) DiscoverHugoTemplateSets() []gosource.TemplateSet {
+	layouts := storage.getHugoLayouts()
+
+	var sets []gosource.TemplateSet
+
+	for relativePath, uri := range layouts.uris {
+		if getHugoTemplateKind(relativePath) != hugoKindPage {
+			continue
+		}
+
+		set := gosource.TemplateSet{Id: "hugo:" + relativePath, Files: []string{storage.getFilePath(uri)}, RootName: path.Base(uri)}
+
+		if baseof := layouts.findBaseof(relativePath); baseof != "" {
+			set.Files = append(set.Files, storage.getFilePath(layouts.uris[baseof]))
+		}
+
+		sets = append(sets, set)
+	}
+
+	slices.SortFunc(sets, func(a gosource.TemplateSet, b gosource.TemplateSet) int { return strings.Compare(a.Id, b.Id) })
+
+	return sets
+}
+
+// Links of the Hugo template for 'go-to-definition', beyond what the analysis of the templates know:
+//   - from the name given to 'partial' to the partial template
+//   - from the name of a '{{ block }}' of a base template to the '{{ define }}' overriding it within the page layouts
+//   - from the name of a '{{ define }}' of a page layout to the '{{ block }}' it override within its base template
+//
+// Empty outside of the Hugo mode.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) FindHugoTemplateLinks(uri string) []TemplateCallLink {
+	relativePath := storage.getRelativePath(uri)
+	kind := getHugoTemplateKind(relativePath)
+	if !storage.Config.IsHugoMode() || kind == "" {
+		return nil
+	}
+
+	layouts := storage.getHugoLayouts()
+	encoding := storage.Client.GetPositionEncoding()
+
+	content := TranslateDelimiters(storage.RawFiles[uri], storage.Config.GetDelimiters(storage.RootUri, uri))
+	lineIndex := NewLineIndex(content, encoding)
+
+	toRange := func(lineIndex *LineIndex, start int, end int) Range {
+		return lineIndex.ToLspRange(lexer.Range{Start: lineIndex.PositionOfOffset(start), End: lineIndex.PositionOfOffset(end)})
+	}
+
+	var links []TemplateCallLink
+
+	for _, call := range findHugoPartialCalls(content, getHugoRootType(kind)) {
+		partialUri := layouts.uris[layouts.findPartial(call.Name)]
+		if partialUri == "" {
+			continue
+		}
+
+		links = append(links, TemplateCallLink{Uri: uri, Range: toRange(lineIndex, call.NameStart, call.NameEnd), TargetUri: partialUri})
+	}
+
+	// templates defined by the scopes of the file, along the range of their name
+	type namedScope struct {
+		keyword   string
+		name      string
+		nameStart int
+		nameEnd   int
+		start     int
+		bodyStart int
+	}
+
+	getNamedScopes := func(content []byte) []namedScope {
+		var scopes []namedScope
+
+		for _, scope := range findTemplateScopes(content)[1:] {
+			action := content[scope.Start:scope.BodyStart]
+
+			nameStart := bytes.IndexAny(action, "\"`")
+			if nameStart < 0 {
+				continue
+			}
+
+			nameEnd := findClosingQuote(action, nameStart)
+			if nameEnd < 0 {
+				continue
+			}
+
+			keyword := "define"
+			if fields := strings.Fields(strings.Trim(string(action[:nameStart]), "{- \t\r\n")); len(fields) > 0 {
+				keyword = fields[0]
+			}
+
+			scopes = append(scopes, namedScope{
+				keyword:   keyword,
+				name:      scope.Name,
+				nameStart: scope.Start + nameStart,
+				nameEnd:   scope.Start + nameEnd + 1,
+				start:     scope.Start,
+				bodyStart: scope.BodyStart,
+			})
+		}
+
+		return scopes
+	}
+
+	// link every scope of the file to the scopes of the same name within the target, whose keyword is 'targetKeyword'
+	linkScopes := func(keyword string, targetUri string, targetKeyword string) {
+		targetContent := TranslateDelimiters(storage.RawFiles[targetUri], storage.Config.GetDelimiters(storage.RootUri, targetUri))
+		targetLineIndex := NewLineIndex(targetContent, encoding)
+		targetScopes := getNamedScopes(targetContent)
+
+		for _, scope := range getNamedScopes(content) {
+			if scope.keyword != keyword {
+				continue
+			}
+
+			for _, target := range targetScopes {
+				if target.keyword != targetKeyword || target.name != scope.name {
+					continue
+				}
+
+				links = append(links, TemplateCallLink{
+					Uri:         uri,
+					Range:       toRange(lineIndex, scope.nameStart, scope.nameEnd),
+					TargetUri:   targetUri,
+					TargetRange: toRange(targetLineIndex, target.start, target.bodyStart),
+				})
+			}
+		}
+	}
+
+	switch kind {
+	case hugoKindPage:
+		if baseof := layouts.findBaseof(relativePath); baseof != "" {
+			linkScopes("define", layouts.uris[baseof], "block")
+		}
+	case hugoKindBaseof:
+		pagePaths := make([]string, 0, len(layouts.uris))
+		for pagePath := range layouts.uris {
+			if layouts.findBaseof(pagePath) == relativePath {
+				pagePaths = append(pagePaths, pagePath)
+			}
+		}
+
+		slices.Sort(pagePaths)
+
+		for _, pagePath := range pagePaths {
+			linkScopes("block", layouts.uris[pagePath], "define")
+		}
+	}
+
+	return links
+}
@@ -0,0 +1,237 @@
+package lsp
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestGetHugoTemplateKind(t *testing.T) {
+	tests := []struct {
+		relativePath string
+		want         string
+	}{
+		{relativePath: "layouts/_default/single.html", want: hugoKindPage},
+		{relativePath: "layouts/index.html", want: hugoKindPage},
+		{relativePath: "layouts/_default/baseof.html", want: hugoKindBaseof},
+		{relativePath: "layouts/posts/list-baseof.html", want: hugoKindBaseof},
+		{relativePath: "layouts/partials/header.html", want: hugoKindPartial},
+		{relativePath: "layouts/_partials/nav/menu.html", want: hugoKindPartial},
+		{relativePath: "layouts/shortcodes/figure.html", want: hugoKindShortcode},
+		{relativePath: "layouts/_default/_markup/render-link.html", want: hugoKindHook},
+		{relativePath: "themes/ananke/layouts/partials/header.html", want: hugoKindPartial},
+		{relativePath: "themes/ananke/layouts/_default/baseof.html", want: hugoKindBaseof},
+		{relativePath: "content/posts/first.html", want: ""},
+		{relativePath: "themes/ananke/static/index.html", want: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := getHugoTemplateKind(test.relativePath)
+			if got != test.want {
+				t.Errorf("\n Path: %s \n Expected: %q \n Got: %q", test.relativePath, test.want, got)
+			}
+		})
+	}
+}
+
+func TestHugoLayoutsLookup(t *testing.T) {
+	uris := make(map[string]string)
+	for _, relativePath := range []string{
+		"layouts/_default/baseof.html",
+		"layouts/_default/single.html",
+		"layouts/_default/list.html",
+		"layouts/posts/baseof.html",
+		"layouts/posts/single.html",
+		"layouts/posts/featured/single.html",
+		"layouts/docs/list-baseof.html",
+		"layouts/docs/list.html",
+		"layouts/docs/single.html",
+		"layouts/partials/header.html",
+		"layouts/partials/nav/menu.html",
+		"themes/ananke/layouts/_default/baseof.rss.xml",
+		"themes/ananke/layouts/_default/list.rss.xml",
+		"themes/ananke/layouts/partials/header.html",
+		"themes/ananke/layouts/partials/footer.html",
+	} {
+		uris[relativePath] = "file:///site/" + relativePath
+	}
+
+	layouts := newHugoLayouts(uris)
+
+	baseofTests := []struct {
+		relativePath string
+		want         string
+	}{
+		{relativePath: "layouts/_default/single.html", want: "layouts/_default/baseof.html"},
+		{relativePath: "layouts/posts/single.html", want: "layouts/posts/baseof.html"},
+		{relativePath: "layouts/posts/featured/single.html", want: "layouts/posts/baseof.html"},
+		{relativePath: "layouts/docs/list.html", want: "layouts/docs/list-baseof.html"},
+		{relativePath: "layouts/docs/single.html", want: "layouts/_default/baseof.html"},
+		{relativePath: "themes/ananke/layouts/_default/list.rss.xml", want: "themes/ananke/layouts/_default/baseof.rss.xml"},
+		{relativePath: "layouts/partials/header.html", want: ""},
+		{relativePath: "layouts/_default/baseof.html", want: ""},
+	}
+
+	for count, test := range baseofTests {
+		t.Run("baseof-"+strconv.Itoa(count), func(t *testing.T) {
+			got := layouts.findBaseof(test.relativePath)
+			if got != test.want {
+				t.Errorf("\n Path: %s \n Expected: %q \n Got: %q", test.relativePath, test.want, got)
+			}
+		})
+	}
+
+	partialTests := []struct {
+		name string
+		want string
+	}{
+		{name: "header.html", want: "layouts/partials/header.html"},
+		{name: "header", want: "layouts/partials/header.html"},
+		{name: "nav/menu", want: "layouts/partials/nav/menu.html"},
+		{name: "footer.html", want: "themes/ananke/layouts/partials/footer.html"},
+		{name: "sidebar.html", want: ""},
+	}
+
+	for count, test := range partialTests {
+		t.Run("partial-"+strconv.Itoa(count), func(t *testing.T) {
+			got := layouts.findPartial(test.name)
+			if got != test.want {
+				t.Errorf("\n Name: %s \n Expected: %q \n Got: %q", test.name, test.want, got)
+			}
+		})
+	}
+}
+
+func TestFindHugoPartialCalls(t *testing.T) {
+	tests := []struct {
+		content   string
+		rootType  string
+		wantTypes []string // type of the context of every call, in order
+	}{
+		{content: `{{ partial "header.html" . }}`, rootType: "Page", wantTypes: []string{"Page"}},
+		{content: `{{ partial "header.html" . }}`, rootType: "", wantTypes: []string{""}},
+		{content: `{{- partialCached "footer" . "key" -}}`, rootType: "Page", wantTypes: []string{"Page"}},
+		{content: `{{ range .Pages }}{{ partial "card" . }}{{ end }}`, rootType: "Page", wantTypes: []string{"Page"}},
+		{content: `{{ range where .Site.RegularPages "Type" "posts" }}{{ partial "card" . }}{{ end }}`, rootType: "Page", wantTypes: []string{"Page"}},
+		{content: `{{ range .Params.tags }}{{ partial "tag" . }}{{ partial "tag" $ }}{{ end }}`, rootType: "Page", wantTypes: []string{"", "Page"}},
+		{content: `{{ with .Params.image }}{{ partial "img" . }}{{ else }}{{ partial "img" . }}{{ end }}`, rootType: "Page", wantTypes: []string{"", "Page"}},
+		{content: `{{ partial "card" (dict "page" . "size" 2) }}`, rootType: "Page", wantTypes: []string{"map[string]any"}},
+		{content: `{{ $x := (partial "card" .Page) }}{{ partials.Include "menu" page }}`, rootType: "Shortcode", wantTypes: []string{"Page", "Page"}},
+		{content: `{{ partial "card" . | safeHTML }}{{ partial "menu" }}`, rootType: "Page", wantTypes: []string{"Page", ""}},
+		{content: `{{/* partial "card" . */}}{{ define "main" }}{{ partial "card" . }}{{ end }}`, rootType: "Page", wantTypes: []string{"Page"}},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			var gotTypes []string
+			for _, call := range findHugoPartialCalls([]byte(test.content), test.rootType) {
+				gotTypes = append(gotTypes, call.InputType)
+
+				if literal := test.content[call.NameStart:call.NameEnd]; literal != strconv.Quote(call.Name) {
+					t.Errorf("\n Content: %s \n Name: %s \n Wrong range of the name: %s", test.content, call.Name, literal)
+				}
+			}
+
+			if !slices.Equal(gotTypes, test.wantTypes) {
+				t.Errorf("\n Content: %s \n Expected: %q \n Got: %q", test.content, test.wantTypes, gotTypes)
+			}
+		})
+	}
+}
+
+func TestGetHugoPartialInputs(t *testing.T) {
+	storage := newModeTestStorage(ModeHugo, "/site", map[string]string{
+		"layouts/_default/single.html":     `{{ partial "header.html" . }}{{ partial "meta" (dict "page" .) }}{{ partial "tags" .Params.tags }}`,
+		"layouts/_default/list.html":       `{{ partial "header.html" .Page }}{{ partial "meta" . }}`,
+		"layouts/shortcodes/note.html":     `{{ partial "nav/menu" .Page }}`,
+		"layouts/partials/header.html":     `{{ partial "nav/menu" . }}`,
+		"layouts/partials/meta.html":       `{{ .page }}`,
+		"layouts/partials/tags.html":       `{{ . }}`,
+		"layouts/partials/nav/menu.html":   `{{ .Title }}`,
+		"layouts/partials/unused.html":     `{{ .Title }}`,
+		"themes/ananke/layouts/index.html": `{{ partial "header" . }}`,
+	})
+
+	want := map[string]string{
+		"file:///site/layouts/partials/header.html":   "Page",
+		"file:///site/layouts/partials/nav/menu.html": "Page", // called by a page and by the 'header' partial
+	}
+
+	got := storage.GetHugoPartialInputs()
+	if !maps.Equal(got, want) {
+		t.Errorf("\n Expected: %v \n Got: %v", want, got)
+	}
+
+	executions := storage.getHugoExecutions("file:///site/layouts/partials/header.html")
+	if len(executions) != 1 || executions[0].TemplateName != "header.html" || executions[0].GoCode != "type Input Page" {
+		t.Errorf("\n Expected: execution of 'header.html' with a 'Page' \n Got: %v", executions)
+	}
+
+	storage.Config.Mode = ModeGo
+	if got := storage.GetHugoPartialInputs(); len(got) != 0 {
+		t.Errorf("\n Expected: no input outside of the Hugo mode \n Got: %v", got)
+	}
+}
+
+func TestFindHugoTemplateLinks(t *testing.T) {
+	storage := newModeTestStorage(ModeHugo, "/site", map[string]string{
+		"layouts/_default/baseof.html": "<html>{{ partial \"head\" . }}\n{{ block \"main\" . }}{{ end }}</html>",
+		"layouts/_default/single.html": "{{ define \"main\" }}\n{{ .Content }}{{ end }}",
+		"layouts/_default/list.html":   "{{ define \"main\" }}{{ end }}{{ define \"aside\" }}{{ end }}",
+		"layouts/partials/head.html":   "<title>{{ .Title }}</title>",
+	})
+
+	type link struct {
+		line      uint
+		start     uint
+		end       uint
+		targetUri string
+		targetEnd Position
+	}
+
+	tests := []struct {
+		uri  string
+		want []link
+	}{
+		{
+			uri: "file:///site/layouts/_default/baseof.html",
+			want: []link{
+				{line: 0, start: 17, end: 23, targetUri: "file:///site/layouts/partials/head.html"},
+				{line: 1, start: 9, end: 15, targetUri: "file:///site/layouts/_default/list.html", targetEnd: Position{Line: 0, Character: 19}},
+				{line: 1, start: 9, end: 15, targetUri: "file:///site/layouts/_default/single.html", targetEnd: Position{Line: 0, Character: 19}},
+			},
+		},
+		{
+			uri: "file:///site/layouts/_default/single.html",
+			want: []link{
+				{line: 0, start: 10, end: 16, targetUri: "file:///site/layouts/_default/baseof.html", targetEnd: Position{Line: 1, Character: 20}},
+			},
+		},
+		{uri: "file:///site/layouts/partials/head.html", want: nil},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			var got []link
+			for _, found := range storage.FindHugoTemplateLinks(test.uri) {
+				if found.Uri != test.uri || found.Range.Start.Line != found.Range.End.Line {
+					t.Errorf("\n Uri: %s \n Unexpected link: %v", test.uri, found)
+				}
+
+				got = append(got, link{
+					line:      found.Range.Start.Line,
+					start:     found.Range.Start.Character,
+					end:       found.Range.End.Character,
+					targetUri: found.TargetUri,
+					targetEnd: found.TargetRange.End,
+				})
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n Uri: %s \n Expected: %v \n Got: %v", test.uri, test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,580 @@
+package lsp
+
+import (
+	"bytes"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota"
+	"github.com/yayolande/gota/lexer"
+	"github.com/yayolande/gota/parser"
+)
+
+// Parse the file as the LSP see it: custom delimiters translated, and what the Go code tell about the file injected
+// (the 'Input' type found at the call sites, and the functions of the 'FuncMap').
+// Neither step move a single character of the original content, so every position (and range) found
+// on the parse tree is also valid on the original file. No range conversion is needed afterward.
+// Must be called while holding 'muTextFromClient', like any access to 'GoSource'
+func (storage *WorkSpaceStore) ParseFile(uri string, content []byte) (*parser.GroupStatementNode, []lexer.Error) {
+	executions, functions := storage.GetGoSourceOfFile(uri)
+
+	return storage.parseFileWithExecutions(uri, content, executions, functions)
+}
+
+// Same as 'ParseFile()', but only the 'Input' type of 'execution' is injected (none when nil).
+// Used to learn what a template require from the data of a single call site.
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) ParseFileWithExecution(uri string, content []byte, execution *gosource.TemplateExecution) (*parser.GroupStatementNode, []lexer.Error) {
+	_, functions := storage.GetGoSourceOfFile(uri)
+
+	var executions []gosource.TemplateExecution
+	if execution != nil {
+		executions = append(executions, *execution)
+	}
+
+	return storage.parseFileWithExecutions(uri, content, executions, functions)
+}
+
+func (storage *WorkSpaceStore) parseFileWithExecutions(uri string, content []byte, executions []gosource.TemplateExecution, functions []gosource.TemplateFunction) (*parser.GroupStatementNode, []lexer.Error) {
+	prelude, _ := storage.GetPreludeOfFile(uri)
+	delimiters := storage.Config.GetDelimiters(storage.RootUri, uri)
+
+	// the actions the parser cannot see are reported along its errors
+	translated, untranslated := translateDelimiters(content, delimiters)
+	delimitersErrs := newDelimitersErrors(content, untranslated, delimiters)
+
+	content = InjectGoCode(translated, path.Base(uri), executions, functions, prelude)
+	content = ExpandGoCodeImports(content, storage.GoSource.Importer)
+
+	parseTree, errs := gota.ParseSingleFile(content)
+
+	return parseTree, append(errs, delimitersErrs...)
+}
+
+// Executions and functions whose template set contain the file, or whose set is unknown.
+// The functions of the libraries enabled by the project come last, the 'FuncMap' of the Go code win over them.
+// Same thing for the executions of the Hugo and Helm modes (see 'getHugoExecutions()' and 'getHelmExecutions()').
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GetGoSourceOfFile(uri string) (executions []gosource.TemplateExecution, functions []gosource.TemplateFunction) {
+	libraryFunctions := storage.Config.GetLibraryFunctions()
+	modeExecutions := append(storage.getHugoExecutions(uri), storage.getHelmExecutions(uri)...)
+
+	filePath := storage.getFilePath(uri)
+	if filePath == "" || (len(storage.GoSource.Executions) == 0 && len(storage.GoSource.Functions) == 0) {
+		return modeExecutions, libraryFunctions
+	}
+
+	isFileInSet := func(setId string) bool {
+		if setId == "" {
+			return true
+		}
+
+		index := slices.IndexFunc(storage.GoSource.Sets, func(set gosource.TemplateSet) bool { return set.Id == setId })
+		return index >= 0 && storage.GoSource.Sets[index].Contains(filePath)
+	}
+
+	for _, execution := range storage.GoSource.Executions {
+		if isFileInSet(execution.SetId) {
+			executions = append(executions, execution)
+		}
+	}
+
+	for _, function := range storage.GoSource.Functions {
+		if isFileInSet(function.SetId) {
+			functions = append(functions, function)
+		}
+	}
+
+	executions = append(executions, modeExecutions...)
+	functions = append(functions, libraryFunctions...)
+
+	return executions, functions
+}
+
+// Os path of a file of the workspace, empty for files outside of the root (or in rootless mode)
+func (storage *WorkSpaceStore) getFilePath(uri string) string {
+	if storage.RootUri == "" || !strings.HasPrefix(uri, storage.RootUri+"/") {
+		return ""
+	}
+
+	relativePath := strings.TrimPrefix(uri, storage.RootUri+"/")
+	if unescaped, err := url.PathUnescape(relativePath); err == nil {
+		relativePath = unescaped
+	}
+
+	return filepath.Join(storage.RootPath, filepath.FromSlash(relativePath))
+}
+
+// File of the template set defining the template, and the range of the '{{ define }}' (or '{{ block }}') opening action.
+// The range is empty, at the start of the file, for the template named after the file.
+// Every template file of the workspace is searched when the set is unknown (empty id).
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) FindTemplateDefinition(setId string, name string) (uri string, reach lexer.Range) {
+	var set *gosource.TemplateSet
+
+	if setId != "" {
+		index := slices.IndexFunc(storage.GoSource.Sets, func(set gosource.TemplateSet) bool { return set.Id == setId })
+		if index < 0 {
+			return "", lexer.Range{}
+		}
+
+		set = &storage.GoSource.Sets[index]
+	}
+
+	uris := make([]string, 0, len(storage.RawFiles))
+	for uri := range storage.RawFiles {
+		if set == nil || set.Contains(storage.getFilePath(uri)) {
+			uris = append(uris, uri)
+		}
+	}
+
+	slices.Sort(uris)
+
+	for _, uri := range uris {
+		content := TranslateDelimiters(storage.RawFiles[uri], storage.Config.GetDelimiters(storage.RootUri, uri))
+		scopes := findTemplateScopes(content)
+
+		index := slices.IndexFunc(scopes[1:], func(scope templateScope) bool { return scope.Name == name })
+		if index < 0 {
+			continue
+		}
+
+		scope := scopes[index+1]
+		lineIndex := NewLineIndex(content, PositionEncodingUTF8)
+
+		return uri, lexer.Range{Start: lineIndex.PositionOfOffset(scope.Start), End: lineIndex.PositionOfOffset(scope.BodyStart)}
+	}
+
+	for _, uri := range uris {
+		if path.Base(uri) == name {
+			return uri, lexer.Range{}
+		}
+	}
+
+	return "", lexer.Range{}
+}
+
+// Root of the file (empty name), or a '{{ define }}'/'{{ block }}' of the file
+type templateScope struct {
+	Name      string
+	Start     int    // offset of the opening action
+	BodyStart int    // offset right after the opening action
+	BodyEnd   int    // offset of the matching '{{ end }}'
+	GoCode    string // content of the 'go:code' comments written within the scope
+	Comments  []goCodeComment
+}
+
+// 'go:code' comment written within a template, the marker open the comment
+type goCodeComment struct {
+	Start     int // offset of the action holding the comment
+	End       int // offset right after the action
+	CodeStart int // offset of the go code, right after the marker
+	CodeEnd   int // offset of the closing '*/'
+}
+
+var goCodeInputDeclaration = regexp.MustCompile(`\btype\s+Input\b`)
+
+func (scope *templateScope) isInputDeclared() bool {
+	return goCodeInputDeclaration.MatchString(scope.GoCode)
+}
+
+func (scope *templateScope) isFunctionDeclared(name string) bool {
+	return regexp.MustCompile(`\bfunc\s+` + regexp.QuoteMeta(name) + `\s*\(`).MatchString(scope.GoCode)
+}
+
+// Lexical scan of the actions of the file, only '{{' and '}}' are recognized (see 'TranslateDelimiters()').
+// This is not a parser, a '}}' within a string literal end the action early, but it is enough to locate the templates
+func findTemplateScopes(content []byte) []templateScope {
+	scopes := []templateScope{{Name: "", BodyStart: 0, BodyEnd: len(content)}}
+	stack := []int{0} // index within 'scopes', -1 for the other blocks ('if', 'range', 'with')
+
+	currentScope := func() int {
+		for index := len(stack) - 1; index >= 0; index-- {
+			if stack[index] >= 0 {
+				return stack[index]
+			}
+		}
+
+		return 0
+	}
+
+	for offset := 0; offset < len(content); {
+		start := bytes.Index(content[offset:], []byte("{{"))
+		if start < 0 {
+			break
+		}
+
+		start += offset
+		bodyStart := start + 2
+
+		inner := bytes.TrimLeft(bytes.TrimPrefix(content[bodyStart:], []byte("-")), " \t\r\n")
+
+		if bytes.HasPrefix(inner, []byte("/*")) {
+			commentStart := len(content) - len(inner)
+
+			commentEnd := bytes.Index(content[commentStart:], []byte("*/"))
+			if commentEnd < 0 {
+				break
+			}
+
+			commentEnd += commentStart
+
+			closing := bytes.Index(content[commentEnd:], []byte("}}"))
+			if closing < 0 {
+				break
+			}
+
+			comment := content[commentStart:commentEnd]
+			if bytes.Contains(comment, []byte("go:code")) {
+				scopes[currentScope()].GoCode += string(comment) + "\n"
+			}
+
+			if code, ok := bytes.CutPrefix(bytes.TrimLeft(comment[2:], " \t\r\n"), []byte("go:code")); ok {
+				scopes[currentScope()].Comments = append(scopes[currentScope()].Comments, goCodeComment{
+					Start:     start,
+					End:       commentEnd + closing + 2,
+					CodeStart: commentEnd - len(code),
+					CodeEnd:   commentEnd,
+				})
+			}
+
+			offset = commentEnd + closing + 2
+			continue
+		}
+
+		closing := bytes.Index(content[bodyStart:], []byte("}}"))
+		if closing < 0 {
+			break
+		}
+
+		closing += bodyStart
+		fields := strings.Fields(strings.Trim(string(content[bodyStart:closing]), "- \t\r\n"))
+
+		keyword := ""
+		if len(fields) > 0 {
+			keyword = fields[0]
+		}
+
+		switch keyword {
+		case "if", "range", "with":
+			stack = append(stack, -1)
+		case "define", "block":
+			name := ""
+			if len(fields) > 1 {
+				name, _ = strconv.Unquote(fields[1])
+			}
+
+			scopes = append(scopes, templateScope{Name: name, Start: start, BodyStart: closing + 2, BodyEnd: len(content)})
+			stack = append(stack, len(scopes)-1)
+		case "end":
+			if len(stack) > 1 {
+				if index := stack[len(stack)-1]; index >= 0 {
+					scopes[index].BodyEnd = start
+				}
+
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		offset = closing + 2
+	}
+
+	return scopes
+}
+
+// Inject the 'Input' type of the executions, the template functions and the prelude, as 'go:code' comments without moving any character of the file:
+//   - for the root of the file (template named after the file), the functions and the prelude, on a new line after the end of the file
+//   - for a '{{ define }}', at the end of the line of the opening action. Skipped when the whole template fit on that line
+//
+// Thus everything injected lie beyond the end of a line of the original content (see 'LineIndex.IsBeyondContent()').
+// What is already declared by a 'go:code' comment of the file is kept as is, and the first execution found win over the others.
+// The prelude is handled like a 'go:code' comment written at the root of the file
+func InjectGoCode(content []byte, fileName string, executions []gosource.TemplateExecution, functions []gosource.TemplateFunction, prelude string) []byte {
+	if len(executions) == 0 && len(functions) == 0 && prelude == "" {
+		return content
+	}
+
+	scopes := findTemplateScopes(content)
+	insertions := make(map[int]string)
+
+	if prelude != "" {
+		scopes[0].GoCode += prelude + "\n"
+		insertions[len(content)] = "\n{{/* go:code " + prelude + " */}}"
+	}
+
+	// functions are declared at the root, visible by every template of the file.
+	// A function named after a Go keyword or a predeclared type (eg. 'default', 'int' of Sprig) is not valid Go,
+	// or shadow the type for the other signatures. It get a comment of its own, so the damage stay within it
+	signatures := make([]string, 0, len(functions))
+	for _, function := range functions {
+		if scopes[0].isFunctionDeclared(function.Name) {
+			continue
+		}
+
+		scopes[0].GoCode += function.GoCode + "\n" // first function of that name win
+
+		if isGoReservedName(function.Name) {
+			insertions[len(content)] += "\n{{/* go:code " + function.GoCode + " */}}"
+			continue
+		}
+
+		signatures = append(signatures, function.GoCode)
+	}
+
+	if len(signatures) > 0 {
+		insertions[len(content)] += "\n{{/* go:code " + strings.Join(signatures, "; ") + " */}}"
+	}
+
+	for _, execution := range executions {
+		index := slices.IndexFunc(scopes, func(scope templateScope) bool {
+			return scope.Name != "" && scope.Name == execution.TemplateName
+		})
+
+		if index < 0 && execution.TemplateName == fileName {
+			index = 0
+		}
+
+		if index < 0 || scopes[index].isInputDeclared() {
+			continue
+		}
+
+		scope := &scopes[index]
+		scope.GoCode += execution.GoCode + "\n" // first execution win
+
+		goCode := "{{/* go:code " + execution.GoCode + " */}}"
+
+		if index == 0 {
+			insertions[len(content)] += "\n" + goCode
+			continue
+		}
+
+		endOfLine := bytes.IndexByte(content[scope.BodyStart:scope.BodyEnd], '\n')
+		if endOfLine < 0 {
+			continue
+		}
+
+		endOfLine += scope.BodyStart
+		if endOfLine > scope.BodyStart && content[endOfLine-1] == '\r' {
+			endOfLine--
+		}
+
+		insertions[endOfLine] = " " + goCode
+	}
+
+	return applyInsertions(content, insertions)
+}
+
+func isGoReservedName(name string) bool {
+	if token.IsKeyword(name) {
+		return true
+	}
+
+	_, isType := types.Universe.Lookup(name).(*types.TypeName)
+	return isType
+}
+
+// Insert the texts at their offset (key) of the original content
+func applyInsertions(content []byte, insertions map[int]string) []byte {
+	if len(insertions) == 0 {
+		return content
+	}
+
+	offsets := make([]int, 0, len(insertions))
+	for offset := range insertions {
+		offsets = append(offsets, offset)
+	}
+
+	slices.Sort(offsets)
+
+	injected := make([]byte, 0, len(content)+len(offsets)*128)
+	previous := 0
+
+	for _, offset := range offsets {
+		injected = append(injected, content[previous:offset]...)
+		injected = append(injected, insertions[offset]...)
+		previous = offset
+	}
+
+	injected = append(injected, content[previous:]...)
+
+	return injected
+}
+
+// Whether the file might import go packages within its 'go:code' comments, see 'ExpandGoCodeImports()'
+func HasGoCodeImports(content []byte) bool {
+	return bytes.Contains(content, []byte("go:code")) && bytes.Contains(content, []byte("import"))
+}
+
+// Resolve the imports of the 'go:code' comments written by hand (see 'GoCodeImporter.ExpandGoCode()'), without moving any character of the file:
+// the comment is rewritten in place with the same length, and the declarations of the imported types are injected
+// beyond the end of the line where the comment end. A comment that cannot be resolved is left as is, and so is
+// a comment followed by another action on its last line (the declarations might land in another template)
+func ExpandGoCodeImports(content []byte, importer *gosource.GoCodeImporter) []byte {
+	const marker = "go:code"
+
+	if importer == nil || !HasGoCodeImports(content) {
+		return content
+	}
+
+	var expanded []byte // copy of the content, once a comment is rewritten
+	insertions := make(map[int]string)
+
+	for offset := 0; offset < len(content); {
+		start := bytes.Index(content[offset:], []byte(marker))
+		if start < 0 {
+			break
+		}
+
+		start += offset + len(marker)
+
+		end := bytes.Index(content[start:], []byte("*/"))
+		if end < 0 {
+			break
+		}
+
+		end += start
+		offset = end
+
+		// the marker must open the comment
+		commentStart := bytes.LastIndex(content[:start], []byte("/*"))
+		if commentStart < 0 || len(bytes.TrimSpace(content[commentStart+2:start-len(marker)])) > 0 {
+			continue
+		}
+
+		goCode := content[start:end]
+		if !bytes.Contains(goCode, []byte("import")) {
+			continue
+		}
+
+		expansion, err := importer.ExpandGoCode(string(goCode))
+		if err != nil {
+			slog.Warn("unable to resolve the imports of go:code, "+err.Error(), slog.String("go_code", string(goCode)))
+			continue
+		}
+
+		closing := bytes.Index(content[end:], []byte("}}"))
+		if closing < 0 {
+			break
+		}
+
+		closing += end + 2
+
+		endOfLine := bytes.IndexByte(content[closing:], '\n')
+		if endOfLine < 0 {
+			endOfLine = len(content)
+		} else {
+			endOfLine += closing
+		}
+
+		if endOfLine > closing && content[endOfLine-1] == '\r' {
+			endOfLine--
+		}
+
+		if bytes.Contains(content[closing:endOfLine], []byte("{{")) {
+			continue
+		}
+
+		if expanded == nil {
+			expanded = bytes.Clone(content)
+		}
+
+		copy(expanded[start:end], expansion.GoCode)
+
+		if expansion.Declarations != "" {
+			insertions[endOfLine] += " {{/* go:code " + expansion.Declarations + " */}}"
+		}
+	}
+
+	if expanded == nil {
+		return content
+	}
+
+	return applyInsertions(expanded, insertions)
+}
+
+// Append the Go doc comment of the template function under the cursor to the hover.
+// The hover is created from the Go signature when the analysis did not provide any
+func appendTemplateFunctionDoc(hover string, reach lexer.Range, functions []gosource.TemplateFunction, lineIndex *LineIndex, position lexer.Position) (string, lexer.Range) {
+	if len(functions) == 0 {
+		return hover, reach
+	}
+
+	name, start, end := findFunctionNameAt(lineIndex, position)
+	if name == "" {
+		return hover, reach
+	}
+
+	index := slices.IndexFunc(functions, func(function gosource.TemplateFunction) bool { return function.Name == name })
+	if index < 0 {
+		return hover, reach
+	}
+
+	function := functions[index]
+
+	if hover == "" {
+		hover = "```go\n" + function.GoCode + "\n```"
+		reach = lexer.Range{
+			Start: lexer.Position{Line: position.Line, Character: start},
+			End:   lexer.Position{Line: position.Line, Character: end},
+		}
+	}
+
+	if function.Doc != "" {
+		hover += "\n\n---\n\n" + function.Doc
+	}
+
+	return hover, reach
+}
+
+// Name of the function under the cursor, along its start and end character on the line.
+// Empty outside of an action, or when the word is a field, a method or a variable
+func findFunctionNameAt(lineIndex *LineIndex, position lexer.Position) (name string, start int, end int) {
+	if lineIndex == nil || position.Line < 0 || position.Line >= len(lineIndex.lineStarts) {
+		return "", 0, 0
+	}
+
+	offset := lineIndex.lineStarts[position.Line] + position.Character
+	content := lineIndex.content
+
+	if offset > len(content) {
+		return "", 0, 0
+	}
+
+	// only within an action, words of the plain text are not functions
+	if bytes.LastIndex(content[:offset], []byte("{{")) <= bytes.LastIndex(content[:offset], []byte("}}")) {
+		return "", 0, 0
+	}
+
+	isIdentifierChar := func(char byte) bool {
+		return char == '_' || ('a' <= char && char <= 'z') || ('A' <= char && char <= 'Z') || ('0' <= char && char <= '9')
+	}
+
+	start = offset
+	for start > 0 && isIdentifierChar(content[start-1]) {
+		start--
+	}
+
+	end = offset
+	for end < len(content) && isIdentifierChar(content[end]) {
+		end++
+	}
+
+	// fields, methods and variables are not functions
+	if start == end || (start > 0 && (content[start-1] == '.' || content[start-1] == '$')) {
+		return "", 0, 0
+	}
+
+	lineStart := lineIndex.lineStarts[position.Line]
+
+	return string(content[start:end]), start - lineStart, end - lineStart
+}
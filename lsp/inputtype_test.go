@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestInjectGoCode(t *testing.T) {
+	execution := func(name string) gosource.TemplateExecution {
+		return gosource.TemplateExecution{TemplateName: name, GoCode: "type Input struct { Name string }"}
+	}
+
+	goCode := "{{/* go:code type Input struct { Name string } */}}"
+
+	tests := []struct {
+		input      string
+		executions []gosource.TemplateExecution
+		want       string
+	}{
+		{input: "{{ .Name }}\n", executions: []gosource.TemplateExecution{execution("home.html")}, want: "{{ .Name }}\n\n" + goCode},
+		{input: "{{ .Name }}", executions: []gosource.TemplateExecution{execution("other.html")}, want: "{{ .Name }}"},
+		{
+			input:      "{{ define \"header\" }}\n\t{{ .Name }}\n{{ end }}\n",
+			executions: []gosource.TemplateExecution{execution("header")},
+			want:       "{{ define \"header\" }} " + goCode + "\n\t{{ .Name }}\n{{ end }}\n",
+		},
+		{
+			input:      "{{ define \"header\" -}}\r\n{{ if .Name }}{{ end }}\r\n{{- end }}",
+			executions: []gosource.TemplateExecution{execution("header")},
+			want:       "{{ define \"header\" -}} " + goCode + "\r\n{{ if .Name }}{{ end }}\r\n{{- end }}",
+		},
+		// single line template, there is no room left without moving the characters
+		{input: "{{ define \"header\" }}{{ .Name }}{{ end }}\n", executions: []gosource.TemplateExecution{execution("header")}, want: "{{ define \"header\" }}{{ .Name }}{{ end }}\n"},
+		{
+			input:      "{{ define \"a\" }}{{ .Name }}{{ end }}\n{{ define \"b\" }}\n{{ .Name }}{{ end }}",
+			executions: []gosource.TemplateExecution{execution("a"), execution("b")},
+			want:       "{{ define \"a\" }}{{ .Name }}{{ end }}\n{{ define \"b\" }} " + goCode + "\n{{ .Name }}{{ end }}",
+		},
+		// 'go:code' written by hand win over the Go call site
+		{
+			input:      "{{/* go:code type Input struct { Age int } */}}\n{{ .Age }}\n",
+			executions: []gosource.TemplateExecution{execution("home.html")},
+			want:       "{{/* go:code type Input struct { Age int } */}}\n{{ .Age }}\n",
+		},
+		{
+			input:      "{{ define \"a\" }}\n\t{{/* go:code type Input int */}}\n{{ end }}\n{{ define \"b\" }}\n{{ end }}",
+			executions: []gosource.TemplateExecution{execution("a"), execution("b"), execution("b")},
+			want:       "{{ define \"a\" }}\n\t{{/* go:code type Input int */}}\n{{ end }}\n{{ define \"b\" }} " + goCode + "\n{{ end }}",
+		},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := InjectGoCode([]byte(test.input), "home.html", test.executions, nil, "")
+			if string(got) != test.want {
+				t.Errorf("\n Input: %q \n Expected: %q \n Got: %q", test.input, test.want, got)
+			}
+
+			// everything injected must lie beyond the content of the original file
+			lineIndex := NewLineIndex([]byte(test.input), PositionEncodingUTF8)
+			injectedIndex := NewLineIndex(got, PositionEncodingUTF8)
+
+			for line := range injectedIndex.LineCount() {
+				character := bytes.Index(injectedIndex.line(line), []byte("{{/* go:code type Input struct { Name"))
+				if character >= 0 && !lineIndex.IsBeyondContent(lexer.Position{Line: line, Character: character}) {
+					t.Errorf("\n injected code at line %d, character %d, is within the original content", line, character)
+				}
+			}
+		})
+	}
+}
+
+// The errors, folding ranges, ... of the injected code are filtered out by their position, see 'LineIndex.IsBeyondContent()'
+func TestInjectedGoCodeIsBeyondContent(t *testing.T) {
+	executions := []gosource.TemplateExecution{
+		{TemplateName: "home.html", GoCode: "type Input struct { Name string }"},
+		{TemplateName: "header", GoCode: "type Input int"},
+		{TemplateName: "footer", GoCode: "type Input int"},
+	}
+
+	functions := []gosource.TemplateFunction{{Name: "upper", GoCode: "func upper(string) string"}}
+
+	inputs := []string{
+		"{{ .Name }}",
+		"{{ .Name }}\n",
+		"{{ define \"header\" }}\n\t{{ . }}\n{{ end }}\n{{ define \"footer\" -}}\r\n{{ . }}\r\n{{- end }}\r\n",
+		"é {{ define \"header\" }}{{ . }}{{ end }}\n\n{{ define \"footer\" }}\n{{ end }}",
+	}
+
+	for count, input := range inputs {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			injected := InjectGoCode([]byte(input), "home.html", executions, functions, "type Shared int")
+
+			lineIndex := NewLineIndex([]byte(input), PositionEncodingUTF8)
+			injectedIndex := NewLineIndex(injected, PositionEncodingUTF8)
+
+			// the original characters are found in order, everything else was injected.
+			// The line breaks are not characters of the line, nor the space put before an injected action
+			original := 0
+
+			for offset := range len(injected) {
+				isInjected := original >= len(input) || injected[offset] != input[original]
+				if !isInjected {
+					original++
+				}
+
+				if injected[offset] == '\n' || injected[offset] == '\r' || (isInjected && injected[offset] == ' ') {
+					continue
+				}
+
+				position := injectedIndex.PositionOfOffset(offset)
+				if lineIndex.IsBeyondContent(position) != isInjected {
+					t.Errorf("\n Input: %q \n Injected: %q \n byte %q at %v, injected %v but beyond the content %v",
+						input, injected, injected[offset], position, isInjected, !isInjected)
+				}
+			}
+
+			if original != len(input) {
+				t.Errorf("\n Input: %q \n Injected: %q \n the original content is not kept", input, injected)
+			}
+		})
+	}
+}
+
+func TestInjectGoCodeFunctions(t *testing.T) {
+	functions := []gosource.TemplateFunction{
+		{Name: "upper", GoCode: "func upper(string) string", Doc: "upper case"},
+		{Name: "add", GoCode: "func add(...int) int"},
+		{Name: "upper", GoCode: "func upper(any) string"},
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "{{ upper .Name }}", want: "{{ upper .Name }}\n{{/* go:code func upper(string) string; func add(...int) int */}}"},
+		// signature written by hand win over the Go code
+		{input: "{{/* go:code func add(int, int) int */}}\n", want: "{{/* go:code func add(int, int) int */}}\n\n{{/* go:code func upper(string) string */}}"},
+	}
+
+	// the prelude come first, the functions it declare are not injected again
+	t.Run(strconv.Itoa(len(tests)), func(t *testing.T) {
+		got := InjectGoCode([]byte("{{ upper .Name }}"), "home.html", nil, functions, "func upper(string) string")
+		want := "{{ upper .Name }}\n{{/* go:code func upper(string) string */}}\n{{/* go:code func add(...int) int */}}"
+		if string(got) != want {
+			t.Errorf("\n Expected: %q \n Got: %q", want, got)
+		}
+	})
+
+	// a function named after a Go keyword or type (eg. Sprig) get a comment of its own
+	t.Run(strconv.Itoa(len(tests)+1), func(t *testing.T) {
+		reserved := []gosource.TemplateFunction{
+			{Name: "default", GoCode: "func default(fallback any, given ...any) any"},
+			{Name: "upper", GoCode: "func upper(s string) string"},
+			{Name: "int", GoCode: "func int(value any) int"},
+		}
+
+		got := InjectGoCode([]byte("{{ default 1 .Count }}"), "home.html", nil, reserved, "")
+		want := "{{ default 1 .Count }}\n{{/* go:code func default(fallback any, given ...any) any */}}\n{{/* go:code func int(value any) int */}}\n{{/* go:code func upper(s string) string */}}"
+		if string(got) != want {
+			t.Errorf("\n Expected: %q \n Got: %q", want, got)
+		}
+	})
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := InjectGoCode([]byte(test.input), "home.html", nil, functions, "")
+			if string(got) != test.want {
+				t.Errorf("\n Input: %q \n Expected: %q \n Got: %q", test.input, test.want, got)
+			}
+		})
+	}
+}
+
+func TestAppendTemplateFunctionDoc(t *testing.T) {
+	functions := []gosource.TemplateFunction{{Name: "upper", GoCode: "func upper(string) string", Doc: "upper case"}}
+	content := []byte("upper {{ upper .upper }}")
+
+	tests := []struct {
+		position lexer.Position
+		hover    string
+		want     string
+	}{
+		{position: lexer.Position{Line: 0, Character: 10}, hover: "", want: "```go\nfunc upper(string) string\n```\n\n---\n\nupper case"},
+		{position: lexer.Position{Line: 0, Character: 10}, hover: "func upper(string) string", want: "func upper(string) string\n\n---\n\nupper case"},
+		{position: lexer.Position{Line: 0, Character: 2}, hover: "", want: ""},  // plain text
+		{position: lexer.Position{Line: 0, Character: 18}, hover: "", want: ""}, // field
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got, _ := appendTemplateFunctionDoc(test.hover, lexer.Range{}, functions, NewLineIndex(content, PositionEncodingUTF8), test.position)
+			if got != test.want {
+				t.Errorf("\n Expected: %q \n Got: %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestExpandGoCodeImports(t *testing.T) {
+	rootPath := writeWorkspace(t, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.22\n",
+		"models/user.go": "package models\n\ntype User struct{ Name string }\n",
+	})
+
+	packages := gosource.ParseWorkspacePackages(rootPath, nil)
+	gosource.CheckWorkspacePackages(rootPath, packages)
+	importer := gosource.NewGoCodeImporter(rootPath, packages)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "{{/* go:code import \"example.com/app/models\"; type Input = models.User */}}\n{{ .Name }}",
+			want:  "{{/* go:code" + strings.Repeat(" ", 32) + ";" + strings.Repeat(" ", 26) + "*/}} {{/* go:code type Input struct { Name string } */}}\n{{ .Name }}",
+		},
+		{
+			input: "{{ define \"a\" }}{{/* go:code\r\n\timport \"example.com/app/models\"\r\n\ttype Input struct { Author models.User }\r\n*/}}\r\n{{ end }}",
+			want:  "{{ define \"a\" }}{{/* go:code\r\n\t                               \r\n\ttype Input struct { Author models_User }\r\n*/}} {{/* go:code type models_User struct { Name string } */}}\r\n{{ end }}",
+		},
+		// the declarations would land after the end of the template
+		{
+			input: "{{ define \"a\" }}{{/* go:code import \"example.com/app/models\"; type Input = models.User */}}{{ end }}",
+			want:  "{{ define \"a\" }}{{/* go:code import \"example.com/app/models\"; type Input = models.User */}}{{ end }}",
+		},
+		{
+			input: "{{/* go:code import \"example.com/missing\"; type Input = missing.User */}}",
+			want:  "{{/* go:code import \"example.com/missing\"; type Input = missing.User */}}",
+		},
+		{input: "{{/* import */}}", want: "{{/* import */}}"},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := ExpandGoCodeImports([]byte(test.input), importer)
+			if string(got) != test.want {
+				t.Errorf("\n Input: %q \n Expected: %q \n Got: %q", test.input, test.want, got)
+			}
+		})
+	}
+}
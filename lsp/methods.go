@@ -3,27 +3,80 @@ package lsp
 import (
 	"encoding/json"
 	"fmt"
+	"go/token"
 	"log/slog"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/yayolande/go-template-lsp/gosource"
+
 	"github.com/yayolande/gota"
 	checker "github.com/yayolande/gota/analyzer"
 	"github.com/yayolande/gota/lexer"
 	"github.com/yayolande/gota/parser"
 )
 
-var filesOpenedByEditor = make(map[string]string)
+// Files whose content is owned by the editor (between 'didOpen' and 'didClose').
+// Every LSP session has its own, the sessions of a daemon never see the documents of each other
+type OpenedDocuments struct {
+	mu       sync.Mutex
+	contents map[string]string // key: uri
+}
+
+func NewOpenedDocuments() *OpenedDocuments {
+	return &OpenedDocuments{contents: make(map[string]string)}
+}
 
 type WorkSpaceStore struct {
 	RootPath          string
+	RootUri           string // same root as 'RootPath', empty in rootless mode
+	Client            *ClientCapabilities
+	OpenedDocuments   *OpenedDocuments // of the session, shared by all its folders
+	Config            *ProjectConfig   // set once when the storage is created, never modified afterward
 	RawFiles          map[string][]byte
 	ParsedFiles       map[string]*parser.GroupStatementNode
 	ErrorsParsedFiles map[string][]lexer.Error
 
 	OpenedFilesAnalyzed map[string]*checker.FileDefinition
 	ErrorsAnalyzedFiles map[string][]lexer.Error
+
+	// Errors of the Go code written within the 'go:code' comments (see 'CheckGoCode()')
+	ErrorsGoCode map[string][]GoCodeError
+
+	// Files (or whole directories) left out by the workspace scan, must be accessed while holding 'muTextFromClient'
+	SkippedFiles []SkippedFile
+
+	// Found in the Go code of the workspace, must be accessed while holding 'muTextFromClient'
+	GoSource             gosource.Discovery
+	UnknownTemplateNames []UnknownTemplateName
+	TemplateCallLinks    []TemplateCallLink
+
+	// Go code shared by the templates (see 'ProjectConfig.Preludes'), key: uri.
+	// In Helm mode, the values of the charts are held here too (see 'isHelmValuesPath()').
+	// Must be accessed while holding 'muTextFromClient'
+	Preludes map[string][]byte
+
+	// Roots of the workspace folders nested within this one, their files belong to those folders and are left out.
+	// Set once when the storage is created, never modified afterward
+	NestedFolderUris []string
+}
+
+// Whether the file belong to a workspace folder nested within this one (see 'NestedFolderUris')
+func (storage *WorkSpaceStore) IsWithinNestedFolder(uri string) bool {
+	for _, nestedUri := range storage.NestedFolderUris {
+		if strings.HasPrefix(uri, nestedUri+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+type SkippedFile struct {
+	Uri    string `json:"uri"`
+	Reason string `json:"reason"`
 }
 
 type ID int
@@ -74,24 +127,47 @@ type NotificationMessage[T any] struct {
 }
 
 type InitializeParams struct {
-	ProcessId    int            `json:"processId"`
-	Capabilities map[string]any `json:"capabilities"`
+	ProcessId    int                `json:"processId"`
+	Capabilities ClientCapabilities `json:"capabilities"`
 	ClientInfo   struct {
 		Name    string `json:"name"`
 		Version string `json:"version"`
 	} `json:"clientInfo"`
-	Locale                string `json:"locale"`
-	RootUri               string `json:"rootUri"`
-	Trace                 any    `json:"trace"`
-	WorkspaceFolders      any    `json:"workspaceFolders"`
-	InitializationOptions any    `json:"initializationOptions"`
+	Locale                string            `json:"locale"`
+	RootUri               string            `json:"rootUri"`
+	Trace                 any               `json:"trace"`
+	WorkspaceFolders      []WorkspaceFolder `json:"workspaceFolders"`
+	InitializationOptions json.RawMessage   `json:"initializationOptions"`
+}
+
+type WorkspaceFolder struct {
+	Uri  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type ServerWorkspaceCapabilities struct {
+	WorkspaceFolders struct {
+		Supported           bool `json:"supported"`
+		ChangeNotifications bool `json:"changeNotifications"`
+	} `json:"workspaceFolders"`
+}
+
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
 }
 
 type ServerCapabilities struct {
-	TextDocumentSync     int  `json:"textDocumentSync"`
-	HoverProvider        bool `json:"hoverProvider"`
-	DefinitionProvider   bool `json:"definitionProvider"`
-	FoldingRangeProvider bool `json:"foldingRangeProvider"`
+	PositionEncoding       PositionEncodingKind        `json:"positionEncoding,omitempty"`
+	TextDocumentSync       int                         `json:"textDocumentSync"`
+	HoverProvider          bool                        `json:"hoverProvider,omitempty"`
+	DefinitionProvider     bool                        `json:"definitionProvider,omitempty"`
+	FoldingRangeProvider   bool                        `json:"foldingRangeProvider,omitempty"`
+	CodeActionProvider     bool                        `json:"codeActionProvider,omitempty"`
+	CompletionProvider     *CompletionOptions          `json:"completionProvider,omitempty"`
+	SemanticTokensProvider *SemanticTokensOptions      `json:"semanticTokensProvider,omitempty"`
+	SignatureHelpProvider  *SignatureHelpOptions       `json:"signatureHelpProvider,omitempty"`
+	ExecuteCommandProvider *ExecuteCommandOptions      `json:"executeCommandProvider,omitempty"`
+	Workspace              ServerWorkspaceCapabilities `json:"workspace"`
 }
 
 type InitializeResult struct {
@@ -109,27 +185,30 @@ type PublishDiagnosticsParams struct {
 }
 
 type Diagnostic struct {
-	Range    Range  `json:"range"`
-	Message  string `json:"message"`
-	Severity int    `json:"severity"`
+	Range              Range                          `json:"range"`
+	Message            string                         `json:"message"`
+	Severity           int                            `json:"severity"`
+	Code               string                         `json:"code,omitempty"`
+	RelatedInformation []DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+}
+
+type DiagnosticRelatedInformation struct {
+	Location Location `json:"location"`
+	Message  string   `json:"message"`
 }
 
-func convertParserRangeToLspRange(parserRange lexer.Range) Range {
+// 'lineIndex' must be built from the file the range belong to
+func convertParserRangeToLspRange(parserRange lexer.Range, lineIndex *LineIndex) Range {
 	if parserRange.IsEmpty() {
 		return Range{}
 	}
 
-	reach := Range{}
-	reach.Start.Line = uint(parserRange.Start.Line)
-	reach.Start.Character = uint(parserRange.Start.Character)
-
-	reach.End.Line = uint(parserRange.End.Line)
-	reach.End.Character = uint(parserRange.End.Character)
-
-	return reach
+	return lineIndex.ToLspRange(parserRange)
 }
 
-func ProcessInitializeRequest(data []byte, lspName string, lspVersion string) (response []byte, root string) {
+// Return the workspace folders to analyse, the 'rootUri' is used as the only folder
+// when the client do not support multi-root workspaces
+func ProcessInitializeRequest(data []byte, lspName string, lspVersion string) (response []byte, folders []WorkspaceFolder, client *ClientCapabilities) {
 	req := RequestMessage[InitializeParams]{}
 
 	err := json.Unmarshal(data, &req)
@@ -144,19 +223,43 @@ func ProcessInitializeRequest(data []byte, lspName string, lspVersion string) (r
 		panic(msg)
 	}
 
+	client = &req.Params.Capabilities
+	client.PositionEncoding = NegotiatePositionEncoding(client.General.PositionEncodings)
+
+	// features registered dynamically are not advertised here, see 'ProcessInitializedNotificatoin()'
 	res := ResponseMessage[InitializeResult]{
 		JsonRpc: "2.0",
 		Id:      req.Id,
 		Result: InitializeResult{
 			Capabilities: ServerCapabilities{
+				PositionEncoding:     client.PositionEncoding,
 				TextDocumentSync:     1,
-				HoverProvider:        true,
-				DefinitionProvider:   true,
-				FoldingRangeProvider: true,
+				HoverProvider:        !client.TextDocument.Hover.DynamicRegistration,
+				DefinitionProvider:   !client.TextDocument.Definition.DynamicRegistration,
+				FoldingRangeProvider: !client.TextDocument.FoldingRange.DynamicRegistration,
+				CodeActionProvider:   true, // only for the go files, thus never registered for the template files
+				ExecuteCommandProvider: &ExecuteCommandOptions{
+					Commands: []string{STATUS_COMMAND},
+				},
 			},
 		},
 	}
 
+	if !client.TextDocument.Completion.DynamicRegistration {
+		res.Result.Capabilities.CompletionProvider = newCompletionOptions()
+	}
+
+	if !client.TextDocument.SemanticTokens.DynamicRegistration {
+		res.Result.Capabilities.SemanticTokensProvider = newSemanticTokensOptions()
+	}
+
+	if !client.TextDocument.SignatureHelp.DynamicRegistration {
+		res.Result.Capabilities.SignatureHelpProvider = newSignatureHelpOptions()
+	}
+
+	res.Result.Capabilities.Workspace.WorkspaceFolders.Supported = true
+	res.Result.Capabilities.Workspace.WorkspaceFolders.ChangeNotifications = true
+
 	res.Result.ServerInfo.Name = lspName
 	res.Result.ServerInfo.Version = lspVersion
 
@@ -167,25 +270,88 @@ func ProcessInitializeRequest(data []byte, lspName string, lspVersion string) (r
 		panic(msg)
 	}
 
-	root, err = url.PathUnescape(req.Params.RootUri) // needed for windows os
+	folders = req.Params.WorkspaceFolders
+	if len(folders) == 0 && req.Params.RootUri != "" {
+		folders = []WorkspaceFolder{{Uri: req.Params.RootUri, Name: "root"}}
+	}
+
+	folders = unescapeWorkspaceFolders(folders)
+
+	return response, folders, client
+}
+
+// Malformated folders are dropped
+func unescapeWorkspaceFolders(folders []WorkspaceFolder) []WorkspaceFolder {
+	validFolders := make([]WorkspaceFolder, 0, len(folders))
+
+	for _, folder := range folders {
+		uri, err := url.PathUnescape(folder.Uri) // needed for windows os
+		if err != nil {
+			slog.Error("workspace folder uri received from client is malformated. "+err.Error(),
+				slog.String("folder_uri", folder.Uri),
+			)
+			continue
+		}
+
+		folder.Uri = uri
+		validFolders = append(validFolders, folder)
+	}
+
+	return validFolders
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	Event struct {
+		Added   []WorkspaceFolder `json:"added"`
+		Removed []WorkspaceFolder `json:"removed"`
+	} `json:"event"`
+}
+
+func ProcessDidChangeWorkspaceFoldersNotification(data []byte) (added []WorkspaceFolder, removed []WorkspaceFolder) {
+	var request RequestMessage[DidChangeWorkspaceFoldersParams]
+
+	err := json.Unmarshal(data, &request)
 	if err != nil {
-		slog.Error("root uri received from client is malformated. "+err.Error(),
-			slog.Group("details",
-				slog.String("root_uri", req.Params.RootUri),
-				slog.Any("request_info", req),
-			),
+		slog.Warn("error while unmarshalling data during 'workspace/didChangeWorkspaceFolders' phase, "+err.Error(),
+			slog.String("received_req", string(data)),
 		)
-		root = "file://root_uri_malformated_error"
+		return nil, nil
 	}
 
-	return response, root
+	added = unescapeWorkspaceFolders(request.Params.Event.Added)
+	removed = unescapeWorkspaceFolders(request.Params.Event.Removed)
+
+	return added, removed
 }
 
-func ProcessInitializedNotificatoin(data []byte) {
-	// This is intentionally left empty since the LSP documentation do not describe anything
-	// The only reason for this notification (for now) is to register new server capabilities
+// Extract the (unescaped) uri of the text document targeted by a request.
+// Return an empty string if the request do not target any text document
+func GetTextDocumentUri(data []byte) string {
+	var request RequestMessage[struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		return ""
+	}
+
+	uri, err := url.PathUnescape(request.Params.TextDocument.Uri) // needed for windows os
+	if err != nil {
+		return request.Params.TextDocument.Uri
+	}
+
+	return uri
+}
+
+// Return the params of the 'client/registerCapability' request to send to the client, or nil if there is none
+func ProcessInitializedNotificatoin(data []byte, client *ClientCapabilities, fileExtensions []string) *RegistrationParams {
+	// The LSP documentation do not describe anything for this notification
+	// The only reason for it (for now) is to register new server capabilities
 	// [Read more](https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/#initialized)
 	slog.Info("Succesfully received 'initialized' notification", slog.String("data", string(data)))
+
+	return createRegistrationParams(client, fileExtensions)
 }
 
 func ProcessShutdownRequest(jsonVersion string, requestId ID) []byte {
@@ -206,6 +372,76 @@ func ProcessShutdownRequest(jsonVersion string, requestId ID) []byte {
 	return responseText
 }
 
+// Null result, for requests the server is unable to answer (eg. file outside of the workspace)
+func ProcessRequestWithoutResult(jsonVersion string, requestId ID) []byte {
+	response := ResponseMessage[any]{
+		JsonRpc: jsonVersion,
+		Id:      requestId,
+		Result:  nil,
+		Error:   nil,
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessRequestWithoutResult(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
+// Command returning the state of every workspace folder, mostly to find out why a file is not analysed
+const STATUS_COMMAND = "go-template-lsp.status"
+
+type WorkspaceStatus struct {
+	Name         string        `json:"name"`
+	Uri          string        `json:"uri"`
+	IsSingleFile bool          `json:"isSingleFile"`
+	FileCount    int           `json:"fileCount"`
+	SkippedFiles []SkippedFile `json:"skippedFiles"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+// Only the status command is supported, any other command get an error response
+func ProcessExecuteCommandRequest(data []byte, statuses []WorkspaceStatus) []byte {
+	var request RequestMessage[ExecuteCommandParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'workspace/executeCommand' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	response := ResponseMessage[any]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+	}
+
+	if request.Params.Command == STATUS_COMMAND {
+		response.Result = statuses
+	} else {
+		response.Error = &ResponseError{
+			Code:    -32602,
+			Message: "unknown command '" + request.Params.Command + "'",
+		}
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessExecuteCommandRequest(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
 func ProcessIllegalRequestAfterShutdown(jsonVersion string, requestId ID) []byte {
 	response := ResponseMessage[any]{
 		JsonRpc: jsonVersion,
@@ -227,6 +463,32 @@ func ProcessIllegalRequestAfterShutdown(jsonVersion string, requestId ID) []byte
 	return responseText
 }
 
+// Response to a message that could not be read (malformed header or invalid json).
+// Its 'id' is unknown, thus 'null' as required by the JSON-RPC specification
+func ProcessParseError(reason string) []byte {
+	response := struct {
+		JsonRpc string         `json:"jsonrpc"`
+		Id      *ID            `json:"id"`
+		Error   *ResponseError `json:"error"`
+	}{
+		JsonRpc: "2.0",
+		Id:      nil,
+		Error: &ResponseError{
+			Code:    -32700,
+			Message: "parse error, " + reason,
+		},
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessParseError(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
 type TextDocumentItem struct {
 	Uri        string `json:"uri"`
 	Version    int    `json:"version"`
@@ -238,7 +500,7 @@ type DidOpenTextDocumentParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 }
 
-func ProcessDidOpenTextDocumentNotification(data []byte) (fileURI string, fileContent []byte) {
+func ProcessDidOpenTextDocumentNotification(data []byte, openedDocuments *OpenedDocuments) (fileURI string, fileContent []byte) {
 	request := RequestMessage[DidOpenTextDocumentParams]{}
 
 	err := json.Unmarshal(data, &request)
@@ -265,7 +527,8 @@ func ProcessDidOpenTextDocumentNotification(data []byte) (fileURI string, fileCo
 	}
 
 	documentContent := request.Params.TextDocument.Text
-	filesOpenedByEditor[documentURI] = documentContent
+
+	openedDocuments.set(documentURI, documentContent)
 
 	return documentURI, []byte(documentContent)
 }
@@ -291,7 +554,7 @@ type DidChangeTextDocumentParams struct {
 	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
 }
 
-func ProcessDidChangeTextDocumentNotification(data []byte) (fileURI string, fileContent []byte) {
+func ProcessDidChangeTextDocumentNotification(data []byte, openedDocuments *OpenedDocuments) (fileURI string, fileContent []byte) {
 	var request RequestMessage[DidChangeTextDocumentParams]
 
 	err := json.Unmarshal(data, &request)
@@ -336,7 +599,8 @@ func ProcessDidChangeTextDocumentNotification(data []byte) (fileURI string, file
 	}
 
 	documentContent := documentChanges[0].Text
-	filesOpenedByEditor[documentURI] = documentContent
+
+	openedDocuments.set(documentURI, documentContent)
 
 	return documentURI, []byte(documentContent)
 }
@@ -345,7 +609,7 @@ type DidCloseTextDocumentParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 }
 
-func ProcessDidCloseTextDocumentNotification(data []byte) (fileURI string, fileContent []byte) {
+func ProcessDidCloseTextDocumentNotification(data []byte, openedDocuments *OpenedDocuments) (fileURI string, fileContent []byte) {
 	var request RequestMessage[DidCloseTextDocumentParams]
 
 	err := json.Unmarshal(data, &request)
@@ -372,17 +636,113 @@ func ProcessDidCloseTextDocumentNotification(data []byte) (fileURI string, fileC
 	}
 
 	documentContent := request.Params.TextDocument.Text
-	delete(filesOpenedByEditor, documentPath)
+
+	openedDocuments.remove(documentPath)
 
 	return documentPath, []byte(documentContent)
 }
 
+func (documents *OpenedDocuments) set(uri string, content string) {
+	documents.mu.Lock()
+	defer documents.mu.Unlock()
+
+	documents.contents[uri] = content
+}
+
+func (documents *OpenedDocuments) remove(uri string) {
+	documents.mu.Lock()
+	defer documents.mu.Unlock()
+
+	delete(documents.contents, uri)
+}
+
+// Whether the client currently own the content of the file (opened and not yet closed),
+// in which case the content on disk is outdated and must be ignored
+func (documents *OpenedDocuments) Contains(uri string) bool {
+	if documents == nil {
+		return false
+	}
+
+	documents.mu.Lock()
+	defer documents.mu.Unlock()
+
+	_, ok := documents.contents[uri]
+	return ok
+}
+
+// Uri of all files whose content is owned by the editor, the other files are owned by the disk
+func (documents *OpenedDocuments) GetUris() []string {
+	if documents == nil {
+		return nil
+	}
+
+	documents.mu.Lock()
+	defer documents.mu.Unlock()
+
+	uris := make([]string, 0, len(documents.contents))
+	for uri := range documents.contents {
+		uris = append(uris, uri)
+	}
+
+	return uris
+}
+
+type FileChangeType int
+
+const (
+	FileCreated FileChangeType = 1
+	FileChanged FileChangeType = 2
+	FileDeleted FileChangeType = 3
+)
+
+type FileEvent struct {
+	Uri  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+func ProcessDidChangeWatchedFilesNotification(data []byte) []FileEvent {
+	var request RequestMessage[DidChangeWatchedFilesParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		slog.Warn("error while unmarshalling data during 'workspace/didChangeWatchedFiles' phase, "+err.Error(),
+			slog.String("received_req", string(data)),
+		)
+		return nil
+	}
+
+	events := request.Params.Changes
+
+	for index, event := range events {
+		fileUri, err := url.PathUnescape(event.Uri) // needed for windows os
+		if err != nil {
+			slog.Error("file uri received from client is malformated. "+err.Error(),
+				slog.Group("details",
+					slog.String("file_uri", event.Uri),
+					slog.Any("request_info", request),
+				),
+			)
+			continue
+		}
+
+		events[index].Uri = fileUri
+	}
+
+	return events
+}
+
 type MarkupContent struct {
 	Kind  string `json:"kind"`
 	Value string `json:"value"`
 }
 
-func ProcessHoverRequest(data []byte, openFiles map[string]*checker.FileDefinition) []byte {
+// 'functions' are the template functions found in the Go code for that file, their doc comment is appended to the hover,
+// and 'hoverGoCode' give the hover of the go code written within the 'go:code' comments (see 'GoCodeHover()')
+func ProcessHoverRequest(data []byte, openFiles map[string]*checker.FileDefinition, rawFiles map[string][]byte, client *ClientCapabilities, functions []gosource.TemplateFunction, hoverGoCode func(uri string, position lexer.Position) (string, lexer.Range)) []byte {
 	type HoverParams struct {
 		TextDocument TextDocumentItem `json:"textDocument"`
 		Position     Position         `json:"position"`
@@ -397,11 +757,6 @@ func ProcessHoverRequest(data []byte, openFiles map[string]*checker.FileDefiniti
 		return nil
 	}
 
-	position := lexer.Position{
-		Line:      int(request.Params.Position.Line),
-		Character: int(request.Params.Position.Character),
-	}
-
 	fileUri, err := url.PathUnescape(request.Params.TextDocument.Uri) // needed for windows os
 	if err != nil {
 		slog.Error("file uri received from client is malformated. "+err.Error(),
@@ -413,20 +768,21 @@ func ProcessHoverRequest(data []byte, openFiles map[string]*checker.FileDefiniti
 		fileUri = request.Params.TextDocument.Uri
 	}
 
+	// opened but not analysed (yet), eg. file excluded by the config
 	file := openFiles[fileUri]
 	if file == nil {
-		msg := ("file requested by lsp client is not open on the server. that file must be open for 'go-to-definition' to make any computation")
-		slog.Error(msg,
-			slog.Group("details",
-				slog.String("uri", request.Params.TextDocument.Uri),
-				slog.Any("unmarshalled_req", request),
-				slog.String("received_req", string(data)),
-			),
-		)
-		panic(msg)
+		slog.Warn("no analysis of the file requested by the lsp client for 'hover'", slog.String("uri", request.Params.TextDocument.Uri))
+		return ProcessRequestWithoutResult(request.JsonRpc, request.Id)
 	}
 
-	typeStringified, reach := gota.Hover(file, position)
+	lineIndex := NewLineIndex(rawFiles[fileUri], client.GetPositionEncoding())
+	position := lineIndex.FromLspPosition(request.Params.Position)
+
+	typeStringified, reach := hoverGoCode(fileUri, position)
+	if typeStringified == "" {
+		typeStringified, reach = gota.Hover(file, position)
+		typeStringified, reach = appendTemplateFunctionDoc(typeStringified, reach, functions, lineIndex, position)
+	}
 
 	type HoverResult struct {
 		Contents MarkupContent `json:"contents"`
@@ -438,13 +794,17 @@ func ProcessHoverRequest(data []byte, openFiles map[string]*checker.FileDefiniti
 		Id:      request.Id,
 		Result: &HoverResult{
 			Contents: MarkupContent{
-				Kind:  "markdown",
+				Kind:  client.GetHoverMarkupKind(),
 				Value: typeStringified,
 			},
-			Range: convertParserRangeToLspRange(reach),
+			Range: convertParserRangeToLspRange(reach, lineIndex),
 		},
 	}
 
+	if response.Result.Contents.Kind == MarkupKindPlainText {
+		response.Result.Contents.Value = convertMarkdownToPlainText(typeStringified)
+	}
+
 	if typeStringified == "" {
 		response.Result = nil
 	}
@@ -482,7 +842,10 @@ type DefinitionResults struct {
 	Location
 }
 
-func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefinition, rawFiles map[string][]byte) (response []byte, fileName string) {
+// The symbols declared by the Go code (struct field of the 'Input' type, function of a 'FuncMap', builtin function) are resolved
+// to their Go declaration. 'findInjectedGoDeclaration' find the declaration of a symbol of the injected 'go:code' (see 'FindInjectedGoDeclaration()'),
+// and 'toGoLocation' convert a position of the Go code to a location for the client
+func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefinition, rawFiles map[string][]byte, client *ClientCapabilities, findInjectedGoDeclaration func(uri string, position lexer.Position) token.Position, toGoLocation func(position token.Position) (Location, bool)) (response []byte, fileName string) {
 	var req RequestMessage[DefinitionParams]
 
 	err := json.Unmarshal(data, &req)
@@ -491,11 +854,6 @@ func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefini
 		return nil, ""
 	}
 
-	position := lexer.Position{
-		Line:      int(req.Params.Position.Line),
-		Character: int(req.Params.Position.Character),
-	}
-
 	fileUri, err := url.PathUnescape(req.Params.TextDocument.Uri) // needed for windows os
 	if err != nil {
 		slog.Error("file uri received from client is malformated. "+err.Error(),
@@ -507,19 +865,15 @@ func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefini
 		fileUri = req.Params.TextDocument.Uri
 	}
 
+	// opened but not analysed (yet), eg. file excluded by the config
 	currentFile := openFiles[fileUri]
 	if currentFile == nil {
-		msg := ("file requested by lsp client for 'go-to-definition' is not open on the server. That file must be open to make any computation")
-		slog.Error(msg,
-			slog.Group("details",
-				slog.String("uri", req.Params.TextDocument.Uri),
-				slog.Any("unmarshalled_req", req),
-				slog.String("received_req", string(data)),
-			),
-		)
-		panic(msg)
+		slog.Warn("no analysis of the file requested by the lsp client for 'go-to-definition'", slog.String("uri", req.Params.TextDocument.Uri))
+		return ProcessRequestWithoutResult(req.JsonRpc, req.Id), ""
 	}
 
+	position := NewLineIndex(rawFiles[fileUri], client.GetPositionEncoding()).FromLspPosition(req.Params.Position)
+
 	defer func() {
 		if r := recover(); r != nil {
 			msg := r.(string)
@@ -548,6 +902,15 @@ func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefini
 
 		switch targetFileNameURI {
 		case "builtin":
+			name, _, _ := findFunctionNameAt(NewLineIndex(rawFiles[fileUri], client.GetPositionEncoding()), position)
+			if name == "" {
+				continue
+			}
+
+			if location, ok := toGoLocation(gosource.FindBuiltinFunction(name)); ok {
+				res.Result = append(res.Result, DefinitionResults{Location: location})
+			}
+
 			continue
 		case "":
 			msg := ("found a symbol definition without a valid fileName during 'go-to-definition'")
@@ -562,9 +925,20 @@ func ProcessGoToDefinition(data []byte, openFiles map[string]*checker.FileDefini
 			panic(msg)
 		}
 
+		targetLineIndex := NewLineIndex(rawFiles[targetFileNameURI], client.GetPositionEncoding())
+
+		// the definition injected from the Go code is not shown, the Go declaration is shown instead
+		if targetLineIndex.IsBeyondContent(reach.Start) {
+			if location, ok := toGoLocation(findInjectedGoDeclaration(targetFileNameURI, reach.Start)); ok {
+				res.Result = append(res.Result, DefinitionResults{Location: location})
+			}
+
+			continue
+		}
+
 		result := DefinitionResults{}
 		result.Uri = targetFileNameURI
-		result.Range = convertParserRangeToLspRange(reach)
+		result.Range = convertParserRangeToLspRange(reach, targetLineIndex)
 
 		res.Result = append(res.Result, result)
 	}
@@ -588,12 +962,30 @@ type FoldingRangeParams struct {
 
 type FoldingRangeResult struct {
 	StartLine      uint             `json:"startLine"`
-	StartCharacter uint             `json:"startCharacter"`
+	StartCharacter *uint            `json:"startCharacter,omitempty"`
 	EndLine        uint             `json:"endLine"`
-	EndCharacter   uint             `json:"endCharacter"`
+	EndCharacter   *uint            `json:"endCharacter,omitempty"`
 	Kind           FoldingRangeKind `json:"kind"`
 }
 
+// Characters are left out when the client only fold whole lines ('lineFoldingOnly')
+func newFoldingRangeResult(reach Range, kind FoldingRangeKind, client *ClientCapabilities) FoldingRangeResult {
+	fold := FoldingRangeResult{
+		StartLine: reach.Start.Line,
+		EndLine:   reach.End.Line,
+		Kind:      kind,
+	}
+
+	if client != nil && client.TextDocument.FoldingRange.LineFoldingOnly {
+		return fold
+	}
+
+	fold.StartCharacter = &reach.Start.Character
+	fold.EndCharacter = &reach.End.Character
+
+	return fold
+}
+
 type FoldingRangeKind string
 
 const (
@@ -625,7 +1017,8 @@ func ProcessFoldingRangeRequest(data []byte, storage *WorkSpaceStore, textFromCl
 		fileUri = req.Params.TextDocument.Uri
 	}
 
-	rootNode := getParseTreeForExistingFile(fileUri, storage, textFromClient, muTextFromClient)
+	rootNode, fileContent := getParseTreeForExistingFile(fileUri, storage, textFromClient, muTextFromClient)
+	lineIndex := NewLineIndex(fileContent, storage.Client.GetPositionEncoding())
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -651,34 +1044,28 @@ func ProcessFoldingRangeRequest(data []byte, storage *WorkSpaceStore, textFromCl
 
 	for _, group := range groups {
 		groupRange := group.Range()
-		reach := convertParserRangeToLspRange(groupRange)
+		if lineIndex.IsBeyondContent(groupRange.Start) {
+			continue // injected by the LSP, see 'InjectGoCode()'
+		}
+		reach := convertParserRangeToLspRange(groupRange, lineIndex)
 
 		if reach.Start.Line != reach.End.Line { // end_line > start_line
 			reach.End.Line--
 		}
 
-		fold := FoldingRangeResult{
-			StartLine:      reach.Start.Line,
-			StartCharacter: reach.Start.Character,
-			EndLine:        reach.End.Line,
-			EndCharacter:   reach.End.Character,
-			Kind:           foldingRangeRegion,
-		}
+		fold := newFoldingRangeResult(reach, foldingRangeRegion, storage.Client)
 
 		res.Result = append(res.Result, fold)
 	}
 
 	for _, comment := range comments {
 		commentRange := comment.Range()
-		reach := convertParserRangeToLspRange(commentRange)
-
-		fold := FoldingRangeResult{
-			StartLine:      reach.Start.Line,
-			StartCharacter: reach.Start.Character,
-			EndLine:        reach.End.Line,
-			EndCharacter:   reach.End.Character,
-			Kind:           foldingRangeComment,
+		if lineIndex.IsBeyondContent(commentRange.Start) {
+			continue
 		}
+		reach := convertParserRangeToLspRange(commentRange, lineIndex)
+
+		fold := newFoldingRangeResult(reach, foldingRangeComment, storage.Client)
 
 		if comment.GoCode != nil {
 			fold.Kind = foldingRangeImport
@@ -696,29 +1083,40 @@ func ProcessFoldingRangeRequest(data []byte, storage *WorkSpaceStore, textFromCl
 	return responseData, fileName
 }
 
-func getParseTreeForExistingFile(uri string, storage *WorkSpaceStore, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) *parser.GroupStatementNode {
+// Content of the file waiting for analysis, or else the last content analysed.
+// Must be called while holding 'muTextFromClient'
+func getMostRecentContent(uri string, storage *WorkSpaceStore, textFromClient map[string][]byte) []byte {
+	if content, ok := textFromClient[uri]; ok && content != nil { // 'nil' content mark a file waiting for deletion
+		return content
+	}
+
+	return storage.RawFiles[uri]
+}
+
+// Return the most recent parse tree of the file, along the content it was parsed from
+func getParseTreeForExistingFile(uri string, storage *WorkSpaceStore, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) (*parser.GroupStatementNode, []byte) {
 	var rootNode *parser.GroupStatementNode = nil
 
 	muTextFromClient.Lock()
 	defer muTextFromClient.Unlock()
 
 	fileContent, ok := textFromClient[uri]
-	if ok {
-		rootNode, _ = gota.ParseSingleFile(fileContent)
-		return rootNode
+	if ok && fileContent != nil { // 'nil' content mark a file waiting for deletion
+		rootNode, _ = storage.ParseFile(uri, fileContent)
+		return rootNode, fileContent
 	}
 
 	rootNode, ok = storage.ParsedFiles[uri]
 	if ok {
-		return rootNode
+		return rootNode, storage.RawFiles[uri]
 	}
 
 	// fallback
 	fileContent, ok = storage.RawFiles[uri]
 	if ok {
-		rootNode, _ = gota.ParseSingleFile(fileContent)
-		return rootNode
+		rootNode, _ = storage.ParseFile(uri, fileContent)
+		return rootNode, fileContent
 	}
 
-	return nil
+	return nil, nil
 }
@@ -6,88 +6,205 @@ import (
 	"errors"
 	"io"
 	"log"
+	"log/slog"
 	"strconv"
 )
 
-// func Decode (in *os.File) *bufio.Scanner {
-func ReceiveInput(input io.Reader) *bufio.Scanner {
-	scanner := bufio.NewScanner(input)
-	scanner.Split(decode)
+// Largest 'Content-Length' accepted, the content of a larger message is skipped without being buffered.
+// Otherwise a single bad (or hostile) header would allocate gigabytes and take the whole server down
+const MAX_CONTENT_LENGTH = 256 << 20
+
+// Longest header line accepted, the rest of a longer line is skipped and its message dropped
+const MAX_HEADER_LINE_LENGTH = 8 << 10
 
-	return scanner
+// Streaming reader of LSP messages ('header' + 'content' parts).
+// Unlike 'bufio.Scanner', the size of a message is only limited by 'MAX_CONTENT_LENGTH',
+// the content is read in one go as soon as its 'Content-Length' is known.
+//
+// A message with a malformed header is never fatal. Its header is consumed,
+// its content is skipped whenever the length is known, and an empty message
+// is produced instead so that the caller can decide what to do with it
+type MessageReader struct {
+	reader           *bufio.Reader
+	message          []byte
+	err              error
+	maxContentLength int
 }
 
-// For most case, 'SendToLspClient()' is prefered since it automatically encode the response.
-// Send response data over output. The respose data must be 'enconded' first
-func SendOutput(output io.Writer, response []byte) {
-	_, err := output.Write(response)
-	if err != nil {
-		log.Printf("Error while writing file to 'stdout': %s", err.Error())
-	}
+type messageHeader struct {
+	contentLength int
+	errs          []error
 }
 
-// Send 'response' to LSP client over the wire ('output').
-// Encoding is done within this function, so it is not advised to do another encoding
-func SendToLspClient(output io.Writer, response []byte) {
-	response = Encode(response)
-	SendOutput(output, response)
+// func Decode (in *os.File) *bufio.Scanner {
+func ReceiveInput(input io.Reader) *MessageReader {
+	return &MessageReader{
+		reader:           bufio.NewReaderSize(input, MAX_HEADER_LINE_LENGTH),
+		maxContentLength: MAX_CONTENT_LENGTH,
+	}
 }
 
-func Encode(dataContent []byte) []byte {
-	length := strconv.Itoa(len(dataContent))
-	dataHeader := []byte("Content-Length: " + length + "\r\n\r\n")
-	data := append(dataHeader, dataContent...)
+// Read the next message, which is then available through 'Bytes()'.
+// Return false when the input is exhausted or an unrecoverable error happened ('Err()')
+func (r *MessageReader) Scan() bool {
+	r.message = nil
 
-	return data
-}
+	if r.err != nil {
+		return false
+	}
 
-// func inputParsingSplitFunc (data []byte, atEOF bool) (advance int, token []byte, err error) {
-func decode(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	indexStartData := bytes.Index(data, []byte("\r\n\r\n"))
-	if indexStartData == -1 {
-		return 0, nil, nil
+	header, err := r.readHeader()
+	if err == io.EOF {
+		return false
+	} else if err != nil {
+		r.err = err
+		return false
 	}
 
-	// TODO: Support 'Content-Type' header as well in the near future
-	contentLength, err := getHeaderContentLengthSplitFunc(data[:indexStartData])
-	if err != nil {
-		return indexStartData + 4, []byte{}, nil
+	if header.contentLength < 0 {
+		slog.Warn("message header dropped, no valid 'Content-Length' found", slog.Any("header_errors", header.errs))
+		r.message = []byte{}
+		return true
+	}
+
+	if header.contentLength > r.maxContentLength {
+		slog.Warn("message content skipped, 'Content-Length' is larger than the limit",
+			slog.Int("content_length", header.contentLength),
+			slog.Int("limit", r.maxContentLength),
+		)
+
+		return r.skipContent(header.contentLength)
+	}
+
+	if len(header.errs) > 0 {
+		slog.Warn("message content dropped, malformated header", slog.Any("header_errors", header.errs))
+		return r.skipContent(header.contentLength)
+	}
+
+	content := make([]byte, header.contentLength)
+
+	_, err = io.ReadFull(r.reader, content)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		slog.Warn("input closed before the end of the message content",
+			slog.Int("content_length", header.contentLength),
+		)
+		return false
+	} else if err != nil {
+		r.err = err
+		return false
 	}
 
-	if len(data[indexStartData:]) < contentLength {
-		return 0, nil, nil
+	r.message = content
+	return true
+}
+
+// Discard the content of a dropped message, without buffering it. An empty message is produced instead
+func (r *MessageReader) skipContent(contentLength int) bool {
+	_, err := io.CopyN(io.Discard, r.reader, int64(contentLength))
+	if err == io.EOF {
+		slog.Warn("input closed before the end of the skipped message content", slog.Int("content_length", contentLength))
+		return false
+	} else if err != nil {
+		r.err = err
+		return false
+	}
+
+	r.message = []byte{}
+	return true
+}
+
+// Content of the last message read by 'Scan()'
+func (r *MessageReader) Bytes() []byte {
+	return r.message
+}
+
+// First non-EOF error encountered by 'Scan()'
+func (r *MessageReader) Err() error {
+	return r.err
+}
+
+// Consume header lines until the empty line separating the header from the content.
+// The header fields are only recognized at the start of a line, the lines of anything else
+// (eg. content of a previous message without a valid 'Content-Length') are ignored
+func (r *MessageReader) readHeader() (*messageHeader, error) {
+	header := &messageHeader{
+		contentLength: -1,
 	}
 
-	indexStartData = indexStartData + 4
-	indexEndData := indexStartData + contentLength
+	for count := 0; ; count++ {
+		line, err := r.reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			header.errs = append(header.errs, errors.New("Header line longer than the limit of "+strconv.Itoa(MAX_HEADER_LINE_LENGTH)+" bytes"))
 
-	return indexEndData, data[indexStartData:indexEndData], nil
+			for err == bufio.ErrBufferFull { // rest of the line
+				_, err = r.reader.ReadSlice('\n')
+			}
+
+			line = []byte("-") // neither empty nor a header field
+		}
+
+		if err == io.EOF {
+			return nil, io.EOF // incomplete header are discarded
+		} else if err != nil {
+			return nil, err
+		}
+
+		// the very first line always belong to the header, even when empty,
+		// otherwise a lone "\r\n\r\n" would be read as 2 distinct (empty) headers
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 && count > 0 {
+			return header, nil
+		}
+
+		parseHeaderField(line, header)
+	}
 }
 
-func getHeaderContentLengthSplitFunc(data []byte) (int, error) {
-	indexHeader := bytes.LastIndex(data, []byte("Content-Length"))
-	if indexHeader == -1 {
-		return -1, errors.New("Unable to find the 'content-length' for this input ! Input parsing aborted")
+func parseHeaderField(line []byte, header *messageHeader) {
+	if value, found := getHeaderFieldValue(line, "content-length"); found {
+		contentLength, err := getHeaderContentLength(value)
+		if err != nil {
+			header.contentLength = -1
+			header.errs = append(header.errs, err)
+			return
+		}
+
+		header.contentLength = contentLength
+		return
 	}
 
-	indexLineSeparator := bytes.Index(data[indexHeader:], []byte("\r\n"))
-	if indexLineSeparator >= 0 {
-		indexLineSeparator += indexHeader
-	} else if indexLineSeparator == -1 {
-		indexLineSeparator = len(data)
+	if value, found := getHeaderFieldValue(line, "content-type"); found {
+		err := validateHeaderContentType(value)
+		if err != nil {
+			header.errs = append(header.errs, err)
+		}
+
+		return
 	}
 
-	indexKeyValueSeparator := bytes.Index(data[indexHeader:indexLineSeparator], []byte(":"))
-	if indexKeyValueSeparator == -1 {
-		return -1, errors.New("Malformated 'Content-Length' ! Unable to find kay-value pair separator ':'")
+	// other header fields are ignored
+}
+
+// Find 'name' at the start of the line (case insensitive) and return what is after its key-value separator ':'
+func getHeaderFieldValue(line []byte, name string) (value []byte, found bool) {
+	if !bytes.HasPrefix(bytes.ToLower(line), []byte(name)) {
+		return nil, false
 	}
 
-	indexKeyValueSeparator += indexHeader
+	rest := bytes.TrimLeft(line[len(name):], " \t")
+	if len(rest) == 0 || rest[0] != ':' {
+		return []byte{}, true // malformated, key-value pair separator ':' is missing
+	}
 
-	contentLengthString := data[indexKeyValueSeparator+1 : indexLineSeparator]
-	contentLengthString = bytes.TrimSpace(contentLengthString)
+	return bytes.TrimSpace(rest[1:]), true
+}
 
-	contentLength, err := strconv.Atoi(string(contentLengthString))
+func getHeaderContentLength(value []byte) (int, error) {
+	if len(value) == 0 {
+		return -1, errors.New("Malformated 'Content-Length' ! Unable to find its value")
+	}
+
+	contentLength, err := strconv.Atoi(string(value))
 	if err != nil {
 		return -1, errors.New("Malformated 'Content-Length' ! content length value is not an integer")
 	}
@@ -96,5 +213,47 @@ func getHeaderContentLengthSplitFunc(data []byte) (int, error) {
 		return -1, errors.New("Error, 'Content-Length' shouldn't have a negative value")
 	}
 
-	return contentLength, nil
+	return contentLength, nil // the limit is enforced by the reader, which skip the content of a larger message
+}
+
+// Only 'utf-8' content is supported, which is also the default when no charset is specified.
+// 'utf8' is accepted as well for backward compatibility, as advised by the LSP specification
+func validateHeaderContentType(value []byte) error {
+	for _, param := range bytes.Split(value, []byte(";")) {
+		key, charset, found := bytes.Cut(bytes.TrimSpace(param), []byte("="))
+		if !found || !bytes.EqualFold(bytes.TrimSpace(key), []byte("charset")) {
+			continue
+		}
+
+		charset = bytes.Trim(bytes.TrimSpace(charset), `"`)
+		if !bytes.EqualFold(charset, []byte("utf-8")) && !bytes.EqualFold(charset, []byte("utf8")) {
+			return errors.New("Unsupported 'Content-Type' charset '" + string(charset) + "', only 'utf-8' is supported")
+		}
+	}
+
+	return nil
+}
+
+// For most case, 'SendToLspClient()' is prefered since it automatically encode the response.
+// Send response data over output. The respose data must be 'enconded' first
+func SendOutput(output io.Writer, response []byte) {
+	_, err := output.Write(response)
+	if err != nil {
+		log.Printf("Error while writing file to 'stdout': %s", err.Error())
+	}
+}
+
+// Send 'response' to LSP client over the wire ('output').
+// Encoding is done within this function, so it is not advised to do another encoding
+func SendToLspClient(output io.Writer, response []byte) {
+	response = Encode(response)
+	SendOutput(output, response)
+}
+
+func Encode(dataContent []byte) []byte {
+	length := strconv.Itoa(len(dataContent))
+	dataHeader := []byte("Content-Length: " + length + "\r\n\r\n")
+	data := append(dataHeader, dataContent...)
+
+	return data
 }
@@ -0,0 +1,229 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReceiveInput(t *testing.T) {
+	largeContent := "{'data': '" + strings.Repeat("x", 200_000) + "'}"
+
+	tests := []struct {
+		input   string
+		wants   []string
+		isError bool
+	}{
+		{
+			input: "Content-Length: 12\r\n" + "\r\n" + "{'num': 597}",
+			wants: []string{"{'num': 597}"},
+		},
+		{
+			input: "Content-Length: 10\r\n" + "\r\n" + "{'num': 597}",
+			wants: []string{"{'num': 59"},
+		},
+		{
+			input: "Content-Type: utf8\r\n" + "\r\n" + "{'num': 597}",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Type: utf8\r\n" + "\r\n" + "{'Content-Length': 22}",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: 22\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'Content-Length': 27}",
+			wants: []string{"{'Content-Length': 27}"},
+		},
+		{
+			input: "Content-Type: utf8\r\n" + "Content-Length: 22\r\n" + "\r\n" + "{'Content-Length': 27}",
+			wants: []string{"{'Content-Length': 27}"},
+		},
+		{
+			input: "Content-Length 22\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'Content-Length': 27}",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{"{'user': 'steveen'}"},
+		},
+		{
+			input: "Content-Length: 19.2\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: 19,2\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{"{'user': 'steveen'}"},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" + "\r\n" + "{'user': 'steveen'}Garbage data in here",
+			wants: []string{"{'user': 'steveen'}"},
+		},
+		{
+			input: "Content-Length: 0\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" + "\r\n" + "{'user': 'steveen'}Garbage data in here",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: -19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" + "\r\n" + "{'user': 'steveen'}Garbage data in here",
+			wants: []string{""},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" +
+				"\r\n" + "{'user': 'steveen'}" +
+				"Content-Length: 10\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'user': 'steveen'}", "{'num': 3}"},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" +
+				"\r\n" + "{'user': 'steveen'}" +
+				"Content-Length: 10\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'user': 'steveen'}", "{'num': 3}"},
+		},
+		{
+			// a header field is only recognized at the start of a line
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" +
+				"\r\n" + "{'user': 'steveen'}Garbage data in here" +
+				"Content-Length: 10\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'user': 'steveen'}", ""},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" +
+				"\r\n" + "{'user': 'steveen'}Garbage data in here\r\n" +
+				"Content-Length: 10\r\n" + "Content-Type: utf8\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'user': 'steveen'}", "{'num': 3}"},
+		},
+		{
+			input: "Content-Type: utf8\r\n" + "Authorization: SIMPLE\r\n" + "Content-Length: 19\r\n" +
+				"\r\n" + "{'user': 'steveen'}" +
+				"Content-Type: utf8\r\n" + "Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'user': 'steveen'}", "{'num': 3}"},
+		},
+		{
+			input: "\r\n" +
+				"\r\n" + "{'user': 'steveen'}" +
+				"Content-Type: utf8\r\n" + "Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"", "{'num': 3}"},
+		},
+		{
+			input: "Content-Length: " + strconv.Itoa(len(largeContent)) + "\r\n" + "\r\n" + largeContent,
+			wants: []string{largeContent},
+		},
+		{
+			input: "Content-Length: 10\r\n" + "Content-Type: application/vscode-jsonrpc; charset=utf-16\r\n" + "\r\n" + "{'num': 3}" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 4}",
+			wants: []string{"", "{'num': 4}"},
+		},
+		{
+			input: "Content-Length: 10\r\n" + "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'num': 3}"},
+		},
+		{
+			input: "content-length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'num': 3}"},
+		},
+		{
+			// the content of an unknown length is read as header lines, which never find a field within the content
+			input: "Content-Length: 19.2\r\n" + "\r\n" + "{'user': 'steveen'}" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"", ""},
+		},
+		{
+			input: "Content-Length: 19.2\r\n" + "\r\n" + "{'user': 'steveen'}\r\n" + "\r\n" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"", "", "{'num': 3}"},
+		},
+		{
+			input: "Content-Length: 10\r\n" + "X-Note: content-length: 4\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'num': 3}"},
+		},
+		{
+			// the content of a message larger than the limit is skipped, the input end before the whole content is skipped
+			input: "Content-Length: 99999999999\r\n" + "\r\n" + "{'user': 'steveen'}" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{},
+		},
+		{
+			input: "Content-Length: " + strconv.Itoa(MAX_CONTENT_LENGTH+1) + "\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{},
+		},
+		{
+			input: "X-Long: " + strings.Repeat("x", MAX_HEADER_LINE_LENGTH) + "\r\n" + "Content-Length: 10\r\n" + "\r\n" + "{'num': 3}" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 4}",
+			wants: []string{"", "{'num': 4}"},
+		},
+		{
+			input: "Content-Length: 99999999999999999999999\r\n" + "\r\n" + "{'user': 'steveen'}",
+			wants: []string{""},
+		},
+	}
+
+	for count, test := range tests {
+		testName := strconv.Itoa(count) + "_" + test.input[:8] + "_test"
+
+		t.Run(testName, func(t *testing.T) {
+			scanner := ReceiveInput(strings.NewReader(test.input))
+
+			answers := []string{}
+
+			for scanner.Scan() {
+				msg := string(scanner.Bytes())
+				answers = append(answers, msg)
+			}
+
+			isError := scanner.Err() != nil
+
+			if len(answers) != len(test.wants) {
+				t.Errorf("\n Size of answers (%d) do not match the expected one (%d)", len(answers), len(test.wants))
+				t.Errorf("\n Expected: %v \n Got: %v \n Error: %v", test.wants, answers, scanner.Err())
+				return
+			}
+
+			for i := 0; i < len(answers); i++ {
+				if answers[i] != test.wants[i] || isError != test.isError {
+					t.Errorf("\n Expected : %s \n Got : %s \n With error: %v", test.wants[i], answers[i], scanner.Err())
+				}
+			}
+		})
+	}
+}
+
+func TestReceiveInputContentLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		wants []string
+	}{
+		{
+			input: "Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"{'num': 3}"},
+		},
+		{
+			input: "Content-Length: 21\r\n" + "\r\n" + "{'content-length': 4}" +
+				"Content-Length: 10\r\n" + "\r\n" + "{'num': 3}",
+			wants: []string{"", "{'num': 3}"},
+		},
+		{
+			input: "Content-Length: 19\r\n" + "\r\n" + "{'user': 'stev",
+			wants: []string{},
+		},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			scanner := ReceiveInput(strings.NewReader(test.input))
+			scanner.maxContentLength = 12
+
+			answers := []string{}
+			for scanner.Scan() {
+				answers = append(answers, string(scanner.Bytes()))
+			}
+
+			if !slices.Equal(answers, test.wants) || scanner.Err() != nil {
+				t.Errorf("\n Input: %q \n Expected: %q \n Got: %q \n With error: %v", test.input, test.wants, answers, scanner.Err())
+			}
+		})
+	}
+}
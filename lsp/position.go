@@ -0,0 +1,206 @@
+package lsp
+
+import (
+	"bytes"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+// Unit in which the 'character' of an LSP position is counted.
+// Negotiated during 'initialize', and 'utf-16' by default as per specification
+type PositionEncodingKind string
+
+const (
+	PositionEncodingUTF8  PositionEncodingKind = "utf-8"
+	PositionEncodingUTF16 PositionEncodingKind = "utf-16"
+	PositionEncodingUTF32 PositionEncodingKind = "utf-32"
+)
+
+// Pick the position encoding used for the session among the ones supported by the client.
+// 'utf-8' is prefered since it is the native unit of the parser (no conversion at all),
+// and 'utf-16' is the fallback mandated by the specification
+func NegotiatePositionEncoding(clientEncodings []string) PositionEncodingKind {
+	preferences := []PositionEncodingKind{PositionEncodingUTF8, PositionEncodingUTF32, PositionEncodingUTF16}
+
+	for _, preference := range preferences {
+		for _, encoding := range clientEncodings {
+			if PositionEncodingKind(encoding) == preference {
+				return preference
+			}
+		}
+	}
+
+	return PositionEncodingUTF16
+}
+
+// Convert positions between the parser and the LSP client for a single file.
+//
+// Parser positions ('lexer.Position') count characters in bytes within a line,
+// while LSP positions count them in the negotiated encoding.
+// Lines are separated by '\n' on both sides
+type LineIndex struct {
+	content    []byte
+	lineStarts []int
+	encoding   PositionEncodingKind
+}
+
+func NewLineIndex(content []byte, encoding PositionEncodingKind) *LineIndex {
+	if encoding == "" {
+		encoding = PositionEncodingUTF16
+	}
+
+	lineStarts := make([]int, 1, bytes.Count(content, []byte("\n"))+1)
+	lineStarts[0] = 0
+
+	for index, char := range content {
+		if char == '\n' {
+			lineStarts = append(lineStarts, index+1)
+		}
+	}
+
+	index := &LineIndex{
+		content:    content,
+		lineStarts: lineStarts,
+		encoding:   encoding,
+	}
+
+	return index
+}
+
+func (li *LineIndex) line(number int) []byte {
+	if li == nil || number < 0 || number >= len(li.lineStarts) {
+		return nil
+	}
+
+	start := li.lineStarts[number]
+	end := len(li.content)
+
+	if number+1 < len(li.lineStarts) {
+		end = li.lineStarts[number+1] - 1 // without '\n'
+	}
+
+	return li.content[start:end]
+}
+
+// Number of lines of the file, an empty file still count as 1 line
+func (li *LineIndex) LineCount() int {
+	if li == nil {
+		return 0
+	}
+
+	return len(li.lineStarts)
+}
+
+// Position past the last line, or past the end of its line ('\r' excluded).
+// Nothing the client sent can be there, only the code injected by the LSP itself
+func (li *LineIndex) IsBeyondContent(pos lexer.Position) bool {
+	if li == nil {
+		return false
+	}
+
+	if pos.Line >= len(li.lineStarts) {
+		return true
+	}
+
+	return pos.Character > len(bytes.TrimSuffix(li.line(pos.Line), []byte("\r")))
+}
+
+// Position (in bytes) of the byte offset within the content
+func (li *LineIndex) PositionOfOffset(offset int) lexer.Position {
+	line, found := slices.BinarySearch(li.lineStarts, offset)
+	if !found {
+		line--
+	}
+
+	return lexer.Position{Line: line, Character: offset - li.lineStarts[line]}
+}
+
+func (li *LineIndex) ToLspPosition(pos lexer.Position) Position {
+	position := Position{
+		Line:      uint(max(pos.Line, 0)),
+		Character: uint(max(pos.Character, 0)),
+	}
+
+	if li == nil || li.encoding == PositionEncodingUTF8 {
+		return position
+	}
+
+	line := li.line(pos.Line)
+	byteCount := min(int(position.Character), len(line))
+	beyondEndOfLine := int(position.Character) - byteCount // position past the end of line are kept as is
+
+	position.Character = uint(countEncodingUnits(line[:byteCount], li.encoding) + beyondEndOfLine)
+
+	return position
+}
+
+func (li *LineIndex) ToLspRange(rg lexer.Range) Range {
+	reach := Range{
+		Start: li.ToLspPosition(rg.Start),
+		End:   li.ToLspPosition(rg.End),
+	}
+
+	return reach
+}
+
+func (li *LineIndex) FromLspPosition(pos Position) lexer.Position {
+	position := lexer.Position{
+		Line:      int(pos.Line),
+		Character: int(pos.Character),
+	}
+
+	if li == nil || li.encoding == PositionEncodingUTF8 {
+		return position
+	}
+
+	line := li.line(position.Line)
+	units := 0
+	offset := 0
+
+	for offset < len(line) && units < position.Character {
+		char, size := utf8.DecodeRune(line[offset:])
+		units += encodingUnitsOfRune(char, li.encoding)
+		offset += size
+	}
+
+	if units < position.Character { // position past the end of line
+		offset += position.Character - units
+	}
+
+	position.Character = offset
+
+	return position
+}
+
+func countEncodingUnits(text []byte, encoding PositionEncodingKind) int {
+	if encoding == PositionEncodingUTF8 {
+		return len(text)
+	}
+
+	count := 0
+	for len(text) > 0 {
+		char, size := utf8.DecodeRune(text)
+		count += encodingUnitsOfRune(char, encoding)
+		text = text[size:]
+	}
+
+	return count
+}
+
+func encodingUnitsOfRune(char rune, encoding PositionEncodingKind) int {
+	switch encoding {
+	case PositionEncodingUTF8:
+		return max(utf8.RuneLen(char), 1)
+	case PositionEncodingUTF32:
+		return 1
+	}
+
+	// utf-16, invalid utf-8 byte are decoded as 'utf8.RuneError' and count as a single unit
+	if char >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestNegotiatePositionEncoding(t *testing.T) {
+	tests := []struct {
+		clientEncodings []string
+		want            PositionEncodingKind
+	}{
+		{clientEncodings: nil, want: PositionEncodingUTF16},
+		{clientEncodings: []string{}, want: PositionEncodingUTF16},
+		{clientEncodings: []string{"utf-16"}, want: PositionEncodingUTF16},
+		{clientEncodings: []string{"utf-16", "utf-8"}, want: PositionEncodingUTF8},
+		{clientEncodings: []string{"utf-32", "utf-16"}, want: PositionEncodingUTF32},
+		{clientEncodings: []string{"latin-1"}, want: PositionEncodingUTF16},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := NegotiatePositionEncoding(test.clientEncodings)
+			if got != test.want {
+				t.Errorf("\n Client encodings: %v \n Expected: %s \n Got: %s", test.clientEncodings, test.want, got)
+			}
+		})
+	}
+}
+
+func TestLineIndexConversion(t *testing.T) {
+	content := []byte("{{ .name }}\n" + "café {{ .price }}\n" + "😀 {{ .emoji }}\r\n" + "last line")
+
+	tests := []struct {
+		encoding PositionEncodingKind
+		parser   lexer.Position
+		client   Position
+	}{
+		// ascii only line, identical for all encodings
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 0, Character: 3}, client: Position{Line: 0, Character: 3}},
+		{encoding: PositionEncodingUTF32, parser: lexer.Position{Line: 0, Character: 3}, client: Position{Line: 0, Character: 3}},
+
+		// 'é' is 2 bytes, but only 1 unit in utf-16 and utf-32
+		{encoding: PositionEncodingUTF8, parser: lexer.Position{Line: 1, Character: 9}, client: Position{Line: 1, Character: 9}},
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 1, Character: 9}, client: Position{Line: 1, Character: 8}},
+		{encoding: PositionEncodingUTF32, parser: lexer.Position{Line: 1, Character: 9}, client: Position{Line: 1, Character: 8}},
+
+		// '😀' is 4 bytes, 2 units in utf-16 (surrogate pair) and 1 unit in utf-32
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 2, Character: 8}, client: Position{Line: 2, Character: 6}},
+		{encoding: PositionEncodingUTF32, parser: lexer.Position{Line: 2, Character: 8}, client: Position{Line: 2, Character: 5}},
+
+		// end of line and beyond
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 1, Character: 19}, client: Position{Line: 1, Character: 18}},
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 1, Character: 22}, client: Position{Line: 1, Character: 21}},
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 3, Character: 9}, client: Position{Line: 3, Character: 9}},
+
+		// line outside of the file
+		{encoding: PositionEncodingUTF16, parser: lexer.Position{Line: 7, Character: 4}, client: Position{Line: 7, Character: 4}},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count)+"_"+string(test.encoding), func(t *testing.T) {
+			lineIndex := NewLineIndex(content, test.encoding)
+
+			gotClient := lineIndex.ToLspPosition(test.parser)
+			if gotClient != test.client {
+				t.Errorf("\n parser to client conversion \n Input: %v \n Expected: %v \n Got: %v", test.parser, test.client, gotClient)
+			}
+
+			gotParser := lineIndex.FromLspPosition(test.client)
+			if gotParser != test.parser {
+				t.Errorf("\n client to parser conversion \n Input: %v \n Expected: %v \n Got: %v", test.client, test.parser, gotParser)
+			}
+		})
+	}
+}
+
+func TestLineIndexFromPositionInsideSurrogatePair(t *testing.T) {
+	lineIndex := NewLineIndex([]byte("😀x"), PositionEncodingUTF16)
+
+	// a client position pointing in the middle of a surrogate pair is moved after the character
+	got := lineIndex.FromLspPosition(Position{Line: 0, Character: 1})
+	want := lexer.Position{Line: 0, Character: 4}
+
+	if got != want {
+		t.Errorf("\n Expected: %v \n Got: %v", want, got)
+	}
+}
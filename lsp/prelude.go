@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Go code of the preludes applying to the file, joined in the order of their uri (along those uris).
+// A prelude apply to the templates of its directory and of the directories below, and to every template of a set
+// whose patterns are found below its directory (see 'gosource.TemplateSet').
+// The comments of the preludes are blanked, so the go code can be injected within a 'go:code' comment.
+// The declarations of the libraries enabled by the project (eg. 'Page' of Hugo) come last, they have no uri,
+// followed by the context of the Helm templates (see 'getHelmContextCode()').
+// Must be called while holding 'muTextFromClient'
+func (storage *WorkSpaceStore) GetPreludeOfFile(uri string) (goCode string, preludeUris []string) {
+	declarations := storage.Config.GetLibraryDeclarations()
+	if helmCode := storage.getHelmContextCode(uri); helmCode != "" {
+		declarations = strings.TrimPrefix(declarations+"\n"+helmCode, "\n")
+	}
+
+	if len(storage.Preludes) == 0 {
+		return declarations, nil
+	}
+
+	filePath := storage.getFilePath(uri)
+
+	var setDirs []string
+	for _, set := range storage.GoSource.Sets {
+		if filePath == "" || !set.Contains(filePath) {
+			continue
+		}
+
+		for _, pattern := range set.Patterns {
+			setDirs = append(setDirs, getPatternDir(pattern))
+		}
+	}
+
+	for preludeUri := range storage.Preludes {
+		// the values of the Helm charts are YAML, they are only read for the type of '.Values'
+		if storage.Config.IsHelmMode() && isHelmValuesPath(storage.getRelativePath(preludeUri)) {
+			continue
+		}
+
+		preludeDir := path.Dir(preludeUri)
+
+		isApplying := strings.HasPrefix(path.Dir(uri)+"/", preludeDir+"/")
+		isApplying = isApplying || slices.ContainsFunc(setDirs, func(dir string) bool {
+			preludePath := filepath.Dir(storage.getFilePath(preludeUri))
+			return dir == preludePath || strings.HasPrefix(dir, preludePath+string(filepath.Separator))
+		})
+
+		if isApplying {
+			preludeUris = append(preludeUris, preludeUri)
+		}
+	}
+
+	slices.Sort(preludeUris)
+
+	codes := make([]string, 0, len(preludeUris))
+	for _, preludeUri := range preludeUris {
+		codes = append(codes, string(blankGoComments(storage.Preludes[preludeUri])))
+	}
+
+	if declarations != "" {
+		codes = append(codes, declarations)
+	}
+
+	return strings.Join(codes, "\n"), preludeUris
+}
+
+// Directory of the pattern, before its first segment holding a glob character
+func getPatternDir(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for strings.ContainsAny(dir, "*?[") && dir != filepath.Dir(dir) {
+		dir = filepath.Dir(dir)
+	}
+
+	return dir
+}
+
+// Replace the comments of the go code by spaces, the length and the lines are kept.
+// A '*/' would otherwise end the 'go:code' comment the go code is injected into
+func blankGoComments(goCode []byte) []byte {
+	if !bytes.Contains(goCode, []byte("//")) && !bytes.Contains(goCode, []byte("/*")) {
+		return goCode
+	}
+
+	blanked := bytes.Clone(goCode)
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(goCode))
+
+	var goScanner scanner.Scanner
+	goScanner.Init(file, goCode, nil, scanner.ScanComments)
+
+	for {
+		position, tok, _ := goScanner.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		if tok != token.COMMENT {
+			continue
+		}
+
+		start := file.Offset(position)
+
+		// the literal of the comment is stripped of its '\r', its length cannot be trusted
+		end := len(goCode)
+		if bytes.HasPrefix(goCode[start:], []byte("/*")) {
+			if index := bytes.Index(goCode[start+2:], []byte("*/")); index >= 0 {
+				end = start + 2 + index + 2
+			}
+		} else if index := bytes.IndexByte(goCode[start:], '\n'); index >= 0 {
+			end = start + index
+		}
+
+		for index := start; index < end; index++ {
+			if blanked[index] != '\n' && blanked[index] != '\r' {
+				blanked[index] = ' '
+			}
+		}
+	}
+
+	return blanked
+}
+
+// Position within the prelude files of the byte 'offset' of the prelude go code (see 'GetPreludeOfFile()').
+// Invalid when the offset lie beyond the preludes
+func (storage *WorkSpaceStore) getPreludePosition(preludeUris []string, offset int) token.Position {
+	for _, preludeUri := range preludeUris {
+		content := storage.Preludes[preludeUri]
+
+		if offset <= len(content) {
+			filePath := storage.getFilePath(preludeUri)
+			if filePath == "" {
+				return token.Position{}
+			}
+
+			position := NewLineIndex(content, PositionEncodingUTF8).PositionOfOffset(offset)
+			return token.Position{Filename: filePath, Line: position.Line + 1, Column: position.Character + 1}
+		}
+
+		offset -= len(content) + 1 // joined by '\n'
+	}
+
+	return token.Position{}
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+)
+
+func TestGetPreludeOfFile(t *testing.T) {
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Config:   DefaultProjectConfig([]string{"html"}),
+		Preludes: map[string][]byte{
+			"file:///app/types.gotypes":             []byte("type User struct { Name string } // shared"),
+			"file:///app/views/admin/admin.gotypes": []byte("type Admin struct { Level int }"),
+			"file:///app/shared/mail.gotypes":       []byte("/* */type Mail struct{}"),
+		},
+		GoSource: gosource.Discovery{
+			Sets: []gosource.TemplateSet{{Id: "main.go:8", Patterns: []string{"/app/shared/mail/*.html"}}},
+		},
+	}
+
+	tests := []struct {
+		uri      string
+		wantCode string
+		wantUris []string
+	}{
+		{
+			uri:      "file:///app/views/home.html",
+			wantCode: "type User struct { Name string }" + strings.Repeat(" ", 10),
+			wantUris: []string{"file:///app/types.gotypes"},
+		},
+		{
+			uri:      "file:///app/views/admin/users.html",
+			wantCode: "type User struct { Name string }" + strings.Repeat(" ", 10) + "\ntype Admin struct { Level int }",
+			wantUris: []string{"file:///app/types.gotypes", "file:///app/views/admin/admin.gotypes"},
+		},
+		// the set found below the directory of the prelude
+		{
+			uri:      "file:///app/shared/mail/signup.html",
+			wantCode: "     type Mail struct{}\ntype User struct { Name string }" + strings.Repeat(" ", 10),
+			wantUris: []string{"file:///app/shared/mail.gotypes", "file:///app/types.gotypes"},
+		},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			goCode, preludeUris := storage.GetPreludeOfFile(test.uri)
+			if goCode != test.wantCode || !slices.Equal(preludeUris, test.wantUris) {
+				t.Errorf("\n Uri: %s \n Expected: %q %q \n Got: %q %q", test.uri, test.wantCode, test.wantUris, goCode, preludeUris)
+			}
+		})
+	}
+}
+
+func TestGetPreludePosition(t *testing.T) {
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Preludes: map[string][]byte{
+			"file:///app/a.gotypes": []byte("type A int\ntype B int"),
+			"file:///app/b.gotypes": []byte("type C int"),
+		},
+	}
+
+	preludeUris := []string{"file:///app/a.gotypes", "file:///app/b.gotypes"}
+
+	tests := []struct {
+		offset int
+		want   token.Position
+	}{
+		{offset: 5, want: token.Position{Filename: "/app/a.gotypes", Line: 1, Column: 6}},
+		{offset: 16, want: token.Position{Filename: "/app/a.gotypes", Line: 2, Column: 6}},
+		{offset: 27, want: token.Position{Filename: "/app/b.gotypes", Line: 1, Column: 6}},
+		{offset: 100},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := storage.getPreludePosition(preludeUris, test.offset)
+			if got != test.want {
+				t.Errorf("\n Offset: %d \n Expected: %v \n Got: %v", test.offset, test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Requests initiated by the server toward the client.
+// The client responses come back through the same input as the client requests,
+// they are routed to the waiting caller by their 'id'
+type ServerRequests struct {
+	output  io.Writer
+	lastId  atomic.Int64
+	mu      sync.Mutex
+	waiting map[ID]chan ResponseMessage[json.RawMessage]
+	closed  bool
+}
+
+func NewServerRequests(output io.Writer) *ServerRequests {
+	requests := &ServerRequests{
+		output:  output,
+		waiting: make(map[ID]chan ResponseMessage[json.RawMessage]),
+	}
+
+	return requests
+}
+
+// Send the request to the client, the returned channel receive the client response.
+// The channel is closed without value if the session end before the response
+func (requests *ServerRequests) Send(method string, params any) <-chan ResponseMessage[json.RawMessage] {
+	responseChannel := make(chan ResponseMessage[json.RawMessage], 1)
+
+	request := RequestMessage[any]{
+		JsonRpc: "2.0",
+		Id:      ID(requests.lastId.Add(1)),
+		Method:  method,
+		Params:  params,
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		msg := ("error while marshalling server request '" + method + "', " + err.Error())
+		slog.Error(msg, slog.Any("request", request))
+		panic(msg)
+	}
+
+	requests.mu.Lock()
+	if requests.closed {
+		requests.mu.Unlock()
+		close(responseChannel)
+		return responseChannel
+	}
+
+	requests.waiting[request.Id] = responseChannel
+	requests.mu.Unlock()
+
+	SendToLspClient(requests.output, data)
+
+	return responseChannel
+}
+
+// Same as 'Send()', but block until the response arrive or the timeout expire.
+// 'ok' is false when no successful response was received
+func (requests *ServerRequests) SendAndWait(method string, params any, timeout time.Duration) (result json.RawMessage, ok bool) {
+	responseChannel := requests.Send(method, params)
+
+	select {
+	case response, isOpen := <-responseChannel:
+		if !isOpen || response.Error != nil {
+			return nil, false
+		}
+
+		return response.Result, true
+	case <-time.After(timeout):
+		slog.Warn("no response from client for server request '" + method + "'")
+		return nil, false
+	}
+}
+
+// Route a client response (a message without 'method') to the caller waiting for it
+func (requests *ServerRequests) ProcessResponseFromClient(data []byte) {
+	var response ResponseMessage[json.RawMessage]
+
+	err := json.Unmarshal(data, &response)
+	if err != nil {
+		slog.Warn("error while unmarshalling client response, "+err.Error(), slog.String("received_res", string(data)))
+		return
+	}
+
+	requests.mu.Lock()
+	responseChannel, ok := requests.waiting[response.Id]
+	delete(requests.waiting, response.Id)
+	requests.mu.Unlock()
+
+	if !ok {
+		slog.Warn("received a response for an unknown server request",
+			slog.Int("id", int(response.Id)),
+			slog.String("received_res", string(data)),
+		)
+		return
+	}
+
+	if response.Error != nil {
+		slog.Warn("client responded with an error to a server request",
+			slog.Int("id", int(response.Id)),
+			slog.Any("error", response.Error),
+		)
+	}
+
+	responseChannel <- response
+	close(responseChannel)
+}
+
+// Release every caller still waiting for a response, must be called at the end of the session
+func (requests *ServerRequests) Close() {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	requests.closed = true
+
+	for id, responseChannel := range requests.waiting {
+		close(responseChannel)
+		delete(requests.waiting, id)
+	}
+}
+
+type WorkDoneProgressValue struct {
+	Kind        string `json:"kind"`
+	Title       string `json:"title,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Percentage  *uint  `json:"percentage,omitempty"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+}
+
+type ProgressParams struct {
+	Token string                `json:"token"`
+	Value WorkDoneProgressValue `json:"value"`
+}
+
+// Progress shown by the client while the server is busy ('window/workDoneProgress').
+// A nil '*WorkDoneProgress' is valid and report nothing, which is the case
+// when the client do not support it or refused the token
+type WorkDoneProgress struct {
+	token  string
+	output io.Writer
+}
+
+var lastWorkDoneProgressToken atomic.Int64
+
+// Ask the client to create a progress token. This block until the client respond,
+// so it must never be called while holding a lock the main loop depends on
+func CreateWorkDoneProgress(requests *ServerRequests, client *ClientCapabilities) *WorkDoneProgress {
+	if requests == nil || client == nil || !client.Window.WorkDoneProgress {
+		return nil
+	}
+
+	token := "go-template-lsp-progress-" + strconv.FormatInt(lastWorkDoneProgressToken.Add(1), 10)
+
+	params := struct {
+		Token string `json:"token"`
+	}{
+		Token: token,
+	}
+
+	_, ok := requests.SendAndWait("window/workDoneProgress/create", params, 5*time.Second)
+	if !ok {
+		return nil
+	}
+
+	progress := &WorkDoneProgress{
+		token:  token,
+		output: requests.output,
+	}
+
+	return progress
+}
+
+func (progress *WorkDoneProgress) send(value WorkDoneProgressValue) {
+	if progress == nil {
+		return
+	}
+
+	notification := NotificationMessage[ProgressParams]{
+		JsonRpc: "2.0",
+		Method:  "$/progress",
+		Params: ProgressParams{
+			Token: progress.token,
+			Value: value,
+		},
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		msg := ("error while marshalling '$/progress' notification, " + err.Error())
+		slog.Error(msg, slog.Any("notification", notification))
+		panic(msg)
+	}
+
+	SendToLspClient(progress.output, data)
+}
+
+func (progress *WorkDoneProgress) Begin(title string, message string) {
+	var percentage uint = 0
+	progress.send(WorkDoneProgressValue{Kind: "begin", Title: title, Message: message, Percentage: &percentage})
+}
+
+// 'done' out of 'total' is converted into a percentage, a 'total' of 0 report the message only
+func (progress *WorkDoneProgress) Report(message string, done int, total int) {
+	value := WorkDoneProgressValue{Kind: "report", Message: message}
+
+	if total > 0 {
+		percentage := uint(min(done*100/total, 100))
+		value.Percentage = &percentage
+	}
+
+	progress.send(value)
+}
+
+func (progress *WorkDoneProgress) End(message string) {
+	progress.send(WorkDoneProgressValue{Kind: "end", Message: message})
+}
@@ -0,0 +1,330 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+)
+
+type SignatureHelpOptions struct {
+	TriggerCharacters   []string `json:"triggerCharacters,omitempty"`
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+func newSignatureHelpOptions() *SignatureHelpOptions {
+	return &SignatureHelpOptions{TriggerCharacters: []string{" ", "("}, RetriggerCharacters: []string{"|"}}
+}
+
+type ParameterInformation struct {
+	Label [2]int `json:"label"` // utf-16 offsets within the label of the signature
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation string                 `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature"`
+	ActiveParameter int                    `json:"activeParameter"`
+}
+
+// Signature of the template function called at the cursor (the 'FuncMap' of the Go code, and the libraries enabled by the project),
+// with the argument under the cursor as active parameter
+func ProcessSignatureHelpRequest(data []byte, storage *WorkSpaceStore, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) []byte {
+	var request RequestMessage[TextDocumentPositionParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/signatureHelp' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	muTextFromClient.Lock()
+	content := getMostRecentContent(fileUri, storage, textFromClient)
+	content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, fileUri))
+	_, functions := storage.GetGoSourceOfFile(fileUri)
+	muTextFromClient.Unlock()
+
+	lineIndex := NewLineIndex(content, storage.Client.GetPositionEncoding())
+	position := lineIndex.FromLspPosition(request.Params.Position)
+
+	response := ResponseMessage[*SignatureHelp]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+	}
+
+	if position.Line >= 0 && position.Line < len(lineIndex.lineStarts) {
+		offset := min(lineIndex.lineStarts[position.Line]+position.Character, len(content))
+		response.Result = getSignatureHelp(content, offset, functions)
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessSignatureHelpRequest(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
+// Signature help of the function called at 'offset' of the content (delimiters translated), nil when none of 'functions' is called
+func getSignatureHelp(content []byte, offset int, functions []gosource.TemplateFunction) *SignatureHelp {
+	name, argumentIndex, ok := findCalledFunction(content, offset)
+	if !ok {
+		return nil
+	}
+
+	index := slices.IndexFunc(functions, func(function gosource.TemplateFunction) bool { return function.Name == name })
+	if index < 0 {
+		return nil
+	}
+
+	function := functions[index]
+
+	signature, isVariadic, ok := newSignatureInformation(function)
+	if !ok {
+		return nil
+	}
+
+	// every argument past the variadic parameter belong to it
+	activeParameter := argumentIndex
+	if isVariadic && activeParameter >= len(signature.Parameters) {
+		activeParameter = len(signature.Parameters) - 1
+	}
+
+	return &SignatureHelp{
+		Signatures:      []SignatureInformation{signature},
+		ActiveSignature: 0,
+		ActiveParameter: activeParameter,
+	}
+}
+
+// Label of the signature is the go code without 'func' (eg. 'upper(s string) string').
+// The name of the function is not parsed, it might be a Go keyword (eg. 'default' of Sprig)
+func newSignatureInformation(function gosource.TemplateFunction) (signature SignatureInformation, isVariadic bool, ok bool) {
+	const prefix = "package signature; func _"
+
+	label := strings.TrimPrefix(function.GoCode, "func ")
+	signatureCode, found := strings.CutPrefix(label, function.Name)
+	if !found {
+		return SignatureInformation{}, false, false
+	}
+
+	fset := token.NewFileSet()
+
+	file, err := goparser.ParseFile(fset, "", prefix+signatureCode, goparser.SkipObjectResolution)
+	if err != nil || len(file.Decls) == 0 {
+		return SignatureInformation{}, false, false
+	}
+
+	decl, ok := file.Decls[0].(*ast.FuncDecl)
+	if !ok {
+		return SignatureInformation{}, false, false
+	}
+
+	// offset within the label of a position within the parsed source
+	labelOffset := func(pos token.Pos) int {
+		offset := fset.Position(pos).Offset - len(prefix) + len(function.Name)
+		return len(utf16.Encode([]rune(label[:offset])))
+	}
+
+	signature = SignatureInformation{
+		Label:         label,
+		Documentation: function.Doc,
+		Parameters:    []ParameterInformation{},
+	}
+
+	params := decl.Type.Params.List
+	if len(params) > 0 {
+		_, isVariadic = params[len(params)-1].Type.(*ast.Ellipsis)
+	}
+
+	for _, field := range params {
+		if len(field.Names) == 0 {
+			signature.Parameters = append(signature.Parameters, ParameterInformation{Label: [2]int{labelOffset(field.Pos()), labelOffset(field.End())}})
+			continue
+		}
+
+		// the type is part of the last name of the field only (eg. 'a, b int')
+		for index, name := range field.Names {
+			end := name.End()
+			if index == len(field.Names)-1 {
+				end = field.End()
+			}
+
+			signature.Parameters = append(signature.Parameters, ParameterInformation{Label: [2]int{labelOffset(name.Pos()), labelOffset(end)}})
+		}
+	}
+
+	return signature, isVariadic, true
+}
+
+// Lexical scan of the action holding 'offset', to find the function called by the command under the cursor,
+// and the index of the argument being written (0 for the first argument).
+// The command start after the last '{{', '|' or '(' still open before the cursor, the control keywords ('if', 'range', ...)
+// and the variable declarations ('$x := ') are skipped. Invalid (false) when the command does not start with a function name,
+// or while the name itself is being written
+func findCalledFunction(content []byte, offset int) (name string, argumentIndex int, ok bool) {
+	if offset < 0 || offset > len(content) {
+		return "", 0, false
+	}
+
+	actionStart := bytes.LastIndex(content[:offset], []byte("{{"))
+	if actionStart < 0 || bytes.LastIndex(content[:offset], []byte("}}")) > actionStart {
+		return "", 0, false
+	}
+
+	action := content[actionStart+2 : offset]
+	if len(action) > 0 && action[0] == '-' {
+		action = action[1:]
+	}
+
+	if bytes.HasPrefix(bytes.TrimLeft(action, " \t\r\n"), []byte("/*")) {
+		return "", 0, false
+	}
+
+	// start of the commands still open at the cursor, the last one is under the cursor
+	commandStarts := []int{0}
+
+	for index := 0; index < len(action); index++ {
+		switch char := action[index]; char {
+		case '"', '\'', '`':
+			end := findClosingQuote(action, index)
+			if end < 0 { // the cursor is within the string
+				index = len(action)
+				break
+			}
+
+			index = end
+		case '(':
+			commandStarts = append(commandStarts, index+1)
+		case ')':
+			if len(commandStarts) > 1 {
+				commandStarts = commandStarts[:len(commandStarts)-1]
+			}
+		case '|':
+			commandStarts[len(commandStarts)-1] = index + 1
+		}
+	}
+
+	command := action[commandStarts[len(commandStarts)-1]:]
+	words := splitCommandWords(command)
+
+	if len(words) > 0 && slices.Contains([]string{"if", "else", "range", "with"}, words[0]) {
+		words = words[1:]
+
+		if len(words) > 0 && slices.Contains([]string{"if", "with"}, words[0]) { // 'else if', 'else with'
+			words = words[1:]
+		}
+	}
+
+	// the name of the template is not an argument
+	if len(words) > 0 && (words[0] == "template" || words[0] == "block") {
+		words = words[min(2, len(words)):]
+	}
+
+	if index := slices.IndexFunc(words, func(word string) bool { return word == ":=" || word == "=" }); index >= 0 {
+		words = words[index+1:]
+	}
+
+	isCursorAfterSpace := len(command) > 0 && bytes.ContainsAny(command[len(command)-1:], " \t\r\n")
+
+	if len(words) == 0 || (len(words) == 1 && !isCursorAfterSpace) {
+		return "", 0, false
+	}
+
+	name = words[0]
+	for _, char := range []byte(name) {
+		if !isGoIdentifierByte(char) {
+			return "", 0, false
+		}
+	}
+
+	if ('0' <= name[0] && name[0] <= '9') || slices.Contains([]string{"true", "false", "nil"}, name) {
+		return "", 0, false
+	}
+
+	argumentIndex = len(words) - 1
+	if !isCursorAfterSpace {
+		argumentIndex--
+	}
+
+	return name, argumentIndex, true
+}
+
+// Words of the command separated by spaces, a string literal or a parenthesized pipeline is a single word
+func splitCommandWords(command []byte) []string {
+	var words []string
+
+	for index := 0; index < len(command); {
+		if bytes.ContainsAny(command[index:index+1], " \t\r\n") {
+			index++
+			continue
+		}
+
+		start := index
+		depth := 0
+
+		for ; index < len(command); index++ {
+			char := command[index]
+
+			if depth == 0 && bytes.ContainsAny([]byte{char}, " \t\r\n") {
+				break
+			}
+
+			switch char {
+			case '"', '\'', '`':
+				end := findClosingQuote(command, index)
+				if end < 0 {
+					end = len(command) - 1
+				}
+
+				index = end
+			case '(':
+				depth++
+			case ')':
+				depth = max(depth-1, 0)
+			}
+		}
+
+		words = append(words, string(command[start:index]))
+	}
+
+	return words
+}
+
+// Index of the quote closing the string literal opened at 'start', -1 when the string is not closed
+func findClosingQuote(text []byte, start int) int {
+	quote := text[start]
+
+	for index := start + 1; index < len(text); index++ {
+		switch {
+		case text[index] == '\\' && quote != '`':
+			index++
+		case text[index] == quote:
+			return index
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,84 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+)
+
+func TestFindCalledFunction(t *testing.T) {
+	tests := []struct {
+		content       string // the cursor is at the end
+		name          string
+		argumentIndex int
+		ok            bool
+	}{
+		{content: "{{ default ", name: "default", argumentIndex: 0, ok: true},
+		{content: "{{- default \"none\" ", name: "default", argumentIndex: 1, ok: true},
+		{content: "{{ default \"no ne", name: "default", argumentIndex: 0, ok: true},
+		{content: "{{ .Name | trunc 10 ", name: "trunc", argumentIndex: 1, ok: true},
+		{content: "{{ if eq (len .Items) ", name: "eq", argumentIndex: 1, ok: true},
+		{content: "{{ if eq (len ", name: "len", argumentIndex: 0, ok: true},
+		{content: "{{ $x := dict \"a\" ", name: "dict", argumentIndex: 1, ok: true},
+		{content: "{{ else if hasKey .", name: "hasKey", argumentIndex: 0, ok: true},
+		{content: "{{ range $i, $v := until ", name: "until", argumentIndex: 0, ok: true},
+		{content: "{{ template \"header\" dict ", name: "dict", argumentIndex: 0, ok: true},
+		{content: "{{ defau", ok: false},      // the name is being written
+		{content: "{{ .Name ", ok: false},     // field
+		{content: "{{ $x ", ok: false},        // variable
+		{content: "{{ 12 ", ok: false},        // number
+		{content: "{{ upper }} ", ok: false},  // outside of an action
+		{content: "{{/* go:code ", ok: false}, // comment
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			name, argumentIndex, ok := findCalledFunction([]byte(test.content), len(test.content))
+			if name != test.name || argumentIndex != test.argumentIndex || ok != test.ok {
+				t.Errorf("\n Content: %q \n Expected: %s %d %t \n Got: %s %d %t", test.content, test.name, test.argumentIndex, test.ok, name, argumentIndex, ok)
+			}
+		})
+	}
+}
+
+func TestGetSignatureHelp(t *testing.T) {
+	functions := gosource.GetLibraryFunctions([]string{"sprig"})
+	functions = append(functions, gosource.TemplateFunction{Name: "greet", GoCode: "func greet(any, string) (string, error)"})
+
+	tests := []struct {
+		content         string // the cursor is at the end
+		label           string
+		parameters      [][2]int
+		activeParameter int
+	}{
+		{content: "{{ default ", label: "default(fallback any, given ...any) any", parameters: [][2]int{{8, 20}, {22, 34}}, activeParameter: 0},
+		{content: "{{ default 1 2 3 ", label: "default(fallback any, given ...any) any", parameters: [][2]int{{8, 20}, {22, 34}}, activeParameter: 1},
+		{content: "{{ substr 0 ", label: "substr(start int, end int, s string) string", parameters: [][2]int{{7, 16}, {18, 25}, {27, 35}}, activeParameter: 1},
+		{content: "{{ greet . ", label: "greet(any, string) (string, error)", parameters: [][2]int{{6, 9}, {11, 17}}, activeParameter: 1},
+		{content: "{{ unknown ", label: ""},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := getSignatureHelp([]byte(test.content), len(test.content), functions)
+			if got == nil {
+				if test.label != "" {
+					t.Errorf("\n Content: %q \n Expected: %s \n Got: nil", test.content, test.label)
+				}
+
+				return
+			}
+
+			var parameters [][2]int
+			for _, parameter := range got.Signatures[0].Parameters {
+				parameters = append(parameters, parameter.Label)
+			}
+
+			if got.Signatures[0].Label != test.label || !slices.Equal(parameters, test.parameters) || got.ActiveParameter != test.activeParameter {
+				t.Errorf("\n Content: %q \n Expected: %s %v %d \n Got: %s %v %d", test.content, test.label, test.parameters, test.activeParameter, got.Signatures[0].Label, parameters, got.ActiveParameter)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+)
+
+// Maximum count of names suggested for an unknown template name
+const MAX_TEMPLATE_NAME_SUGGESTIONS = 3
+
+// 'ExecuteTemplate()' call of the Go code naming a template that no file of its template set define
+type UnknownTemplateName struct {
+	Uri         string // go file
+	Range       Range  // string literal, quotes included
+	Name        string
+	SetId       string   // empty when the template set of the call is unknown
+	Suggestions []string // closest names defined within the template set, best first
+}
+
+func (unknown *UnknownTemplateName) Message() string {
+	msg := "template '" + unknown.Name + "' is not defined by any template of the workspace"
+	if unknown.SetId != "" {
+		msg = "template '" + unknown.Name + "' is not defined by the templates parsed at '" + unknown.SetId + "'"
+	}
+
+	if len(unknown.Suggestions) > 0 {
+		msg += ", did you mean '" + unknown.Suggestions[0] + "' ?"
+	}
+
+	return msg
+}
+
+// Template names that a call of the set can execute: the base name of the files, and the '{{ define }}'/'{{ block }}' names,
+// along the name given to 'template.New()'. Every template file of the workspace is used when the set is unknown (empty id).
+// Return nil when no template file belong to the set, in which case nothing can be checked.
+// Must be called while holding 'muTextFromClient', like any access to 'GoSource'
+func (storage *WorkSpaceStore) GetTemplateNamesOfSet(setId string) []string {
+	var set *gosource.TemplateSet
+
+	if setId != "" {
+		index := slices.IndexFunc(storage.GoSource.Sets, func(set gosource.TemplateSet) bool { return set.Id == setId })
+		if index < 0 {
+			return nil
+		}
+
+		set = &storage.GoSource.Sets[index]
+	}
+
+	var names []string
+
+	for uri, content := range storage.RawFiles {
+		if set != nil && !set.Contains(storage.getFilePath(uri)) {
+			continue
+		}
+
+		names = append(names, path.Base(uri))
+
+		content = TranslateDelimiters(content, storage.Config.GetDelimiters(storage.RootUri, uri))
+		for _, scope := range findTemplateScopes(content)[1:] {
+			names = append(names, scope.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	if set != nil && set.RootName != "" {
+		names = append(names, set.RootName)
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+// Closest names to 'name' (edit distance), best first.
+// Names too far away to be a typo are left out
+func SuggestTemplateNames(name string, knownNames []string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	maxDistance := max(2, len([]rune(name))/3)
+	suggestions := make([]suggestion, 0, len(knownNames))
+
+	for _, knownName := range knownNames {
+		distance := levenshteinDistance(strings.ToLower(name), strings.ToLower(knownName))
+		if distance <= maxDistance && knownName != name {
+			suggestions = append(suggestions, suggestion{name: knownName, distance: distance})
+		}
+	}
+
+	slices.SortStableFunc(suggestions, func(a, b suggestion) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+
+		return strings.Compare(a.name, b.name)
+	})
+
+	names := make([]string, 0, min(len(suggestions), MAX_TEMPLATE_NAME_SUGGESTIONS))
+	for _, suggestion := range suggestions[:min(len(suggestions), MAX_TEMPLATE_NAME_SUGGESTIONS)] {
+		names = append(names, suggestion.name)
+	}
+
+	return names
+}
+
+// Count of rune insertions, deletions and substitutions needed to turn 'a' into 'b'
+func levenshteinDistance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	previous := make([]int, len(target)+1)
+	current := make([]int, len(target)+1)
+
+	for index := range previous {
+		previous[index] = index
+	}
+
+	for i := range source {
+		current[0] = i + 1
+
+		for j := range target {
+			cost := 1
+			if source[i] == target[j] {
+				cost = 0
+			}
+
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(target)]
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      struct {
+		Diagnostics []Diagnostic `json:"diagnostics"`
+		Only        []string     `json:"only"`
+	} `json:"context"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+type CodeAction struct {
+	Title       string        `json:"title"`
+	Kind        string        `json:"kind"`
+	Diagnostics []Diagnostic  `json:"diagnostics,omitempty"`
+	IsPreferred bool          `json:"isPreferred,omitempty"`
+	Edit        WorkspaceEdit `json:"edit"`
+}
+
+const codeActionKindQuickFix = "quickfix"
+
+// Quick fixes replacing the unknown template names within the requested range by the suggested names
+func ProcessCodeActionRequest(data []byte, unknownNames []UnknownTemplateName) []byte {
+	var request RequestMessage[CodeActionParams]
+
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		msg := ("error while unmarshalling data during 'textDocument/codeAction' request, " + err.Error())
+		slog.Error(msg, slog.String("received_req", string(data)))
+		panic(msg)
+	}
+
+	response := ResponseMessage[[]CodeAction]{
+		JsonRpc: request.JsonRpc,
+		Id:      request.Id,
+		Result:  []CodeAction{},
+	}
+
+	fileUri := request.Params.TextDocument.Uri
+	if unescaped, err := url.PathUnescape(fileUri); err == nil {
+		fileUri = unescaped
+	}
+
+	only := request.Params.Context.Only
+	isQuickFixWanted := len(only) == 0 || slices.ContainsFunc(only, func(kind string) bool {
+		return kind == codeActionKindQuickFix || kind == ""
+	})
+
+	for _, unknown := range unknownNames {
+		if !isQuickFixWanted || unknown.Uri != fileUri || !isRangeOverlapping(unknown.Range, request.Params.Range) {
+			continue
+		}
+
+		var diagnostics []Diagnostic
+		for _, diagnostic := range request.Params.Context.Diagnostics {
+			if diagnostic.Range == unknown.Range {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+
+		for index, suggestion := range unknown.Suggestions {
+			action := CodeAction{
+				Title:       "Change template name to '" + suggestion + "'",
+				Kind:        codeActionKindQuickFix,
+				Diagnostics: diagnostics,
+				IsPreferred: index == 0,
+				Edit: WorkspaceEdit{
+					Changes: map[string][]TextEdit{
+						request.Params.TextDocument.Uri: {{Range: unknown.Range, NewText: strconv.Quote(suggestion)}},
+					},
+				},
+			}
+
+			response.Result = append(response.Result, action)
+		}
+	}
+
+	responseText, err := json.Marshal(response)
+	if err != nil {
+		msg := ("Error while marshalling ProcessCodeActionRequest(): " + err.Error())
+		slog.Error(msg)
+		panic(msg)
+	}
+
+	return responseText
+}
+
+// Ranges touching each other overlap, since the cursor alone is sent as an empty range
+func isRangeOverlapping(a Range, b Range) bool {
+	isBefore := func(x Position, y Position) bool {
+		return x.Line < y.Line || (x.Line == y.Line && x.Character < y.Character)
+	}
+
+	return !isBefore(a.End, b.Start) && !isBefore(b.End, a.Start)
+}
@@ -0,0 +1,104 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+
+	"github.com/yayolande/gota/lexer"
+)
+
+func TestSuggestTemplateNames(t *testing.T) {
+	knownNames := []string{"header", "footer", "home.html", "home.tmpl", "layout.html", "Header"}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "heder", want: []string{"Header", "header"}},
+		{name: "hom.html", want: []string{"home.html"}},
+		{name: "layout", want: []string{}},
+		{name: "sidebar", want: []string{}},
+		{name: "header", want: []string{"Header"}},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := SuggestTemplateNames(test.name, knownNames)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n Name: %q \n Expected: %q \n Got: %q", test.name, test.want, got)
+			}
+		})
+	}
+}
+
+func TestGetTemplateNamesOfSet(t *testing.T) {
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Config:   DefaultProjectConfig([]string{"html"}),
+		RawFiles: map[string][]byte{
+			"file:///app/views/home.html":  []byte(`{{ define "header" }}{{ end }}{{ block "content" . }}{{ end }}`),
+			"file:///app/mail/signup.html": []byte(`{{ define "body" }}{{ end }}`),
+		},
+		GoSource: gosource.Discovery{
+			Sets: []gosource.TemplateSet{{Id: "main.go:8", Patterns: []string{"/app/views/*.html"}, RootName: "base"}},
+		},
+	}
+
+	tests := []struct {
+		setId string
+		want  []string
+	}{
+		{setId: "main.go:8", want: []string{"base", "content", "header", "home.html"}},
+		{setId: "", want: []string{"body", "content", "header", "home.html", "signup.html"}},
+		{setId: "other.go:1", want: nil},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			got := storage.GetTemplateNamesOfSet(test.setId)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("\n Set: %q \n Expected: %q \n Got: %q", test.setId, test.want, got)
+			}
+		})
+	}
+}
+
+func TestFindTemplateDefinition(t *testing.T) {
+	storage := &WorkSpaceStore{
+		RootPath: "/app",
+		RootUri:  "file:///app",
+		Config:   DefaultProjectConfig([]string{"html"}),
+		RawFiles: map[string][]byte{
+			"file:///app/views/home.html": []byte("<h1>home</h1>\n  {{- define \"header\" }}\n{{ end }}"),
+			"file:///app/mail/base.html":  []byte(`{{ define "header" }}{{ end }}`),
+		},
+		GoSource: gosource.Discovery{
+			Sets: []gosource.TemplateSet{{Id: "main.go:8", Patterns: []string{"/app/views/*.html"}}},
+		},
+	}
+
+	tests := []struct {
+		setId string
+		name  string
+		uri   string
+		reach lexer.Range
+	}{
+		{setId: "main.go:8", name: "header", uri: "file:///app/views/home.html", reach: lexer.Range{Start: lexer.Position{Line: 1, Character: 2}, End: lexer.Position{Line: 1, Character: 24}}},
+		{setId: "", name: "header", uri: "file:///app/mail/base.html", reach: lexer.Range{End: lexer.Position{Character: 21}}},
+		{setId: "main.go:8", name: "home.html", uri: "file:///app/views/home.html"},
+		{setId: "main.go:8", name: "base.html"},
+	}
+
+	for count, test := range tests {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			uri, reach := storage.FindTemplateDefinition(test.setId, test.name)
+			if uri != test.uri || reach != test.reach {
+				t.Errorf("\n Template: %q \n Expected: %s %+v \n Got: %s %+v", test.name, test.uri, test.reach, uri, reach)
+			}
+		})
+	}
+}
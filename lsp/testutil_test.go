@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Write the files (relative to the root, separated by '/') into a temporary workspace, and return its root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	rootPath := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(rootPath, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rootPath
+}
+
+// Storage of a workspace in the given mode, the files are relative to the root.
+// The preludes (eg. the values of the Helm charts) are routed like the scan does, see 'ProjectConfig.IsPreludePath()'
+func newModeTestStorage(mode string, rootPath string, files map[string]string) *WorkSpaceStore {
+	config := DefaultProjectConfig([]string{"html", "tmpl"})
+	config.Mode = mode
+
+	storage := &WorkSpaceStore{
+		RootPath: rootPath,
+		RootUri:  "file://" + rootPath,
+		Config:   config,
+		RawFiles: make(map[string][]byte),
+		Preludes: make(map[string][]byte),
+	}
+
+	for relativePath, content := range files {
+		uri := storage.RootUri + "/" + relativePath
+
+		if config.IsPreludePath(relativePath) {
+			storage.Preludes[uri] = []byte(content)
+			continue
+		}
+
+		storage.RawFiles[uri] = []byte(content)
+	}
+
+	return storage
+}
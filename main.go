@@ -3,18 +3,28 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go/token"
 
+	"errors"
+	"io"
+	"io/fs"
 	"log/slog"
 	"maps"
+	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"encoding/json"
 	"net/url"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 
+	"github.com/yayolande/go-template-lsp/gosource"
 	"github.com/yayolande/go-template-lsp/lsp"
 
 	"github.com/yayolande/gota"
@@ -46,10 +56,20 @@ type requestCounter struct {
 		DidOpen   int
 		DidChange int
 	}
-	FoldingRange int
-	Definition   int
-	Hover        int
-	Other        int
+	Workspace struct {
+		DidChangeWatchedFiles     int
+		DidChangeWorkspaceFolders int
+		DidChangeConfiguration    int
+	}
+	FoldingRange   int
+	CodeAction     int
+	Definition     int
+	Hover          int
+	Completion     int
+	SemanticTokens int
+	SignatureHelp  int
+	ExecuteCommand int
+	Other          int
 }
 
 var TARGET_FILE_EXTENSIONS []string = []string{
@@ -60,11 +80,16 @@ var TARGET_FILE_EXTENSIONS []string = []string{
 var SERVER_NAME string = "Go Template LSP"
 var SERVER_VERSION string = "0.4.1"
 var SERVER_BUILD_DATE string = "2026/04/04 20:00"
-var serverCounter requestCounter = requestCounter{}
+
+// Quiet time waited after a change of a go file before discovering the template sets again,
+// since the go code is type checked as a whole and the editor send a change on every key stroke
+var GO_SOURCE_SETTLE_DELAY time.Duration = 300 * time.Millisecond
 
 func main() {
 	// 1. Parse CLI arguments
 	isversionFlagEnabled := flag.Bool("version", false, "print the LSP version")
+	listenAddress := flag.String("listen", "", "run as a daemon accepting LSP sessions on a socket (eg. 'tcp:127.0.0.1:7400' or 'unix:/tmp/go-template-lsp.sock')")
+	pipePath := flag.String("pipe", "", "run as a daemon accepting LSP sessions on a named pipe (unix domain socket) located at this path")
 	flag.Parse()
 
 	if *isversionFlagEnabled { // print LSP version then exit
@@ -74,21 +99,187 @@ func main() {
 
 	// 2. Start LSP
 	configureLogging()
-	scanner := lsp.ReceiveInput(os.Stdin)
 
-	// ******************************************************************************
-	// WARNING: In under no cirscumstance the 4 variable below should be re-assnigned
-	// Otherwise, a nasty bug will appear (value not synced with the rest of the app)
-	// ******************************************************************************
+	if *listenAddress != "" && *pipePath != "" {
+		fmt.Fprintln(os.Stderr, "'-listen' and '-pipe' flags cannot be used at the same time")
+		os.Exit(2)
+	}
+
+	if *listenAddress != "" || *pipePath != "" {
+		network, address, err := parseListenAddress(*listenAddress)
+		if *pipePath != "" {
+			network, address, err = "unix", *pipePath, nil
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+
+		err = serveDaemon(network, address)
+		if err != nil {
+			msg := "error while running LSP daemon: " + err.Error()
+			slog.Error(msg)
+			panic(msg)
+		}
+
+		return
+	}
+
+	// the process is the only session, a crashed analysis end it
+	err := startLspSession(os.Stdin, os.Stdout, func() { os.Exit(1) })
+	if err != nil {
+		msg := "error while closing LSP: " + err.Error()
+		slog.Error(msg)
+		panic(msg)
+	}
+}
+
+// Split the '-listen' flag value into a network and an address understood by 'net.Listen()'.
+// Accepted values are 'tcp:host:port' and 'unix:/path/to/socket'
+func parseListenAddress(value string) (network string, address string, err error) {
+	network, address, found := strings.Cut(value, ":")
+	if !found || address == "" {
+		return "", "", errors.New("malformated '-listen' value, expected 'tcp:host:port' or 'unix:/path/to/socket' but got '" + value + "'")
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return network, address, nil
+	}
+
+	return "", "", errors.New("unsupported network '" + network + "' for '-listen', only 'tcp' and 'unix' are supported")
+}
+
+// Long-lived server mode. Every accepted connection is an independent LSP session,
+// which let editors (or a debugger) attach and detach without restarting the process.
+// Sessions do not share anything, every one of them parse the workspace on its own
+func serveDaemon(network string, address string) error {
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	defer listener.Close()
+
+	slog.Info("lsp daemon listening",
+		slog.String("network", network),
+		slog.String("address", listener.Addr().String()),
+	)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			// a request crashing its session must not take down the daemon and the other sessions along it
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					slog.Error("lsp session crashed, its connection is closed",
+						slog.Any("panic", recovered),
+						slog.String("remote_address", conn.RemoteAddr().String()),
+						slog.String("stack", string(debug.Stack())),
+					)
+				}
+			}()
 
-	storage := &workSpaceStore{}
+			slog.Info("lsp session opened", slog.String("remote_address", conn.RemoteAddr().String()))
 
-	rootPathNotication := make(chan string, 2)
-	textChangedNotification := make(chan bool, 2)
-	textFromClient := make(map[string][]byte)
-	muTextFromClient := new(sync.Mutex)
+			err := startLspSession(conn, conn, func() { _ = conn.Close() })
+			if err != nil {
+				slog.Error("lsp session closed with error: "+err.Error(), slog.String("remote_address", conn.RemoteAddr().String()))
+				return
+			}
+
+			slog.Info("lsp session closed", slog.String("remote_address", conn.RemoteAddr().String()))
+		}(conn)
+	}
+}
 
-	go ProcessDiagnosticNotification(storage, rootPathNotication, textChangedNotification, textFromClient, muTextFromClient)
+// Remove the socket left by a previous daemon. Any other file at that path is an error, most likely a mistyped '-pipe'
+func removeStaleSocket(address string) error {
+	info, err := os.Lstat(address)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.New("'" + address + "' already exists and is not a socket, it is left untouched")
+	}
+
+	return os.Remove(address)
+}
+
+// Handle a whole LSP session, from 'initialize' to 'exit', over 'input' and 'output'.
+// Return once the client exit or the connection is closed. 'closeSession' end the session from another goroutine
+// (eg. crash of a diagnostic handler), it must make the reading of 'input' fail
+func startLspSession(input io.Reader, output io.Writer, closeSession func()) error {
+	scanner := lsp.ReceiveInput(input)
+	serverCounter := requestCounter{}
+	serverRequests := lsp.NewServerRequests(output)
+
+	var client *lsp.ClientCapabilities = nil
+	folders := make(map[string]*workspaceFolder) // key: folder uri
+	openedDocuments := lsp.NewOpenedDocuments()
+
+	// When the client do not provide any root, the first opened file is used to find one
+	var isRootDiscoveryPending bool = false
+
+	// Client settings, each folder then apply its own project file on top of it
+	editorConfig := lsp.DefaultProjectConfig(TARGET_FILE_EXTENSIONS)
+
+	// Registrations depending on the file extensions, replaced whenever the extensions change
+	var registration *lsp.RegistrationParams = nil
+	var registeredExtensions []string = nil
+
+	// Response of the 'workspace/configuration' request pulling the client settings, applied as soon as it arrive.
+	// Only the latest pull is kept, the response of a pull sent before it is stale and ignored
+	var pendingConfiguration <-chan lsp.ResponseMessage[json.RawMessage] = nil
+
+	pullEditorSettings := func() {
+		if pendingConfiguration != nil {
+			slog.Info("'workspace/configuration' request superseded by a new one, its response will be ignored")
+		}
+
+		pendingConfiguration = serverRequests.Send("workspace/configuration", lsp.NewConfigurationParams())
+	}
+
+	// every folder is reloaded with the new client settings, nothing change when they are invalid or the same as before
+	applyEditorSettings := func(settings json.RawMessage, source string) {
+		previousConfig := editorConfig
+		editorConfig = loadEditorConfig(editorConfig, settings, source, output)
+
+		if editorConfig.Equal(previousConfig) {
+			editorConfig = previousConfig
+			return
+		}
+
+		for _, uri := range mapToKeys(folders) {
+			reloadWorkspaceFolder(folders, uri, client, editorConfig, output, serverRequests, closeSession)
+		}
+
+		registration, registeredExtensions = updateRegistration(registration, registeredExtensions, client, editorConfig, folders, serverRequests)
+	}
+
+	defer func() {
+		for uri := range folders {
+			closeWorkspaceFolder(folders, uri, false)
+		}
+
+		serverRequests.Close()
+	}()
 
 	var request lsp.RequestMessage[any]
 	var response []byte
@@ -105,21 +296,53 @@ func main() {
 		slog.String("server_name", SERVER_NAME),
 		slog.String("server_version", SERVER_VERSION),
 	)
-	defer slog.Info("shutting down lsp server", GetServerGroupLogging(storage, serverCounter, request, textFromClient))
+	defer slog.Info("shutting down lsp server", GetServerGroupLogging(folders, openedDocuments, serverCounter, request))
 
 	for scanner.Scan() {
 		data := scanner.Bytes()
 
 		// TODO: All over the code base, replace 'json' module by a custom made 'stringer' tool
 		// because it is more performat
-		json.Unmarshal(data, &request)
+		request = lsp.RequestMessage[any]{}
+
+		err := json.Unmarshal(data, &request)
+		if err != nil {
+			// malformated messages are dropped, but the session goes on.
+			// The client is told, otherwise it would wait forever when the message was a request
+			slog.Warn("unable to unmarshal message from client, message dropped. "+err.Error(),
+				slog.String("received_message", string(data)),
+			)
+
+			lsp.SendToLspClient(output, lsp.ProcessParseError(err.Error()))
+			continue
+		}
+
+		if request.Method == "" { // response to a request sent by the server
+			serverRequests.ProcessResponseFromClient(data)
+
+			select {
+			case pulled, ok := <-pendingConfiguration: // never ready while nil
+				pendingConfiguration = nil
+
+				if !ok || pulled.Error != nil {
+					break
+				}
+
+				if settings := lsp.ProcessConfigurationResponse(pulled.Result); settings != nil {
+					applyEditorSettings(settings, "workspace/configuration")
+				}
+			default:
+			}
+
+			continue
+		}
 
 		if isExiting {
 			if request.Method == "exit" {
 				break
 			} else {
 				response = lsp.ProcessIllegalRequestAfterShutdown(request.JsonRpc, request.Id)
-				lsp.SendToLspClient(os.Stdout, response)
+				lsp.SendToLspClient(output, response)
 			}
 
 			continue
@@ -127,22 +350,39 @@ func main() {
 
 		// TODO: behavior of the 'method' do not respect the LSP spec. For instance 'initialize' must only happen once
 		// However there is nothing stoping a rogue program to 'initialize' more than once, or even to not 'initialize' at all
-		slog.Info("request "+request.Method, GetServerGroupLogging(storage, serverCounter, request, textFromClient))
+		slog.Info("request "+request.Method, GetServerGroupLogging(folders, openedDocuments, serverCounter, request))
 
 		switch request.Method {
 		case "initialize":
 			serverCounter.Initialize++
-			var rootURI string
-			response, rootURI = lsp.ProcessInitializeRequest(data, SERVER_NAME, SERVER_VERSION)
+			var workspaces []lsp.WorkspaceFolder
+			response, workspaces, client = lsp.ProcessInitializeRequest(data, SERVER_NAME, SERVER_VERSION)
 
-			notifyTheRootPath(rootPathNotication, rootURI)
-			rootPathNotication = nil
-			isRequestResponse = true
+			// the response must reach the client before the diagnostic handlers start sending their own requests
+			lsp.SendToLspClient(output, response)
+
+			editorConfig = loadEditorConfig(editorConfig, lsp.GetInitializationOptions(data), "initializationOptions", output)
+
+			for _, workspace := range workspaces {
+				openWorkspaceFolder(folders, workspace, client, openedDocuments, editorConfig, output, serverRequests, closeSession)
+			}
+
+			isRootDiscoveryPending = len(folders) == 0
+			isRequestResponse = false
 
 		case "initialized":
 			serverCounter.Initialized++
 			isRequestResponse = false
-			lsp.ProcessInitializedNotificatoin(data)
+
+			registeredExtensions = collectFileExtensions(editorConfig, folders)
+			registration = lsp.ProcessInitializedNotificatoin(data, client, registeredExtensions)
+			if registration != nil {
+				_ = serverRequests.Send("client/registerCapability", registration)
+			}
+
+			if client.SupportsConfigurationRequest() {
+				pullEditorSettings()
+			}
 		case "shutdown":
 			// TODO: close opened buffers and stop task analysis
 			serverCounter.Shutdown++
@@ -150,41 +390,246 @@ func main() {
 			isRequestResponse = true
 			response = lsp.ProcessShutdownRequest(request.JsonRpc, request.Id)
 
+		case "workspace/didChangeWorkspaceFolders":
+			serverCounter.Workspace.DidChangeWorkspaceFolders++
+			isRequestResponse = false
+			added, removed := lsp.ProcessDidChangeWorkspaceFoldersNotification(data)
+
+			for _, workspace := range removed {
+				removeWorkspaceFolder(folders, workspace.Uri, client, editorConfig, output, serverRequests, closeSession)
+			}
+
+			for _, workspace := range added {
+				openWorkspaceFolder(folders, workspace, client, openedDocuments, editorConfig, output, serverRequests, closeSession)
+			}
+
+			registration, registeredExtensions = updateRegistration(registration, registeredExtensions, client, editorConfig, folders, serverRequests)
+		case "workspace/didChangeConfiguration":
+			serverCounter.Workspace.DidChangeConfiguration++
+			isRequestResponse = false
+
+			// clients using the pull model send no settings, and expect a 'workspace/configuration' request instead
+			settings := lsp.ProcessDidChangeConfigurationNotification(data)
+			if settings == nil {
+				if client.SupportsConfigurationRequest() {
+					pullEditorSettings()
+				}
+
+				break
+			}
+
+			applyEditorSettings(settings, "workspace/didChangeConfiguration")
 		case "textDocument/didOpen":
 			serverCounter.TextDocument.DidOpen++
 			isRequestResponse = false
-			fileURI, fileContent = lsp.ProcessDidOpenTextDocumentNotification(data)
+			fileURI, fileContent = lsp.ProcessDidOpenTextDocumentNotification(data, openedDocuments)
+
+			if isRootDiscoveryPending && fileURI != "" {
+				isRootDiscoveryPending = false
+
+				if rootUri := findGoModuleRoot(fileURI); rootUri != "" {
+					openWorkspaceFolder(folders, lsp.WorkspaceFolder{Uri: rootUri, Name: "go-module"}, client, openedDocuments, editorConfig, output, serverRequests, closeSession)
+					registration, registeredExtensions = updateRegistration(registration, registeredExtensions, client, editorConfig, folders, serverRequests)
+				}
+			}
 
-			insertTextDocumentToDiagnostic(fileURI, fileContent, textChangedNotification, textFromClient, muTextFromClient)
+			// rootless mode, the document is analysed on its own. Go files and prelude files are never analysed as templates
+			if fileURI != "" && findWorkspaceFolder(folders, fileURI) == nil && !isGoSourceFile(fileURI) && !strings.HasSuffix(fileURI, "."+lsp.PRELUDE_FILE_EXTENSION) {
+				openSingleFileWorkspace(folders, fileURI, client, openedDocuments, editorConfig, output, serverRequests, closeSession)
+			}
+
+			insertTextDocumentToWorkspace(folders, fileURI, fileContent)
 		case "textDocument/didChange":
 			serverCounter.TextDocument.DidChange++
 			isRequestResponse = false
-			fileURI, fileContent = lsp.ProcessDidChangeTextDocumentNotification(data)
+			fileURI, fileContent = lsp.ProcessDidChangeTextDocumentNotification(data, openedDocuments)
 
-			insertTextDocumentToDiagnostic(fileURI, fileContent, textChangedNotification, textFromClient, muTextFromClient)
+			insertTextDocumentToWorkspace(folders, fileURI, fileContent)
 		case "textDocument/didClose":
 			serverCounter.TextDocument.DidClose++
-			// TODO: Not sure what to do
+			isRequestResponse = false
+			fileURI, _ = lsp.ProcessDidCloseTextDocumentNotification(data, openedDocuments)
+
+			// a document analysed on its own is forgotten once closed
+			if folder := findWorkspaceFolder(folders, fileURI); folder != nil && folder.isSingleFile {
+				closeWorkspaceFolder(folders, folder.Uri, true)
+				break
+			}
+
+			// the unsaved changes of the editor are discarded, the disk is the owner of the file again.
+			// Files that never existed on disk (eg. never saved new file) are removed from the workspace
+			fileURI, fileContent = getFileChangedOnDisk(lsp.FileEvent{Uri: fileURI, Type: lsp.FileChanged}, findWorkspaceFolder(folders, fileURI))
+			insertTextDocumentToWorkspace(folders, fileURI, fileContent)
+		case "workspace/didChangeWatchedFiles":
+			serverCounter.Workspace.DidChangeWatchedFiles++
+			isRequestResponse = false
+			events := lsp.ProcessDidChangeWatchedFilesNotification(data)
+
+			for _, event := range events {
+				if folder := findFolderOfProjectConfig(folders, event.Uri); folder != nil { // hot reload of the config
+					reloadWorkspaceFolder(folders, folder.Uri, client, editorConfig, output, serverRequests, closeSession)
+					registration, registeredExtensions = updateRegistration(registration, registeredExtensions, client, editorConfig, folders, serverRequests)
+					continue
+				}
+
+				fileURI, fileContent = getFileChangedOnDisk(event, findWorkspaceFolder(folders, event.Uri))
+				insertTextDocumentToWorkspace(folders, fileURI, fileContent)
+			}
 		case "textDocument/hover":
 			serverCounter.Hover++
 			isRequestResponse = true
-			response = lsp.ProcessHoverRequest(data, storage.OpenedFilesAnalyzed)
+
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil || !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) || !isFileAnalysed(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			folder.muTextFromClient.Lock()
+			_, functions := folder.storage.GetGoSourceOfFile(lsp.GetTextDocumentUri(data))
+			folder.muTextFromClient.Unlock()
+
+			hoverGoCode := func(uri string, position lexer.Position) (string, lexer.Range) {
+				folder.muTextFromClient.Lock()
+				defer folder.muTextFromClient.Unlock()
+
+				return folder.storage.GoCodeHover(uri, folder.storage.RawFiles[uri], position)
+			}
+
+			response = lsp.ProcessHoverRequest(data, folder.storage.OpenedFilesAnalyzed, folder.storage.RawFiles, client, functions, hoverGoCode)
 		case "textDocument/definition":
 			serverCounter.Definition++
 			isRequestResponse = true
-			response, _ = lsp.ProcessGoToDefinition(data, storage.OpenedFilesAnalyzed, storage.RawFiles)
 
-			// insertTextDocumentToDiagnostic(fileURI, fileContent, textChangedNotification, textFromClient, muTextFromClient)
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			// from the template name of an 'ExecuteTemplate()' call to the template
+			if isGoSourceFile(lsp.GetTextDocumentUri(data)) {
+				folder.muTextFromClient.Lock()
+				templateCallLinks := folder.storage.TemplateCallLinks
+				folder.muTextFromClient.Unlock()
+
+				response = lsp.ProcessGoSourceDefinition(data, templateCallLinks)
+				break
+			}
+
+			if !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			// from the name given to 'partial', '{{ block }}' or '{{ define }}' to the template found by the Hugo lookup,
+			// or from the name given to 'include' to the '{{ define }}' of the Helm chart
+			if folder.storage.Config.IsHugoMode() || folder.storage.Config.IsHelmMode() {
+				folder.muTextFromClient.Lock()
+				modeLinks := folder.storage.FindHugoTemplateLinks(lsp.GetTextDocumentUri(data))
+				modeLinks = append(modeLinks, folder.storage.FindHelmTemplateLinks(lsp.GetTextDocumentUri(data))...)
+				folder.muTextFromClient.Unlock()
+
+				if links := lsp.FindTemplateCallLinksAt(data, modeLinks); len(links) > 0 {
+					response = lsp.ProcessGoSourceDefinition(data, links)
+					break
+				}
+			}
+
+			if !isFileAnalysed(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			findInjectedGoDeclaration := func(uri string, position lexer.Position) token.Position {
+				folder.muTextFromClient.Lock()
+				defer folder.muTextFromClient.Unlock()
+
+				return folder.storage.FindInjectedGoDeclaration(uri, position)
+			}
+
+			// the go files are read from disk, the editor buffer might be slightly ahead
+			toGoLocation := func(position token.Position) (lsp.Location, bool) {
+				if !position.IsValid() {
+					return lsp.Location{}, false
+				}
+
+				goFiles := newGoFileIndexes(nil, client.GetPositionEncoding())
+				return lsp.Location{Uri: filePathToUri(position.Filename), Range: goFiles.toLspRange(position, position)}, true
+			}
+
+			response, _ = lsp.ProcessGoToDefinition(data, folder.storage.OpenedFilesAnalyzed, folder.storage.RawFiles, client, findInjectedGoDeclaration, toGoLocation)
 		case "textDocument/foldingRange":
 			serverCounter.FoldingRange++
 			isRequestResponse = true
-			response, _ = lsp.ProcessFoldingRangeRequest(data, storage, textFromClient, muTextFromClient)
+
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil || !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			response, _ = lsp.ProcessFoldingRangeRequest(data, folder.storage, folder.textFromClient, folder.muTextFromClient)
+		case "textDocument/completion":
+			serverCounter.Completion++
+			isRequestResponse = true
+
+			// only the go code of the 'go:code' comments is completed
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil || !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			response = lsp.ProcessCompletionRequest(data, folder.storage, folder.textFromClient, folder.muTextFromClient)
+		case "textDocument/semanticTokens/full":
+			serverCounter.SemanticTokens++
+			isRequestResponse = true
+
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil || !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			response = lsp.ProcessSemanticTokensRequest(data, folder.storage, folder.textFromClient, folder.muTextFromClient)
+		case "textDocument/signatureHelp":
+			serverCounter.SignatureHelp++
+			isRequestResponse = true
+
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil || !isTemplateFile(folder, lsp.GetTextDocumentUri(data)) {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			response = lsp.ProcessSignatureHelpRequest(data, folder.storage, folder.textFromClient, folder.muTextFromClient)
+		case "textDocument/codeAction":
+			serverCounter.CodeAction++
+			isRequestResponse = true
+
+			folder := findWorkspaceFolder(folders, lsp.GetTextDocumentUri(data))
+			if folder == nil {
+				response = lsp.ProcessRequestWithoutResult(request.JsonRpc, request.Id)
+				break
+			}
+
+			folder.muTextFromClient.Lock()
+			unknownTemplateNames := folder.storage.UnknownTemplateNames
+			folder.muTextFromClient.Unlock()
+
+			response = lsp.ProcessCodeActionRequest(data, unknownTemplateNames)
+		case "workspace/executeCommand":
+			serverCounter.ExecuteCommand++
+			isRequestResponse = true
+
+			response = lsp.ProcessExecuteCommandRequest(data, collectWorkspaceStatus(folders))
 		default:
 			serverCounter.Other++
 		}
 
 		if isRequestResponse {
-			lsp.SendToLspClient(os.Stdout, response)
+			lsp.SendToLspClient(output, response)
 
 			// INFO: This is only for debug purpose
 			res := lsp.ResponseMessage[any]{}
@@ -193,10 +638,8 @@ func main() {
 				slog.Group("server",
 					slog.String("name", SERVER_NAME),
 					slog.String("version", SERVER_VERSION),
-					slog.String("root_path", storage.RootPath),
+					slog.Any("workspace_folders", mapToKeys(folders)),
 					slog.Any("request_counter", serverCounter),
-					slog.Any("open_files", mapToKeys(storage.RawFiles)),
-					slog.Any("files_waiting_processing", mapToKeys(textFromClient)),
 					slog.Any("last_response", res),
 				),
 			)
@@ -206,17 +649,80 @@ func main() {
 		isRequestResponse = false
 	}
 
-	if scanner.Err() != nil {
-		msg := "error while closing LSP: " + scanner.Err().Error()
-		slog.Error(msg)
-		panic(msg)
+	return scanner.Err()
+}
+
+// Convert a file system event into the content to analyse, read from disk.
+// A 'nil' content mean the file must be removed from the workspace.
+// Return an empty 'uri' when the event must be ignored (eg. file owned by the editor, or excluded by the config of the 'folder')
+func getFileChangedOnDisk(event lsp.FileEvent, folder *workspaceFolder) (uri string, content []byte) {
+	if folder == nil || folder.isSingleFile {
+		return "", nil
+	}
+
+	// go files define the template sets, see 'discoverTemplateSets()'. The preludes are shared by the templates
+	config := folder.storage.Config
+	if !config.IsFileIncluded(folder.Uri, event.Uri) && !config.IsPreludeFile(folder.Uri, event.Uri) && !isGoSourceFile(event.Uri) {
+		return "", nil
+	}
+
+	// the editor buffer is more recent than the disk, the analysis will
+	// catch up with the disk once the editor close the file
+	if folder.storage.OpenedDocuments.Contains(event.Uri) {
+		return "", nil
+	}
+
+	if event.Type == lsp.FileDeleted || !strings.HasPrefix(event.Uri, "file://") {
+		return event.Uri, nil // no disk counterpart (eg. 'untitled:' buffer)
 	}
+
+	info, err := os.Stat(uriToFilePath(event.Uri))
+	if maxFileSize := config.GetMaxFileSize(); err == nil && maxFileSize > 0 && info.Size() > maxFileSize {
+		skipFileChangedOnDisk(folder, event.Uri, "file larger than "+strconv.FormatInt(maxFileSize, 10)+" bytes (maxFileSize)")
+		return event.Uri, nil
+	}
+
+	// the other limits of the workspace scan (see 'openProjectFiles()') hold for the files changed afterward.
+	// Go files are not read by the scan
+	if !isGoSourceFile(event.Uri) {
+		rootPath := uriToFilePath(folder.Uri)
+
+		relativePath, err := filepath.Rel(rootPath, uriToFilePath(event.Uri))
+		if err == nil && config.IsGitignoreUsed() && isIgnoredByGitignoreFiles(rootPath, filepath.ToSlash(relativePath)) {
+			skipFileChangedOnDisk(folder, event.Uri, "ignored by .gitignore")
+			return event.Uri, nil
+		}
+
+		folder.muTextFromClient.Lock()
+		_, isKnown := folder.storage.RawFiles[event.Uri]
+		_, isKnownPrelude := folder.storage.Preludes[event.Uri]
+		fileCount := len(folder.storage.RawFiles) + len(folder.storage.Preludes)
+		folder.muTextFromClient.Unlock()
+
+		if maxFileCount := config.GetMaxFileCount(); maxFileCount > 0 && !isKnown && !isKnownPrelude && fileCount >= maxFileCount {
+			skipFileChangedOnDisk(folder, event.Uri, "more than "+strconv.Itoa(maxFileCount)+" files in the workspace (maxFileCount)")
+			return "", nil
+		}
+	}
+
+	content, err = os.ReadFile(uriToFilePath(event.Uri))
+	if err != nil { // most likely deleted right after the event was sent
+		slog.Warn("unable to read file changed on disk, "+err.Error(), slog.String("file_uri", event.Uri))
+		return event.Uri, nil
+	}
+
+	if content == nil {
+		content = []byte{}
+	}
+
+	return event.Uri, content
 }
 
 // Queue like system that notify concerned goroutine when new 'text document' is received from the client.
 // Not all sent 'text document' are processed in order, or even processed at all.
 // In other word, if the same document is inserted many time, only the most recent will be processed when
-// concerned goroutine is ready to do so
+// concerned goroutine is ready to do so.
+// A 'nil' content mark the file for deletion from the workspace
 func insertTextDocumentToDiagnostic(uri string, content []byte, textChangedNotification chan bool, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) {
 	if uri == "" {
 		return
@@ -268,7 +774,7 @@ func notifyTheRootPath(rootPathNotication chan string, rootURI string) {
 }
 
 // Independently diagnostic code source and send notifications to client
-func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication chan string, textChangedNotification chan bool, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) {
+func ProcessDiagnosticNotification(output io.Writer, serverRequests *lsp.ServerRequests, storage *workSpaceStore, rootPathNotication chan string, textChangedNotification chan bool, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) {
 	if rootPathNotication == nil || textChangedNotification == nil {
 		msg := ("channel(s) for 'ProcessDiagnosticNotification()' not properly initialized")
 		slog.Error(msg)
@@ -284,30 +790,85 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 	rootPath, ok := <-rootPathNotication
 	rootPathNotication = nil
 	if !ok {
-		msg := ("rootPathNotification is closed or nil within 'ProcessDiagnosticNotification()'. " +
-			"that channel should only emit the root path once, and then be closed right after and then never used again")
-		slog.Error(msg)
-		panic(msg)
+		// the channel only close without emitting the root path when the session end before 'initialize'
+		slog.Info("lsp session closed before receiving the root path, diagnostic handler stopped")
+		return
 	}
 
-	rootPath = uriToFilePath(rootPath)
+	// An empty root path mean the storage hold a single document (rootless mode),
+	// there is nothing to scan on disk and the document come from the client only
+	var progress *lsp.WorkDoneProgress = nil
+
+	var skippedFiles []lsp.SkippedFile = nil
+
+	// What the go code tell about the templates, and the go files more recent than the disk (key: os path)
+	var goSource gosource.Discovery
+	goSourceOverlay := make(map[string][]byte)
+
+	// go files whose last published diagnostics were not empty
+	var goFilesWithDiagnostics []string
+	dataMismatches := make(dataMismatchCache)
+
+	if rootPath != "" {
+		rootPath = uriToFilePath(rootPath)
+
+		// the progress of the initial scan is carried over to the first analysis below,
+		// which is always a full workspace analysis
+		progress = lsp.CreateWorkDoneProgress(serverRequests, storage.Client)
+		progress.Begin("Indexing templates", "scanning workspace files")
+
+		storage.RootPath = rootPath
+		storage.RootUri = filePathToUri(rootPath)
+		nestedFolderPaths := make([]string, 0, len(storage.NestedFolderUris))
+		for _, nestedUri := range storage.NestedFolderUris {
+			nestedFolderPaths = append(nestedFolderPaths, uriToFilePath(nestedUri))
+		}
+
+		storage.RawFiles, skippedFiles = openProjectFiles(rootPath, storage.Config, nestedFolderPaths)
+
+		// Since the client only recognize URI, it is better to adopt this early
+		// on the server as well to avoid perpetual conversion from 'uri' to 'path'
+		storage.RawFiles = convertKeysFromFilePathToUri(storage.RawFiles)
+
+		// the prelude files are not templates
+		storage.Preludes = make(map[string][]byte)
+		for uri, content := range storage.RawFiles {
+			if storage.Config.IsPreludeFile(storage.RootUri, uri) {
+				storage.Preludes[uri] = content
+				delete(storage.RawFiles, uri)
+			}
+		}
+
+		progress.Report("found "+strconv.Itoa(len(storage.RawFiles))+" template files", 0, 0)
 
-	storage.RootPath = rootPath
-	storage.RawFiles = gota.OpenProjectFiles(rootPath, TARGET_FILE_EXTENSIONS)
+		goSource = discoverTemplateSets(rootPath, goSourceOverlay)
+	}
+
+	if storage.RawFiles == nil {
+		storage.RawFiles = make(map[string][]byte)
+	}
 
-	// Since the client only recognize URI, it is better to adopt this early
-	// on the server as well to avoid perpetual conversion from 'uri' to 'path'
-	storage.RawFiles = convertKeysFromFilePathToUri(storage.RawFiles)
+	if storage.Preludes == nil {
+		storage.Preludes = make(map[string][]byte)
+	}
 
 	muTextFromClient.Lock()
+	storage.SkippedFiles = skippedFiles
+	storage.GoSource = goSource
+
 	{
 		temporaryClone := maps.Clone(textFromClient)
 		maps.Copy(textFromClient, storage.RawFiles)
 		maps.Copy(textFromClient, temporaryClone)
 	}
 
-	if len(textFromClient) > 0 && len(textChangedNotification) == 0 { // Trigger analysis when files found
-		textChangedNotification <- true
+	// Trigger analysis when files found. This is not done with 'textChangedNotification',
+	// since the channel might already be closed if the session ended during the scan
+	isAnalysisPending := len(textFromClient) > 0
+
+	if len(textFromClient) == 0 {
+		progress.End("no template file found")
+		progress = nil
 	}
 
 	muTextFromClient.Unlock()
@@ -316,6 +877,7 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 	storage.OpenedFilesAnalyzed = make(map[string]*checker.FileDefinition)
 	storage.ErrorsAnalyzedFiles = make(map[string][]lexer.Error)
 	storage.ErrorsParsedFiles = make(map[string][]lexer.Error)
+	storage.ErrorsGoCode = make(map[string][]lsp.GoCodeError)
 
 	notification := &lsp.NotificationMessage[lsp.PublishDiagnosticsParams]{
 		JsonRpc: "2.0",
@@ -339,9 +901,12 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 
 	// watch for client edit notification (didChange, ...)
 	var chainedFiles []gota.FileAnalysisAndError = nil
+	var modeTemplateSets []gosource.TemplateSet
 	cloneTextFromClient := make(map[string][]byte)
 
-	for _ = range textChangedNotification {
+	for isAnalysisPending || waitTextChangedNotification(textChangedNotification) {
+		isAnalysisPending = false
+
 		if len(textFromClient) == 0 {
 			msg := ("got a change notification but the text from client was empty. " +
 				"check that the 'textFromClient' still point to the correct address " +
@@ -350,6 +915,20 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 			panic(msg)
 		}
 
+		// Only full workspace analysis are long enough to be worth a progress report.
+		// The progress token must be created outside of the mutex below, since it wait for a client response
+		// that the main loop cannot read while blocked on that same mutex
+		if progress == nil {
+			muTextFromClient.Lock()
+			isFullWorkspaceAnalysis := len(textFromClient) > 1 && len(textFromClient) >= len(storage.RawFiles)
+			muTextFromClient.Unlock()
+
+			if isFullWorkspaceAnalysis {
+				progress = lsp.CreateWorkDoneProgress(serverRequests, storage.Client)
+				progress.Begin("Analysing templates", "")
+			}
+		}
+
 		// This mutex synchronize the 'textFromClient' resource
 		// incidently, it also protect/synchronize the shared 'storage.parsedFiles' and 'storage.RawFiles'
 		// this property is heavily used within the function 'ProcessFoldingRangeRequest()'
@@ -357,23 +936,117 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 
 		clear(cloneTextFromClient)
 		namesOfFileChanged := make([]string, 0, len(textFromClient))
+		namesOfFileDeleted := make([]string, 0)
+		totalFilesToParse := len(textFromClient)
+		isGoSourceChanged := false
+
+		// the preludes are updated first, since the templates parsed below inject them.
+		// Their prelude before the change is kept, to find the templates to parse again
+		var previousPreludes map[string]string
 
 		for uri, fileContent := range textFromClient {
-			if !isFileInsideWorkspace(uri, rootPath, TARGET_FILE_EXTENSIONS) {
+			if rootPath == "" || !storage.Config.IsPreludeFile(storage.RootUri, uri) {
+				continue
+			}
+
+			if previousPreludes == nil {
+				previousPreludes = make(map[string]string, len(storage.ParsedFiles))
+				for templateUri := range storage.ParsedFiles {
+					previousPreludes[templateUri], _ = storage.GetPreludeOfFile(templateUri)
+				}
+			}
+
+			if fileContent == nil { // file deleted from disk
+				delete(storage.Preludes, uri)
+			} else {
+				storage.Preludes[uri] = fileContent
+			}
+
+			delete(textFromClient, uri)
+		}
+
+		// the context of the Hugo partials and of the Helm '{{ define }}' is found at their call sites,
+		// the files whose context changed are parsed again below
+		var previousContextInputs map[string]string
+
+		for uri, fileContent := range textFromClient {
+			if storage.HasContextCalls(fileContent) || storage.HasContextCalls(storage.RawFiles[uri]) {
+				previousContextInputs = storage.GetContextCallInputs()
+				break
+			}
+		}
+
+		for uri, fileContent := range textFromClient {
+			if len(namesOfFileChanged)%25 == 0 {
+				progress.Report("parsing "+strconv.Itoa(len(namesOfFileChanged))+"/"+strconv.Itoa(totalFilesToParse)+" files", len(namesOfFileChanged), 2*totalFilesToParse)
+			}
+
+			// go code is not analysed here, but it define the template sets.
+			// They are discovered again once the mutex is released
+			if rootPath != "" && isGoSourceFile(uri) {
+				updateGoSourceOverlay(goSourceOverlay, storage, uri, fileContent)
+				isGoSourceChanged = true
+				continue
+			}
+
+			if !isFileInsideWorkspace(uri, rootPath, storage.Config) || storage.IsWithinNestedFolder(uri) {
 				slog.Warn("skiped file", slog.String("file_uri", uri))
 				continue
 			}
 
+			if fileContent == nil { // file deleted from disk
+				removeFileFromStorage(storage, uri)
+				namesOfFileDeleted = append(namesOfFileDeleted, uri)
+				continue
+			}
+
 			storage.RawFiles[uri] = fileContent // must be done here inbetween mutex
 			cloneTextFromClient[uri] = fileContent
 
-			parseTree, localErrs := gota.ParseSingleFile(fileContent) // main processing
+			parseTree, localErrs := storage.ParseFile(uri, fileContent) // main processing
 
 			storage.ParsedFiles[uri] = parseTree // must be done here inbetween mutex
 			storage.ErrorsParsedFiles[uri] = localErrs
 			namesOfFileChanged = append(namesOfFileChanged, uri)
 		}
 
+		// the templates whose prelude changed are handled like modified files, the dependency chain reanalysis do the rest
+		for uri, previousPrelude := range previousPreludes {
+			if _, ok := cloneTextFromClient[uri]; ok || storage.ParsedFiles[uri] == nil {
+				continue
+			}
+
+			if prelude, _ := storage.GetPreludeOfFile(uri); prelude == previousPrelude {
+				continue
+			}
+
+			parseTree, localErrs := storage.ParseFile(uri, storage.RawFiles[uri])
+
+			storage.ParsedFiles[uri] = parseTree
+			storage.ErrorsParsedFiles[uri] = localErrs
+
+			cloneTextFromClient[uri] = storage.RawFiles[uri]
+			namesOfFileChanged = append(namesOfFileChanged, uri)
+		}
+
+		if previousContextInputs != nil {
+			contextInputs := storage.GetContextCallInputs()
+
+			for uri := range storage.ParsedFiles {
+				if _, ok := cloneTextFromClient[uri]; ok || contextInputs[uri] == previousContextInputs[uri] {
+					continue
+				}
+
+				parseTree, localErrs := storage.ParseFile(uri, storage.RawFiles[uri])
+
+				storage.ParsedFiles[uri] = parseTree
+				storage.ErrorsParsedFiles[uri] = localErrs
+
+				cloneTextFromClient[uri] = storage.RawFiles[uri]
+				namesOfFileChanged = append(namesOfFileChanged, uri)
+			}
+		}
+
 		clear(textFromClient)
 		for _ = range len(textChangedNotification) { // clear all notifications
 			_ = <-textChangedNotification
@@ -381,27 +1054,100 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 
 		muTextFromClient.Unlock()
 
-		if len(cloneTextFromClient) == 0 {
+		for _, uri := range namesOfFileDeleted { // clear diagnostics of deleted files
+			notification = clearPushDiagnosticNotification(notification)
+			notification.Params.Uri = uri
+
+			response, err := json.Marshal(notification)
+			if err != nil {
+				msg := "Diagnostic Handler is Unable to 'marshall' notification response, " + err.Error()
+				slog.Error(msg, slog.String("file_uri", uri), slog.Any("notification", notification))
+				panic(msg)
+			}
+
+			lsp.SendToLspClient(output, response)
+		}
+
+		if isGoSourceChanged {
+			waitGoSourceSettled(storage, goSourceOverlay, textChangedNotification, textFromClient, muTextFromClient)
+
+			previousGoSource := goSource
+			goSource = discoverTemplateSets(rootPath, goSourceOverlay)
+
+			muTextFromClient.Lock()
+			storage.GoSource = goSource
+
+			// the 'Input' types or the functions injected from the go code changed, every template is parsed again to pick them up.
+			// Otherwise only the templates importing go packages within their 'go:code' are, since those packages might have changed
+			isInjectionChanged := isInjectedGoCodeChanged(previousGoSource, goSource)
+
+			for uri := range storage.ParsedFiles {
+				if isInjectionChanged || lsp.HasGoCodeImports(storage.RawFiles[uri]) {
+					parseTree, localErrs := storage.ParseFile(uri, storage.RawFiles[uri])
+
+					storage.ParsedFiles[uri] = parseTree
+					storage.ErrorsParsedFiles[uri] = localErrs
+
+					if _, ok := cloneTextFromClient[uri]; !ok {
+						namesOfFileChanged = append(namesOfFileChanged, uri)
+					}
+				}
+			}
+
+			muTextFromClient.Unlock()
+		}
+
+		if len(cloneTextFromClient) == 0 && len(namesOfFileDeleted) == 0 && !isGoSourceChanged {
+			progress.End("nothing to analyse")
+			progress = nil
 			continue
 		}
 
 		chainedFiles = nil
+		progress.Report("analysing "+strconv.Itoa(len(cloneTextFromClient))+" files", len(cloneTextFromClient), 2*len(cloneTextFromClient))
 
 		// BUG: this is so ugly
 		// Semantical analysis of files is tighly coupled with this function
 		// I wanted to experiment only with the 'parsing' package,
 		// but it is too challenging to remove or comment out the analysis section
 
-		if len(cloneTextFromClient) == len(storage.ParsedFiles) {
-			chainedFiles = gota.DefinitionAnalisisWithinWorkspace(storage.ParsedFiles)
+		// files depending on a deleted file are unknown at this point, only a full analysis can find them.
+		// Same thing when the go code changed, since any file might have moved to another template set
+		isFullAnalysis := len(cloneTextFromClient) == len(storage.ParsedFiles) || len(namesOfFileDeleted) > 0 || isGoSourceChanged
+
+		// a Hugo page layout is executed along its base template, and a Helm chart along its subcharts.
+		// Those sets change as files are added or removed
+		templateSets := goSource.Sets
+		if storage.Config.IsHugoMode() || storage.Config.IsHelmMode() {
+			muTextFromClient.Lock()
+			if storage.Config.IsHugoMode() {
+				templateSets = storage.DiscoverHugoTemplateSets()
+			} else {
+				templateSets = storage.DiscoverHelmTemplateSets()
+			}
+			muTextFromClient.Unlock()
+
+			isSameSet := func(a gosource.TemplateSet, b gosource.TemplateSet) bool {
+				return a.Id == b.Id && slices.Equal(a.Files, b.Files)
+			}
+			isFullAnalysis = isFullAnalysis || !slices.EqualFunc(modeTemplateSets, templateSets, isSameSet)
+			modeTemplateSets = templateSets
+		}
 
-		} else if len(cloneTextFromClient) > 0 {
-			chainedFiles = gota.DefinitionAnalysisChainTrigerredByBatchFileChange(storage.ParsedFiles, namesOfFileChanged...)
+		if isFullAnalysis || len(cloneTextFromClient) > 0 {
+			chainedFiles = analyseTemplateSets(storage.ParsedFiles, templateSets, namesOfFileChanged, isFullAnalysis)
 
 			// } else if len(cloneTextFromClient) == 1 {
 			// chainedFiles = gota.DefinitionAnalysisChainTrigerredBysingleFileChange(namesOfFileChanged[0], storage.parsedFiles)
 		}
 
+		// the go code of the comments is checked on its own, the analysis above only tell it did not understand it
+		muTextFromClient.Lock()
+		for _, uri := range namesOfFileChanged {
+			storage.ErrorsGoCode[uri] = storage.CheckGoCode(uri, storage.RawFiles[uri])
+		}
+		muTextFromClient.Unlock()
+
 		namesOfFileChanged = namesOfFileChanged[:0] // empty the slice
 
 		for _, fileAnalyzed := range chainedFiles {
@@ -417,8 +1163,22 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 			errs = append(errs, storage.ErrorsParsedFiles[uri]...)
 			errs = append(errs, storage.ErrorsAnalyzedFiles[uri]...)
 
+			lineIndex := lsp.NewLineIndex(storage.RawFiles[uri], storage.Client.GetPositionEncoding())
+
 			notification = clearPushDiagnosticNotification(notification)
-			notification = setParseErrosToDiagnosticsNotification(errs, notification)
+
+			// files out of the diagnostics scope are still sent, but empty, to clear what was previously reported
+			if storage.Config.IsDiagnosticReported(uri, storage.OpenedDocuments) {
+				goCodeErrs := storage.ErrorsGoCode[uri]
+				syntaxSeverity := storage.Config.GetRuleSeverity(lsp.DiagnosticRuleSyntax)
+				analysisSeverity := storage.Config.GetRuleSeverity(lsp.DiagnosticRuleAnalysis)
+
+				notification = setParseErrosToDiagnosticsNotification(removeErrorsWithinGoCode(storage.ErrorsParsedFiles[uri], goCodeErrs), syntaxSeverity, notification, lineIndex)
+				notification = setParseErrosToDiagnosticsNotification(removeErrorsWithinGoCode(storage.ErrorsAnalyzedFiles[uri], goCodeErrs), analysisSeverity, notification, lineIndex)
+				notification = setGoCodeErrorsToDiagnosticsNotification(goCodeErrs, syntaxSeverity, analysisSeverity, notification, lineIndex)
+			}
+
+			notification.Params.Diagnostics = lsp.AdaptDiagnosticsToClient(notification.Params.Diagnostics, storage.Client)
 			notification.Params.Uri = uri
 
 			response, err := json.Marshal(notification)
@@ -435,13 +1195,96 @@ func ProcessDiagnosticNotification(storage *workSpaceStore, rootPathNotication c
 				panic(msg)
 			}
 
-			lsp.SendToLspClient(os.Stdout, response)
+			lsp.SendToLspClient(output, response)
+		}
+
+		// the template names and data used by the go code are checked once the templates are up to date
+		if rootPath != "" {
+			muTextFromClient.Lock()
+			if isGoSourceChanged {
+				clear(dataMismatches)
+			} else {
+				dataMismatches.invalidate(storage, append(mapToKeys(cloneTextFromClient), namesOfFileDeleted...))
+			}
+			muTextFromClient.Unlock()
+
+			checkTemplateCallsData(storage, muTextFromClient, dataMismatches)
+
+			muTextFromClient.Lock()
+			goFiles := newGoFileIndexes(goSourceOverlay, storage.Client.GetPositionEncoding())
+
+			storage.UnknownTemplateNames = findUnknownTemplateNames(storage, goFiles)
+			storage.TemplateCallLinks = findTemplateCallLinks(storage, goFiles)
+			goDiagnostics := createGoSourceDiagnostics(storage, storage.UnknownTemplateNames, dataMismatches, goFiles)
+			muTextFromClient.Unlock()
+
+			goFilesWithDiagnostics = publishGoSourceDiagnostics(output, storage, goDiagnostics, goFilesWithDiagnostics, notification)
 		}
 
+		progress.End(strconv.Itoa(len(chainedFiles)) + " files analysed")
+		progress = nil
+
 		storageSanityCheck(storage)
 	}
 }
 
+// Only the go files opened by the editor are more recent than the disk, the other ones are read from disk.
+// Thus the overlay never hold more than the opened files
+func updateGoSourceOverlay(goSourceOverlay map[string][]byte, storage *workSpaceStore, uri string, content []byte) {
+	if storage.OpenedDocuments.Contains(uri) {
+		goSourceOverlay[uriToFilePath(uri)] = content
+		return
+	}
+
+	delete(goSourceOverlay, uriToFilePath(uri))
+}
+
+// Block until no go file changed for 'GO_SOURCE_SETTLE_DELAY', the go changes received meanwhile are moved into the overlay.
+// The other changes stay queued for the next analysis
+func waitGoSourceSettled(storage *workSpaceStore, goSourceOverlay map[string][]byte, textChangedNotification chan bool, textFromClient map[string][]byte, muTextFromClient *sync.Mutex) {
+	for {
+		time.Sleep(GO_SOURCE_SETTLE_DELAY)
+
+		muTextFromClient.Lock()
+		isGoSourceChanged := false
+
+		for uri, fileContent := range textFromClient {
+			if isGoSourceFile(uri) {
+				updateGoSourceOverlay(goSourceOverlay, storage, uri, fileContent)
+				delete(textFromClient, uri)
+				isGoSourceChanged = true
+			}
+		}
+
+		if len(textFromClient) == 0 { // nothing left for the notification
+			for _ = range len(textChangedNotification) {
+				_ = <-textChangedNotification
+			}
+		}
+		muTextFromClient.Unlock()
+
+		if !isGoSourceChanged {
+			return
+		}
+	}
+}
+
+// Block until the next notification. Return false once the channel is closed
+func waitTextChangedNotification(textChangedNotification chan bool) bool {
+	_, ok := <-textChangedNotification
+	return ok
+}
+
+// Forget everything about the file, must be called while holding 'muTextFromClient'
+func removeFileFromStorage(storage *workSpaceStore, uri string) {
+	delete(storage.RawFiles, uri)
+	delete(storage.ParsedFiles, uri)
+	delete(storage.ErrorsParsedFiles, uri)
+	delete(storage.OpenedFilesAnalyzed, uri)
+	delete(storage.ErrorsAnalyzedFiles, uri)
+	delete(storage.ErrorsGoCode, uri)
+}
+
 func storageSanityCheck(storage *workSpaceStore) {
 	if len(storage.OpenedFilesAnalyzed) != len(storage.ParsedFiles) {
 		msg := "size mismatch between 'semantic analysed files' and 'parsed files'"
@@ -493,15 +1336,14 @@ func storageSanityCheck(storage *workSpaceStore) {
 	}
 }
 
-func isFileInsideWorkspace(uri string, rootPath string, allowedFileExntesions []string) bool {
-	path := uri
-	rootPath = filePathToUri(rootPath)
-
-	if !strings.HasPrefix(path, rootPath) {
-		return false
+// In rootless mode ('rootPath' is empty), the storage only receive the document it was created for,
+// so that document is always accepted whatever its extension or scheme (eg. 'untitled:' buffer)
+func isFileInsideWorkspace(uri string, rootPath string, config *lsp.ProjectConfig) bool {
+	if rootPath == "" {
+		return uri != ""
 	}
 
-	return gota.HasFileExtension(path, allowedFileExntesions)
+	return config.IsFileIncluded(filePathToUri(rootPath), uri)
 }
 
 func clearPushDiagnosticNotification(notification *lsp.NotificationMessage[lsp.PublishDiagnosticsParams]) *lsp.NotificationMessage[lsp.PublishDiagnosticsParams] {
@@ -511,14 +1353,18 @@ func clearPushDiagnosticNotification(notification *lsp.NotificationMessage[lsp.P
 	return notification
 }
 
-func setParseErrosToDiagnosticsNotification(errs []gota.Error, response *lsp.NotificationMessage[lsp.PublishDiagnosticsParams]) *lsp.NotificationMessage[lsp.PublishDiagnosticsParams] {
+// Append 'errs' to the diagnostics of the notification, with the given 'severity'.
+// Nothing is appended when the severity is 0 (rule turned off)
+func setParseErrosToDiagnosticsNotification(errs []gota.Error, severity int, response *lsp.NotificationMessage[lsp.PublishDiagnosticsParams], lineIndex *lsp.LineIndex) *lsp.NotificationMessage[lsp.PublishDiagnosticsParams] {
 	if response == nil {
 		msg := ("diagnostics errors cannot be appended on 'nil' response. first create the the response")
 		slog.Error(msg)
 		panic(msg)
 	}
 
-	response.Params.Diagnostics = []lsp.Diagnostic{}
+	if severity == 0 {
+		return response
+	}
 
 	for _, err := range errs {
 		if err == nil {
@@ -532,10 +1378,15 @@ func setParseErrosToDiagnosticsNotification(errs []gota.Error, response *lsp.Not
 			panic(msg)
 		}
 
+		// errors within the 'go:code' injected from the Go call sites are not the user's concern
+		if lineIndex.IsBeyondContent(err.GetRange().Start) {
+			continue
+		}
+
 		diagnostic := lsp.Diagnostic{
 			Message:  err.GetError(),
-			Range:    *fromParserRangeToLspRange(err.GetRange()),
-			Severity: 1, // 1 = Error, 2 = Warning, 3 = Info, 4 = Hint
+			Range:    *fromParserRangeToLspRange(err.GetRange(), lineIndex),
+			Severity: severity, // 1 = Error, 2 = Warning, 3 = Info, 4 = Hint
 		}
 
 		response.Params.Diagnostics = append(response.Params.Diagnostics, diagnostic)
@@ -544,19 +1395,64 @@ func setParseErrosToDiagnosticsNotification(errs []gota.Error, response *lsp.Not
 	return response
 }
 
-func fromParserRangeToLspRange(rg lexer.Range) *lsp.Range {
-	reach := &lsp.Range{
-		Start: lsp.Position{
-			Line:      uint(rg.Start.Line),
-			Character: uint(rg.Start.Character),
-		},
-		End: lsp.Position{
-			Line:      uint(rg.End.Line),
-			Character: uint(rg.End.Character),
-		},
+// Append the errors of the go code, the syntax errors with 'syntaxSeverity' and the others with 'analysisSeverity'.
+// Nothing is appended for a rule turned off (severity 0)
+func setGoCodeErrorsToDiagnosticsNotification(errs []lsp.GoCodeError, syntaxSeverity int, analysisSeverity int, response *lsp.NotificationMessage[lsp.PublishDiagnosticsParams], lineIndex *lsp.LineIndex) *lsp.NotificationMessage[lsp.PublishDiagnosticsParams] {
+	for _, err := range errs {
+		severity := analysisSeverity
+		if err.IsSyntaxError() {
+			severity = syntaxSeverity
+		}
+
+		if severity == 0 {
+			continue
+		}
+
+		diagnostic := lsp.Diagnostic{
+			Message:  err.Message,
+			Range:    *fromParserRangeToLspRange(err.Range, lineIndex),
+			Severity: severity,
+			Code:     err.Code,
+		}
+
+		response.Params.Diagnostics = append(response.Params.Diagnostics, diagnostic)
 	}
 
-	return reach
+	return response
+}
+
+// The errors of the parser and the analysis found within a 'go:code' comment already reported by 'go/parser' or 'go/types'
+// are removed, they only tell the go code was not understood
+func removeErrorsWithinGoCode(errs []gota.Error, goCodeErrs []lsp.GoCodeError) []gota.Error {
+	if len(goCodeErrs) == 0 {
+		return errs
+	}
+
+	isBefore := func(x lexer.Position, y lexer.Position) bool {
+		return x.Line < y.Line || (x.Line == y.Line && x.Character < y.Character)
+	}
+
+	kept := make([]gota.Error, 0, len(errs))
+	for _, err := range errs {
+		start := err.GetRange().Start
+
+		isWithinGoCode := slices.ContainsFunc(goCodeErrs, func(goCodeErr lsp.GoCodeError) bool {
+			return !isBefore(start, goCodeErr.Comment.Start) && isBefore(start, goCodeErr.Comment.End)
+		})
+
+		if !isWithinGoCode {
+			kept = append(kept, err)
+		}
+	}
+
+	return kept
+}
+
+// 'lineIndex' must be built from the file the range belong to
+func fromParserRangeToLspRange(rg lexer.Range, lineIndex *lsp.LineIndex) *lsp.Range {
+	reach := lineIndex.ToLspRange(rg)
+
+	return &reach
 }
 
 func uriToFilePath(uri string) string {
@@ -709,12 +1605,21 @@ func configureLogging() {
 	slog.SetDefault(logger)
 }
 
-func GetServerGroupLogging[T any](storage *workSpaceStore, counter requestCounter, request lsp.RequestMessage[T], textFromClient map[string][]byte) slog.Attr {
+func GetServerGroupLogging[T any](folders map[string]*workspaceFolder, openedDocuments *lsp.OpenedDocuments, counter requestCounter, request lsp.RequestMessage[T]) slog.Attr {
+	workspaces := make([]slog.Attr, 0, len(folders))
+
+	for uri, folder := range folders {
+		workspaces = append(workspaces, slog.Group(uri,
+			slog.String("root_path", folder.storage.RootPath),
+			slog.Any("open_files", mapToKeys(folder.storage.RawFiles)),
+			slog.Any("files_waiting_processing", mapToKeys(folder.textFromClient)),
+		))
+	}
+
 	group := slog.Group("server",
-		slog.String("root_path", storage.RootPath),
 		slog.Any("last_request", request),
-		slog.Any("open_files", mapToKeys(storage.RawFiles)),
-		slog.Any("files_waiting_processing", mapToKeys(textFromClient)),
+		slog.Any("workspace_folders", slog.GroupValue(workspaces...)),
+		slog.Any("files_owned_by_editor", openedDocuments.GetUris()),
 		slog.Any("request_counter", counter),
 	)
 
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/lsp"
+
+	"github.com/yayolande/gota"
+)
+
+// Read every template file (and prelude file) of the workspace, in place of 'gota.OpenProjectFiles()'.
+// The walk honor the include/exclude globs, the '.gitignore' files and the size/count limits of the config.
+// Files left out are returned as 'skippedFiles', along the reason (key of 'files' are os path).
+// The directories of 'nestedFolderPaths' are left out, they are scanned by their own workspace folder
+func openProjectFiles(rootPath string, config *lsp.ProjectConfig, nestedFolderPaths []string) (files map[string][]byte, skippedFiles []lsp.SkippedFile) {
+	files = make(map[string][]byte)
+
+	var ignoreRules []lsp.GitignoreRule
+	isGitignoreUsed := config.IsGitignoreUsed()
+	maxFileSize := config.GetMaxFileSize()
+	maxFileCount := config.GetMaxFileCount()
+
+	skip := func(path string, reason string) {
+		skippedFiles = append(skippedFiles, lsp.SkippedFile{Uri: filePathToUri(path), Reason: reason})
+	}
+
+	err := filepath.WalkDir(rootPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			slog.Warn("error while scanning the workspace, "+err.Error(), slog.String("path", path))
+
+			if entry != nil && entry.IsDir() && path != rootPath {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(rootPath, path)
+		if err != nil {
+			return nil
+		}
+
+		relativePath = filepath.ToSlash(relativePath)
+
+		if entry.IsDir() {
+			if path != rootPath {
+				if entry.Name() == ".git" || slices.Contains(nestedFolderPaths, path) {
+					return fs.SkipDir
+				}
+
+				if config.IsDirectoryExcluded(relativePath) {
+					skip(path, "directory excluded by the config (exclude)")
+					return fs.SkipDir
+				}
+
+				if isGitignoreUsed && lsp.IsIgnoredByGitignore(ignoreRules, relativePath, true) {
+					skip(path, "directory ignored by .gitignore")
+					return fs.SkipDir
+				}
+			}
+
+			// rules of a nested '.gitignore' are anchored to its directory, so they never leak to the siblings
+			if isGitignoreUsed {
+				content, err := os.ReadFile(filepath.Join(path, ".gitignore"))
+				if err == nil {
+					ignoreRules = append(ignoreRules, lsp.ParseGitignore(content, relativePath)...)
+				}
+			}
+
+			return nil
+		}
+
+		// prelude files only follow their own globs, see 'ProjectConfig.IsPreludePath()'
+		isPrelude := config.IsPreludePath(relativePath)
+
+		if !entry.Type().IsRegular() || (!isPrelude && !gota.HasFileExtension(path, config.GetExtensions())) {
+			return nil
+		}
+
+		if !isPrelude && !config.IsModeTemplatePath(relativePath) {
+			return nil
+		}
+
+		if !isPrelude && !config.IsPathIncluded(relativePath) {
+			skip(path, "excluded by the config (include/exclude)")
+			return nil
+		}
+
+		if isGitignoreUsed && lsp.IsIgnoredByGitignore(ignoreRules, relativePath, false) {
+			skip(path, "ignored by .gitignore")
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			skip(path, "unreadable file, "+err.Error())
+			return nil
+		}
+
+		if maxFileSize > 0 && info.Size() > maxFileSize {
+			skip(path, "file larger than "+strconv.FormatInt(maxFileSize, 10)+" bytes (maxFileSize)")
+			return nil
+		}
+
+		if maxFileCount > 0 && len(files) >= maxFileCount {
+			skip(path, "more than "+strconv.Itoa(maxFileCount)+" files in the workspace (maxFileCount)")
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			skip(path, "unreadable file, "+err.Error())
+			return nil
+		}
+
+		if content == nil {
+			content = []byte{}
+		}
+
+		files[path] = content
+
+		return nil
+	})
+
+	if err != nil {
+		slog.Error("workspace scan interrupted, "+err.Error(), slog.String("root_path", rootPath))
+	}
+
+	if len(skippedFiles) > 0 {
+		slog.Info("files skipped during the workspace scan",
+			slog.String("root_path", rootPath),
+			slog.Int("count", len(skippedFiles)),
+			slog.Any("skipped_files", skippedFiles),
+		)
+	}
+
+	return files, skippedFiles
+}
+
+// Whether the file is ignored by the '.gitignore' files found from the root down to its directory, or one of its directories is.
+// Same outcome as the workspace scan, for the files changed afterward (see 'getFileChangedOnDisk()')
+func isIgnoredByGitignoreFiles(rootPath string, relativePath string) bool {
+	var ignoreRules []lsp.GitignoreRule
+
+	directory := ""
+	segments := strings.Split(relativePath, "/")
+
+	for index, segment := range segments {
+		content, err := os.ReadFile(filepath.Join(rootPath, filepath.FromSlash(directory), ".gitignore"))
+		if err == nil {
+			ignoreRules = append(ignoreRules, lsp.ParseGitignore(content, directory)...)
+		}
+
+		if directory != "" {
+			directory += "/"
+		}
+
+		directory += segment
+
+		if lsp.IsIgnoredByGitignore(ignoreRules, directory, index < len(segments)-1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Report the file changed on disk left out by the filters of the workspace scan, like the files skipped by the scan itself
+func skipFileChangedOnDisk(folder *workspaceFolder, uri string, reason string) {
+	slog.Info("file changed on disk is skipped, "+reason, slog.String("file_uri", uri))
+
+	folder.muTextFromClient.Lock()
+	defer folder.muTextFromClient.Unlock()
+
+	if !slices.ContainsFunc(folder.storage.SkippedFiles, func(skipped lsp.SkippedFile) bool { return skipped.Uri == uri }) {
+		folder.storage.SkippedFiles = append(folder.storage.SkippedFiles, lsp.SkippedFile{Uri: uri, Reason: reason})
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/yayolande/go-template-lsp/gosource"
+	"github.com/yayolande/go-template-lsp/lsp"
+
+	"github.com/yayolande/gota"
+	"github.com/yayolande/gota/lexer"
+	"github.com/yayolande/gota/parser"
+)
+
+func isGoSourceFile(uri string) bool {
+	return strings.HasSuffix(uri, ".go")
+}
+
+// Go files and prelude files belong to the folder without being templates, the requests on them have no template answer
+func isTemplateFile(folder *workspaceFolder, uri string) bool {
+	return !isGoSourceFile(uri) && !folder.storage.Config.IsPreludeFile(folder.Uri, uri)
+}
+
+// Whether the analysis of the file is available. An opened file left out by the config or by a nested folder
+// (eg. 'node_modules/x.html') is never analysed, and a file just opened is not analysed yet
+func isFileAnalysed(folder *workspaceFolder, uri string) bool {
+	folder.muTextFromClient.Lock()
+	defer folder.muTextFromClient.Unlock()
+
+	return folder.storage.OpenedFilesAnalyzed[uri] != nil
+}
+
+// Find the template sets, the template executions and the template functions from the Go code of the workspace.
+// 'goSourceOverlay' hold the go files more recent than the disk (key: os path)
+func discoverTemplateSets(rootPath string, goSourceOverlay map[string][]byte) gosource.Discovery {
+	packages := gosource.ParseWorkspacePackages(rootPath, goSourceOverlay)
+	gosource.CheckWorkspacePackages(rootPath, packages)
+
+	discovery := gosource.DiscoverTemplateSets(rootPath, packages)
+
+	ids := make([]string, 0, len(discovery.Sets))
+	for _, set := range discovery.Sets {
+		ids = append(ids, set.Id)
+	}
+
+	callSites := make([]string, 0, len(discovery.Executions))
+	for _, execution := range discovery.Executions {
+		callSites = append(callSites, execution.CallSite+" -> "+execution.TemplateName)
+	}
+
+	functionNames := make([]string, 0, len(discovery.Functions))
+	for _, function := range discovery.Functions {
+		functionNames = append(functionNames, function.Name)
+	}
+
+	slog.Info("template sets discovered from go code",
+		slog.String("root_path", rootPath),
+		slog.Int("go_packages", len(packages)),
+		slog.Any("template_sets", ids),
+		slog.Any("template_executions", callSites),
+		slog.Any("template_functions", functionNames),
+	)
+
+	return discovery
+}
+
+// Whether the go code injected within the templates changed, in which case every template must be parsed again
+func isInjectedGoCodeChanged(previous gosource.Discovery, current gosource.Discovery) bool {
+	// the position and the doc of the functions are not injected
+	isSameFunction := func(a gosource.TemplateFunction, b gosource.TemplateFunction) bool {
+		return a.SetId == b.SetId && a.Name == b.Name && a.GoCode == b.GoCode
+	}
+
+	// the patterns of a set decide which templates see its functions and its preludes
+	isSameSet := func(a gosource.TemplateSet, b gosource.TemplateSet) bool {
+		return a.Id == b.Id && slices.Equal(a.Patterns, b.Patterns)
+	}
+
+	return !slices.Equal(previous.Executions, current.Executions) || !slices.EqualFunc(previous.Functions, current.Functions, isSameFunction) ||
+		!slices.EqualFunc(previous.Sets, current.Sets, isSameSet)
+}
+
+// Line index of the go files, read from the overlay (the go files more recent than the disk), or else from disk
+type goFileIndexes struct {
+	overlay  map[string][]byte // key: os path
+	encoding lsp.PositionEncodingKind
+	indexes  map[string]*lsp.LineIndex
+}
+
+func newGoFileIndexes(goSourceOverlay map[string][]byte, encoding lsp.PositionEncodingKind) *goFileIndexes {
+	return &goFileIndexes{overlay: goSourceOverlay, encoding: encoding, indexes: make(map[string]*lsp.LineIndex)}
+}
+
+// Range of the go code between 'start' and 'end', both within the same file
+func (files *goFileIndexes) toLspRange(start token.Position, end token.Position) lsp.Range {
+	lineIndex := files.indexes[start.Filename]
+	if lineIndex == nil {
+		content, ok := files.overlay[start.Filename]
+		if !ok {
+			content, _ = os.ReadFile(start.Filename)
+		}
+
+		lineIndex = lsp.NewLineIndex(content, files.encoding)
+		files.indexes[start.Filename] = lineIndex
+	}
+
+	// go columns are 1-based byte offsets
+	reach := lexer.Range{
+		Start: lexer.Position{Line: start.Line - 1, Character: start.Column - 1},
+		End:   lexer.Position{Line: end.Line - 1, Character: end.Column - 1},
+	}
+
+	return *fromParserRangeToLspRange(reach, lineIndex)
+}
+
+// Link the template names given to 'ExecuteTemplate()' by the go code to the template they execute, for 'go-to-definition'.
+// Must be called while holding 'muTextFromClient'
+func findTemplateCallLinks(storage *workSpaceStore, goFiles *goFileIndexes) []lsp.TemplateCallLink {
+	var links []lsp.TemplateCallLink
+
+	for _, call := range storage.GoSource.Calls {
+		if !call.NameStart.IsValid() {
+			continue // only string literals can be clicked
+		}
+
+		uri, definition := storage.FindTemplateDefinition(call.SetId, call.TemplateName)
+		if uri == "" {
+			continue
+		}
+
+		link := lsp.TemplateCallLink{
+			Uri:         filePathToUri(call.NameStart.Filename),
+			Range:       goFiles.toLspRange(call.NameStart, call.NameEnd),
+			TargetUri:   uri,
+			TargetRange: *fromParserRangeToLspRange(definition, lsp.NewLineIndex(storage.RawFiles[uri], goFiles.encoding)),
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// Check the template names given to 'ExecuteTemplate()' by the go code against the templates of their set.
+// Must be called while holding 'muTextFromClient'
+func findUnknownTemplateNames(storage *workSpaceStore, goFiles *goFileIndexes) []lsp.UnknownTemplateName {
+	var unknownNames []lsp.UnknownTemplateName
+	namesBySet := make(map[string][]string)
+
+	for _, call := range storage.GoSource.Calls {
+		if !call.NameStart.IsValid() {
+			continue // only string literals are checked
+		}
+
+		names, ok := namesBySet[call.SetId]
+		if !ok {
+			names = storage.GetTemplateNamesOfSet(call.SetId)
+			namesBySet[call.SetId] = names
+		}
+
+		// a set without any template file is a set the LSP know nothing about
+		if len(names) == 0 || slices.Contains(names, call.TemplateName) {
+			continue
+		}
+
+		unknownName := lsp.UnknownTemplateName{
+			Uri:         filePathToUri(call.NameStart.Filename),
+			Range:       goFiles.toLspRange(call.NameStart, call.NameEnd),
+			Name:        call.TemplateName,
+			SetId:       call.SetId,
+			Suggestions: lsp.SuggestTemplateNames(call.TemplateName, names),
+		}
+
+		unknownNames = append(unknownNames, unknownName)
+	}
+
+	return unknownNames
+}
+
+// Diagnostics of the go files: the unknown template names, and the data not satisfying their template.
+// Must be called while holding 'muTextFromClient'
+func createGoSourceDiagnostics(storage *workSpaceStore, unknownNames []lsp.UnknownTemplateName, mismatches dataMismatchCache, goFiles *goFileIndexes) map[string][]lsp.Diagnostic {
+	severity := storage.Config.GetRuleSeverity(lsp.DiagnosticRuleExecuteTemplate)
+	diagnosticsByUri := make(map[string][]lsp.Diagnostic)
+
+	if severity == 0 {
+		return diagnosticsByUri
+	}
+
+	for _, unknownName := range unknownNames {
+		diagnostic := lsp.Diagnostic{
+			Range:    unknownName.Range,
+			Message:  unknownName.Message(),
+			Severity: severity,
+		}
+
+		diagnosticsByUri[unknownName.Uri] = append(diagnosticsByUri[unknownName.Uri], diagnostic)
+	}
+
+	for uri, diagnostics := range createDataMismatchDiagnostics(storage, mismatches, goFiles, severity) {
+		diagnosticsByUri[uri] = append(diagnosticsByUri[uri], diagnostics...)
+	}
+
+	return diagnosticsByUri
+}
+
+// Publish the diagnostics of the go files, and clear those of the go files that no longer have any.
+// Return the go files whose diagnostics are not empty
+func publishGoSourceDiagnostics(output io.Writer, storage *workSpaceStore, diagnosticsByUri map[string][]lsp.Diagnostic, previousUris []string, notification *lsp.NotificationMessage[lsp.PublishDiagnosticsParams]) []string {
+	maps.DeleteFunc(diagnosticsByUri, func(uri string, diagnostics []lsp.Diagnostic) bool {
+		return len(diagnostics) == 0 || !storage.Config.IsDiagnosticReported(uri, storage.OpenedDocuments) || storage.IsWithinNestedFolder(uri)
+	})
+
+	uris := mapToKeys(diagnosticsByUri)
+	slices.Sort(uris)
+
+	clearedUris := slices.DeleteFunc(slices.Clone(previousUris), func(uri string) bool { return slices.Contains(uris, uri) })
+
+	for _, uri := range slices.Concat(uris, clearedUris) {
+		notification = clearPushDiagnosticNotification(notification)
+		notification.Params.Uri = uri
+
+		if diagnostics, ok := diagnosticsByUri[uri]; ok {
+			notification.Params.Diagnostics = lsp.AdaptDiagnosticsToClient(diagnostics, storage.Client)
+		}
+
+		response, err := json.Marshal(notification)
+		if err != nil {
+			msg := "Diagnostic Handler is Unable to 'marshall' notification response, " + err.Error()
+			slog.Error(msg, slog.String("file_uri", uri), slog.Any("notification", notification))
+			panic(msg)
+		}
+
+		lsp.SendToLspClient(output, response)
+	}
+
+	return uris
+}
+
+// Split the files by template set, in the order of the sets.
+// The files belonging to no set are grouped together at the end
+func groupFilesByTemplateSet(parsedFiles map[string]*parser.GroupStatementNode, templateSets []gosource.TemplateSet) []map[string]*parser.GroupStatementNode {
+	groups := make([]map[string]*parser.GroupStatementNode, len(templateSets), len(templateSets)+1)
+	orphans := make(map[string]*parser.GroupStatementNode)
+
+	for index := range groups {
+		groups[index] = make(map[string]*parser.GroupStatementNode)
+	}
+
+	for uri, parseTree := range parsedFiles {
+		isOrphan := true
+
+		if len(templateSets) > 0 && strings.HasPrefix(uri, "file://") {
+			filePath := uriToFilePath(uri)
+
+			for index := range templateSets {
+				if templateSets[index].Contains(filePath) {
+					groups[index][uri] = parseTree
+					isOrphan = false
+				}
+			}
+		}
+
+		if isOrphan {
+			orphans[uri] = parseTree
+		}
+	}
+
+	if len(orphans) > 0 {
+		groups = append(groups, orphans)
+	}
+
+	return groups
+}
+
+// Run the definition analysis set by set, since only the files of a same set share their '{{ define }}' at runtime.
+// The files belonging to no set are analysed together, which is also the case of every file when no set is found (eg. no Go code).
+// A file belonging to many sets keep the analysis of the first one
+func analyseTemplateSets(parsedFiles map[string]*parser.GroupStatementNode, templateSets []gosource.TemplateSet, namesOfFileChanged []string, isFullAnalysis bool) []gota.FileAnalysisAndError {
+	var chainedFiles []gota.FileAnalysisAndError
+	isAnalysed := make(map[string]bool)
+
+	for _, group := range groupFilesByTemplateSet(parsedFiles, templateSets) {
+		var filesAnalysed []gota.FileAnalysisAndError
+
+		if isFullAnalysis {
+			filesAnalysed = gota.DefinitionAnalisisWithinWorkspace(group)
+		} else {
+			namesOfGroupFileChanged := slices.DeleteFunc(slices.Clone(namesOfFileChanged), func(uri string) bool {
+				_, ok := group[uri]
+				return !ok
+			})
+
+			if len(namesOfGroupFileChanged) == 0 {
+				continue
+			}
+
+			filesAnalysed = gota.DefinitionAnalysisChainTrigerredByBatchFileChange(group, namesOfGroupFileChanged...)
+		}
+
+		for _, fileAnalyzed := range filesAnalysed {
+			if isAnalysed[fileAnalyzed.FileName] {
+				continue
+			}
+
+			isAnalysed[fileAnalyzed.FileName] = true
+			chainedFiles = append(chainedFiles, fileAnalyzed)
+		}
+	}
+
+	return chainedFiles
+}
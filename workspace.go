@@ -0,0 +1,392 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/yayolande/go-template-lsp/lsp"
+)
+
+// Every workspace folder is analysed on its own, with its own storage and diagnostic handler.
+// Thus templates of different folders never see each other (eg. same 'define' name in 2 services)
+type workspaceFolder struct {
+	Name string
+	Uri  string
+
+	// The folder is made of a single document (scratch file, 'untitled:' buffer, file outside of all folders, ...)
+	// and its 'Uri' is the document uri. Nothing is read from disk, the document is analysed on its own
+	isSingleFile bool
+
+	// ******************************************************************************
+	// WARNING: In under no cirscumstance the 4 fields below should be re-assnigned
+	// Otherwise, a nasty bug will appear (value not synced with the rest of the app)
+	// ******************************************************************************
+
+	storage                 *workSpaceStore
+	textChangedNotification chan bool
+	textFromClient          map[string][]byte
+	muTextFromClient        *sync.Mutex
+
+	stopped chan struct{} // closed once the diagnostic handler of the folder returned
+}
+
+// Start the analysis of the folder, nothing is done if the folder is already opened.
+// The folders containing the new folder are reloaded, so that they give up its files
+func openWorkspaceFolder(folders map[string]*workspaceFolder, workspace lsp.WorkspaceFolder, client *lsp.ClientCapabilities, openedDocuments *lsp.OpenedDocuments, editorConfig *lsp.ProjectConfig, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) *workspaceFolder {
+	uri := strings.TrimSuffix(workspace.Uri, "/")
+	if uri == "" {
+		return nil
+	}
+
+	if folder, ok := folders[uri]; ok {
+		return folder
+	}
+
+	if !strings.HasPrefix(uri, "file://") {
+		slog.Warn("skiped workspace folder, only 'file://' folders can be scanned", slog.String("uri", uri))
+		return nil
+	}
+
+	folder := &workspaceFolder{
+		Name: workspace.Name,
+		Uri:  uri,
+	}
+
+	// the new folder only publish once the parents cleared the diagnostics of its files
+	parents := findParentWorkspaceFolders(folders, uri)
+
+	config := loadFolderConfig(editorConfig, uri, output)
+	startWorkspaceFolder(folders, folder, uri, config, parents, client, openedDocuments, output, serverRequests, closeSession)
+
+	for _, parent := range parents {
+		reloadWorkspaceFolder(folders, parent.Uri, client, editorConfig, output, serverRequests, closeSession)
+	}
+
+	return folder
+}
+
+// Start the analysis of a lone document, used when the document belong to no workspace folder.
+// Nothing is done if the document is already analysed on its own
+func openSingleFileWorkspace(folders map[string]*workspaceFolder, documentUri string, client *lsp.ClientCapabilities, openedDocuments *lsp.OpenedDocuments, editorConfig *lsp.ProjectConfig, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) *workspaceFolder {
+	if documentUri == "" {
+		return nil
+	}
+
+	if folder, ok := folders[documentUri]; ok {
+		return folder
+	}
+
+	folder := &workspaceFolder{
+		Name:         "single-file",
+		Uri:          documentUri,
+		isSingleFile: true,
+	}
+
+	// an empty root uri tell the diagnostic handler to not scan the disk
+	startWorkspaceFolder(folders, folder, "", editorConfig, nil, client, openedDocuments, output, serverRequests, closeSession)
+
+	return folder
+}
+
+// The diagnostic handler of the new folder only start once the ones of 'previous' stopped,
+// otherwise their notifications would interleave when a folder is reloaded.
+// A crash of the diagnostic handler close the session through 'closeSession', the other sessions of the daemon go on
+func startWorkspaceFolder(folders map[string]*workspaceFolder, folder *workspaceFolder, rootUri string, config *lsp.ProjectConfig, previous []*workspaceFolder, client *lsp.ClientCapabilities, openedDocuments *lsp.OpenedDocuments, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) {
+	folder.storage = &workSpaceStore{Client: client, OpenedDocuments: openedDocuments, Config: config}
+	if rootUri != "" {
+		folder.storage.NestedFolderUris = findNestedFolderUris(folders, folder.Uri)
+	}
+
+	folder.textChangedNotification = make(chan bool, 2)
+	folder.textFromClient = make(map[string][]byte)
+	folder.muTextFromClient = new(sync.Mutex)
+	folder.stopped = make(chan struct{})
+
+	rootPathNotication := make(chan string, 2)
+
+	go func() {
+		defer close(folder.stopped)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// nothing is published, the state of the storage is unknown at this point
+			slog.Error("diagnostic handler crashed, its lsp session is closed",
+				slog.Any("panic", recovered),
+				slog.String("folder_uri", folder.Uri),
+				slog.String("stack", string(debug.Stack())),
+			)
+
+			closeSession()
+		}()
+
+		for _, folder := range previous {
+			<-folder.stopped
+		}
+
+		ProcessDiagnosticNotification(output, serverRequests, folder.storage, rootPathNotication, folder.textChangedNotification, folder.textFromClient, folder.muTextFromClient)
+	}()
+
+	notifyTheRootPath(rootPathNotication, rootUri)
+	folders[folder.Uri] = folder
+
+	slog.Info("workspace folder opened",
+		slog.String("name", folder.Name),
+		slog.String("uri", folder.Uri),
+		slog.Bool("is_single_file", folder.isSingleFile),
+	)
+}
+
+// Restart the analysis of the folder with an up to date config.
+// Documents opened by the editor are carried over, everything else is read again from disk
+func reloadWorkspaceFolder(folders map[string]*workspaceFolder, uri string, client *lsp.ClientCapabilities, editorConfig *lsp.ProjectConfig, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) {
+	restartWorkspaceFolder(folders, uri, nil, client, editorConfig, output, serverRequests, closeSession)
+}
+
+// Stop the analysis of a folder removed by the client.
+// The folders containing it are reloaded to take back its files, once its diagnostics are cleared
+func removeWorkspaceFolder(folders map[string]*workspaceFolder, uri string, client *lsp.ClientCapabilities, editorConfig *lsp.ProjectConfig, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) {
+	uri = strings.TrimSuffix(uri, "/")
+
+	removed, ok := folders[uri]
+	if !ok {
+		return
+	}
+
+	openedDocuments := collectOpenedDocuments(removed)
+	closeWorkspaceFolder(folders, uri, true)
+
+	for _, parent := range findParentWorkspaceFolders(folders, uri) {
+		restartWorkspaceFolder(folders, parent.Uri, []*workspaceFolder{removed}, client, editorConfig, output, serverRequests, closeSession)
+	}
+
+	for fileUri, content := range openedDocuments {
+		if folder := findWorkspaceFolder(folders, fileUri); folder != nil && !folder.isSingleFile {
+			insertTextDocumentToDiagnostic(fileUri, content, folder.textChangedNotification, folder.textFromClient, folder.muTextFromClient)
+		}
+	}
+}
+
+// See 'reloadWorkspaceFolder()', the new diagnostic handler wait for the ones of 'stopping' as well
+func restartWorkspaceFolder(folders map[string]*workspaceFolder, uri string, stopping []*workspaceFolder, client *lsp.ClientCapabilities, editorConfig *lsp.ProjectConfig, output io.Writer, serverRequests *lsp.ServerRequests, closeSession func()) {
+	previous, ok := folders[uri]
+	if !ok {
+		return
+	}
+
+	openedDocuments := collectOpenedDocuments(previous)
+	closeWorkspaceFolder(folders, uri, true)
+
+	folder := &workspaceFolder{
+		Name:         previous.Name,
+		Uri:          previous.Uri,
+		isSingleFile: previous.isSingleFile,
+	}
+
+	stopping = append(stopping, previous)
+
+	if folder.isSingleFile {
+		startWorkspaceFolder(folders, folder, "", editorConfig, stopping, client, previous.storage.OpenedDocuments, output, serverRequests, closeSession)
+	} else {
+		config := loadFolderConfig(editorConfig, folder.Uri, output)
+		startWorkspaceFolder(folders, folder, folder.Uri, config, stopping, client, previous.storage.OpenedDocuments, output, serverRequests, closeSession)
+	}
+
+	// the documents of a folder nested since the previous start now belong to it
+	for fileUri, content := range openedDocuments {
+		owner := folder
+		if folder.storage.IsWithinNestedFolder(fileUri) {
+			owner = findWorkspaceFolder(folders, fileUri)
+		}
+
+		insertTextDocumentToDiagnostic(fileUri, content, owner.textChangedNotification, owner.textFromClient, owner.muTextFromClient)
+	}
+}
+
+// Content of the documents of the folder opened by the editor, the most recent known by the folder
+func collectOpenedDocuments(previous *workspaceFolder) map[string][]byte {
+	openedDocuments := make(map[string][]byte)
+
+	previous.muTextFromClient.Lock()
+	for fileUri, content := range previous.storage.RawFiles {
+		if previous.storage.OpenedDocuments.Contains(fileUri) {
+			openedDocuments[fileUri] = content
+		}
+	}
+
+	for fileUri, content := range previous.storage.Preludes {
+		if previous.storage.OpenedDocuments.Contains(fileUri) {
+			openedDocuments[fileUri] = content
+		}
+	}
+
+	for fileUri, content := range previous.textFromClient { // more recent than the storage
+		if content != nil && previous.storage.OpenedDocuments.Contains(fileUri) {
+			openedDocuments[fileUri] = content
+		}
+	}
+	previous.muTextFromClient.Unlock()
+
+	return openedDocuments
+}
+
+// State of every folder, for the status command
+func collectWorkspaceStatus(folders map[string]*workspaceFolder) []lsp.WorkspaceStatus {
+	statuses := make([]lsp.WorkspaceStatus, 0, len(folders))
+
+	for _, folder := range folders {
+		status := lsp.WorkspaceStatus{
+			Name:         folder.Name,
+			Uri:          folder.Uri,
+			IsSingleFile: folder.isSingleFile,
+		}
+
+		folder.muTextFromClient.Lock()
+		status.FileCount = len(folder.storage.RawFiles)
+		status.SkippedFiles = slices.Clone(folder.storage.SkippedFiles)
+		folder.muTextFromClient.Unlock()
+
+		if status.SkippedFiles == nil {
+			status.SkippedFiles = []lsp.SkippedFile{}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	slices.SortFunc(statuses, func(a, b lsp.WorkspaceStatus) int { return strings.Compare(a.Uri, b.Uri) })
+
+	return statuses
+}
+
+// Root of the Go module owning the file, found by looking for the nearest 'go.mod' upward.
+// Used as workspace root when the client did not provide any.
+// Return an empty string when the file is not within a Go module
+func findGoModuleRoot(fileUri string) string {
+	if !strings.HasPrefix(fileUri, "file://") {
+		return ""
+	}
+
+	directory := filepath.Dir(uriToFilePath(fileUri))
+
+	for {
+		info, err := os.Stat(filepath.Join(directory, "go.mod"))
+		if err == nil && !info.IsDir() {
+			return filePathToUri(directory)
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory { // file system root reached
+			return ""
+		}
+
+		directory = parent
+	}
+}
+
+// Stop the analysis of the folder.
+// When the folder is removed from a running session, the diagnostics of its files are cleared as well
+func closeWorkspaceFolder(folders map[string]*workspaceFolder, uri string, clearDiagnostics bool) {
+	uri = strings.TrimSuffix(uri, "/")
+
+	folder, ok := folders[uri]
+	if !ok {
+		return
+	}
+
+	delete(folders, uri)
+
+	if clearDiagnostics { // every file is marked for deletion, the diagnostic handler then publish empty diagnostics
+		folder.muTextFromClient.Lock()
+		for fileUri := range folder.storage.RawFiles {
+			folder.textFromClient[fileUri] = nil
+		}
+
+		for fileUri := range folder.textFromClient { // files not analysed yet
+			folder.textFromClient[fileUri] = nil
+		}
+
+		if len(folder.textFromClient) > 0 && len(folder.textChangedNotification) == 0 {
+			folder.textChangedNotification <- true
+		}
+		folder.muTextFromClient.Unlock()
+	}
+
+	// the diagnostic handler process what is left in the channel, then stop
+	close(folder.textChangedNotification)
+
+	slog.Info("workspace folder closed", slog.String("name", folder.Name), slog.String("uri", folder.Uri))
+}
+
+// Folder owning the file, the most nested folder win when folders are nested.
+// Return nil if the file belong to no folder
+func findWorkspaceFolder(folders map[string]*workspaceFolder, fileUri string) *workspaceFolder {
+	var owner *workspaceFolder = nil
+
+	for uri, folder := range folders {
+		if folder.isSingleFile && fileUri == uri { // the document is its own folder
+			return folder
+		}
+
+		if folder.isSingleFile || !strings.HasPrefix(fileUri, uri+"/") {
+			continue
+		}
+
+		if owner == nil || len(uri) > len(owner.Uri) {
+			owner = folder
+		}
+	}
+
+	return owner
+}
+
+// Folders (not single file) strictly containing the folder 'uri'
+func findParentWorkspaceFolders(folders map[string]*workspaceFolder, uri string) []*workspaceFolder {
+	var parents []*workspaceFolder
+
+	for parentUri, folder := range folders {
+		if !folder.isSingleFile && strings.HasPrefix(uri, parentUri+"/") {
+			parents = append(parents, folder)
+		}
+	}
+
+	return parents
+}
+
+// Roots of the folders (not single file) nested within the folder 'uri', their files are routed to them by 'findWorkspaceFolder()'
+func findNestedFolderUris(folders map[string]*workspaceFolder, uri string) []string {
+	var nestedUris []string
+
+	for nestedUri, folder := range folders {
+		if !folder.isSingleFile && strings.HasPrefix(nestedUri, uri+"/") {
+			nestedUris = append(nestedUris, nestedUri)
+		}
+	}
+
+	slices.Sort(nestedUris)
+
+	return nestedUris
+}
+
+// Queue the file for analysis within the folder it belong to
+func insertTextDocumentToWorkspace(folders map[string]*workspaceFolder, uri string, content []byte) {
+	if uri == "" {
+		return
+	}
+
+	folder := findWorkspaceFolder(folders, uri)
+	if folder == nil {
+		slog.Warn("skiped file, it is outside of all workspace folders", slog.String("file_uri", uri))
+		return
+	}
+
+	insertTextDocumentToDiagnostic(uri, content, folder.textChangedNotification, folder.textFromClient, folder.muTextFromClient)
+}